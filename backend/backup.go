@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"booksystem/internal/backup"
+	"booksystem/internal/db"
+)
+
+// runBackupCommand 执行 backup 子命令：备份 SQLite 数据库到备份目录，可用于 cron 定时备份
+func runBackupCommand(args []string) int {
+	cfg := loadConfig()
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: booksystem backup [-dir dir] [-compress] [-keep n]")
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", cfg.Backup.Dir, "backup directory")
+	compress := fs.Bool("compress", cfg.Backup.Compress, "gzip the backup")
+	keep := fs.Int("keep", 0, "keep only the newest n backups (0 keeps all)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return 2
+	}
+	if cfg.Database.Driver != db.DriverSQLite {
+		fmt.Fprintf(os.Stderr, "backup only supports sqlite, use the %s backup tools instead\n", cfg.Database.Driver)
+		return 1
+	}
+
+	backups, err := backup.NewManager(openDatabase(cfg), *dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	info, err := backups.Create(*compress)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("created  %s (%d bytes)\n", info.Name, info.Size)
+
+	removed, err := backups.Prune(*keep)
+	for _, name := range removed {
+		fmt.Printf("removed  %s\n", name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runRestoreCommand 执行 restore 子命令：检查备份文件的完整性后替换数据库文件，需先停止服务
+func runRestoreCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: booksystem restore <backup file>\n\nstop the server before restoring; the current database file is kept next to it")
+		return 2
+	}
+	cfg := loadConfig()
+	if cfg.Database.Driver != db.DriverSQLite {
+		fmt.Fprintf(os.Stderr, "restore only supports sqlite, use the %s backup tools instead\n", cfg.Database.Driver)
+		return 1
+	}
+
+	dbPath := backup.SQLitePath(cfg.Database.ConnString())
+	previous, err := backup.Restore(args[0], dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("restored %s from %s\n", dbPath, args[0])
+	if previous != "" {
+		fmt.Printf("previous database saved as %s\n", previous)
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// command booksystem 的子命令，run 返回进程退出码
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) int
+}
+
+// commandList 全部子命令，和 HTTP 服务使用相同的配置
+func commandList() []command {
+	return []command{
+		{"serve", "", "start the HTTP server (default when no command is given)", runServeCommand},
+		{"migrate", "up|down|status", "apply, roll back or list schema migrations", runMigrateCommand},
+		{"import-books", "[-format f] [-quantity n] <file>", "import books from an ISO 2709 or MARCXML file", runImportBooksCommand},
+		{"export-books", "[-format f] [-lang l] [-o file]", "export all books as csv, xlsx, iso2709 or marcxml", runExportBooksCommand},
+		{"create-admin", "[-reset] <username>", "create an admin account, or reset its password with -reset (accounts are not enforced by the API yet)", runCreateAdminCommand},
+		{"reconcile-stock", "[-dry-run]", "recompute in-stock counts from outstanding borrows", runReconcileStockCommand},
+		{"backup", "[-dir d] [-compress] [-keep n]", "take an online backup of the SQLite database", runBackupCommand},
+		{"restore", "<file>", "verify a backup and swap it in (stop the server first)", runRestoreCommand},
+		{"purge-sessions", "", "delete borrow/return lists and kiosk pointers left by expired Redis sessions", runPurgeSessionsCommand},
+		{"config", "print", "print the effective configuration with passwords hidden", runConfigCommand},
+		{"seed", "", "load demo locations, books and a borrow record into an empty database", runSeedCommand},
+	}
+}
+
+// runCommand 执行 args[0] 对应的子命令
+func runCommand(args []string) int {
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printCommandUsage(os.Stdout)
+		return 0
+	}
+	for _, cmd := range commandList() {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printCommandUsage(os.Stderr)
+	return 2
+}
+
+func printCommandUsage(out *os.File) {
+	fmt.Fprintln(out, "usage: booksystem [command] [arguments]\n\ncommands:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, cmd := range commandList() {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "\nconfiguration is read from config.yaml (or the file named by CONFIG_FILE) and environment variables,\nthe same as the server; run `booksystem config print` to see the effective values")
+}
+
+func runServeCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: booksystem serve")
+		return 2
+	}
+	serve()
+	return 0
+}
+
+// runConfigCommand 执行 config print：输出配置文件、环境变量和默认值合并后的配置，配置无效时列出错误
+func runConfigCommand(args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: booksystem config print")
+		return 2
+	}
+	cfg := loadConfig()
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if path := configFile(); path != "" {
+		fmt.Printf("# config file: %s, overridden by environment variables\n", path)
+	} else {
+		fmt.Println("# no config file, defaults overridden by environment variables")
+	}
+	os.Stdout.Write(out)
+	return 0
+}
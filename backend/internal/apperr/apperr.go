@@ -0,0 +1,126 @@
+// Package apperr 接口错误：错误码目录和带错误码的错误类型。
+// 每个错误码对应一个 HTTP 状态码，提示模板按语言保存在 i18n 包的提示目录中；
+// 处理函数返回 *Error，由 handler 按请求的语言统一输出错误码、提示和字段校验明细；
+// 内部原因（如数据库错误）只写入日志，不返回给客户端。
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"booksystem/internal/i18n"
+)
+
+// Error 带错误码的错误
+type Error struct {
+	Code   Code
+	Params map[string]interface{} // 提示模板中的参数
+	Fields []FieldError           // 字段校验明细，仅 ValidationFailed 使用
+	Data   interface{}            // 附加数据，如冲突的节点
+	Err    error                  // 内部原因，只写入日志
+}
+
+// FieldError 单个字段的校验错误，Code 为字段错误码（如 Required）
+type FieldError struct {
+	Field  string
+	Code   Code
+	Params map[string]interface{}
+}
+
+// New 创建错误，kv 为提示模板的参数名和值，如 New(DuplicateBarcode, "barcode", code)
+func New(code Code, kv ...interface{}) *Error {
+	return &Error{Code: code, Params: params(kv)}
+}
+
+// Wrap 创建以 err 为内部原因的错误
+func Wrap(code Code, err error, kv ...interface{}) *Error {
+	return &Error{Code: code, Params: params(kv), Err: err}
+}
+
+// Internal 服务器内部错误，err 只写入日志
+func Internal(err error) *Error {
+	return Wrap(InternalError, err)
+}
+
+// Invalid 单个字段校验失败，code 为字段错误码，kv 为其提示模板的参数
+func Invalid(field string, code Code, kv ...interface{}) *Error {
+	return Validation(Field(field, code, kv...))
+}
+
+// Validation 字段校验失败
+func Validation(fields ...FieldError) *Error {
+	return &Error{Code: ValidationFailed, Fields: fields}
+}
+
+// Field 创建字段校验错误
+func Field(field string, code Code, kv ...interface{}) FieldError {
+	return FieldError{Field: field, Code: code, Params: params(kv)}
+}
+
+// WithData 设置附加数据
+func (e *Error) WithData(data interface{}) *Error {
+	e.Data = data
+	return e
+}
+
+// Status 错误码对应的 HTTP 状态码
+func (e *Error) Status() int {
+	return status(e.Code)
+}
+
+// Message lang 语言的提示信息。字段校验失败时附上各字段的提示
+func (e *Error) Message(lang i18n.Lang) string {
+	msg := i18n.Text(lang, string(e.Code), e.Params)
+	if len(e.Fields) == 0 {
+		return msg
+	}
+	details := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		details[i] = f.Message(lang)
+	}
+	return msg + ": " + strings.Join(details, "; ")
+}
+
+// Message lang 语言的字段提示信息，模板中的 {field} 为字段名
+func (f FieldError) Message(lang i18n.Lang) string {
+	p := map[string]interface{}{"field": f.Field}
+	for k, v := range f.Params {
+		p[k] = v
+	}
+	return i18n.Text(lang, string(f.Code), p)
+}
+
+// Error 错误码、英文提示和内部原因，用于日志
+func (e *Error) Error() string {
+	msg := string(e.Code) + ": " + e.Message(i18n.En)
+	// 提示参数中已带有原因时不再重复
+	if e.Err != nil && !strings.Contains(msg, e.Err.Error()) {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// As 取出 err 链中的 *Error，没有时为空
+func As(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+func params(kv []interface{}) map[string]interface{} {
+	if len(kv) == 0 {
+		return nil
+	}
+	p := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		p[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return p
+}
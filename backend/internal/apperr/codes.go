@@ -0,0 +1,163 @@
+package apperr
+
+import "net/http"
+
+// Code 错误码，客户端据此判断错误类型，不随提示文字和语言变化；也是 i18n 提示目录的键
+type Code string
+
+// 通用错误
+const (
+	BadRequest       Code = "BAD_REQUEST"       // 请求体无法解析
+	ValidationFailed Code = "VALIDATION_FAILED" // 字段校验失败，明细见 details
+	InternalError    Code = "INTERNAL_ERROR"
+)
+
+// 字段错误码，用于字段校验明细
+const (
+	Required     Code = "REQUIRED"
+	InvalidValue Code = "INVALID_VALUE"
+	TooSmall     Code = "TOO_SMALL"
+	OutOfRange   Code = "OUT_OF_RANGE"
+	TooMany      Code = "TOO_MANY"
+	DuplicateID  Code = "DUPLICATE_ID"
+	MissingID    Code = "MISSING_ID"
+	ForeignID    Code = "FOREIGN_ID"
+	SameLayer    Code = "SAME_LAYER"
+	// InvalidLocationCode 位置简码含有字母和数字以外的字符或过长
+	InvalidLocationCode Code = "INVALID_LOCATION_CODE"
+	// InStockExceedsQuantity 在库数量大于总数量
+	InStockExceedsQuantity Code = "IN_STOCK_EXCEEDS_QUANTITY"
+)
+
+// 图书和一维码
+const (
+	BookNotFound          Code = "BOOK_NOT_FOUND"
+	DuplicateBarcode      Code = "DUPLICATE_BARCODE"
+	BarcodeChecksum       Code = "BARCODE_CHECKSUM"
+	BarcodeUnknownFormat  Code = "BARCODE_UNKNOWN_FORMAT"
+	BookHasLoans          Code = "BOOK_HAS_LOANS"
+	CatalogEntryNotFound  Code = "CATALOG_ENTRY_NOT_FOUND"
+	AuthorNotFound        Code = "AUTHOR_NOT_FOUND"
+	PublisherNotFound     Code = "PUBLISHER_NOT_FOUND"
+	CategoryNotFound      Code = "CATEGORY_NOT_FOUND"
+	TagNotFound           Code = "TAG_NOT_FOUND"
+	DuplicateName         Code = "DUPLICATE_NAME"
+	ReferenceNotFound     Code = "REFERENCE_NOT_FOUND"
+	UnsupportedImportFile Code = "UNSUPPORTED_IMPORT_FILE"
+	MarcMissingBarcode    Code = "MARC_MISSING_BARCODE"
+	MarcMissingTitle      Code = "MARC_MISSING_TITLE"
+	MarcRecordTooLong     Code = "MARC_RECORD_TOO_LONG"
+)
+
+// 借还
+const (
+	StockExhausted    Code = "STOCK_EXHAUSTED" // 图书已全部借出，借阅不校验库存，只用于扫码提示
+	LoanLimitExceeded Code = "LOAN_LIMIT_EXCEEDED"
+	LoanNotFound      Code = "LOAN_NOT_FOUND"
+	BorrowerNotFound  Code = "BORROWER_NOT_FOUND"
+	BorrowerNotSet    Code = "BORROWER_NOT_SET"
+	NoBooksSelected   Code = "NO_BOOKS_SELECTED"
+	SessionExpired    Code = "SESSION_EXPIRED"
+)
+
+// 位置
+const (
+	AreaNotFound       Code = "AREA_NOT_FOUND"
+	BookshelfNotFound  Code = "BOOKSHELF_NOT_FOUND"
+	ShelfLayerNotFound Code = "SHELF_LAYER_NOT_FOUND"
+	NoMatchingLayers   Code = "NO_MATCHING_LAYERS"
+	DuplicateCode      Code = "DUPLICATE_CODE"
+	LocationConflict   Code = "LOCATION_CONFLICT"
+	LocationNotEmpty   Code = "LOCATION_NOT_EMPTY"
+	TargetInSubtree    Code = "TARGET_IN_SUBTREE"
+)
+
+// 文件、附件和备份
+const (
+	FileTooLarge         Code = "FILE_TOO_LARGE"
+	UnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+	CoverNotImage        Code = "COVER_NOT_IMAGE"
+	InvalidFile          Code = "INVALID_FILE"
+	AttachmentNotFound   Code = "ATTACHMENT_NOT_FOUND"
+	ThumbnailNotFound    Code = "THUMBNAIL_NOT_FOUND"
+	BackupNotFound       Code = "BACKUP_NOT_FOUND"
+	BackupNotSupported   Code = "BACKUP_NOT_SUPPORTED"
+	InvalidLabelLayout   Code = "INVALID_LABEL_LAYOUT"
+	TooManyLabels        Code = "TOO_MANY_LABELS"
+	BarcodeNotEncodable  Code = "BARCODE_NOT_ENCODABLE"
+	ExportTooLarge       Code = "EXPORT_TOO_LARGE"
+)
+
+// statuses 错误码对应的 HTTP 状态码，提示信息见 i18n 包的提示目录
+var statuses = map[Code]int{
+	BadRequest:       http.StatusBadRequest,
+	ValidationFailed: http.StatusBadRequest,
+	InternalError:    http.StatusInternalServerError,
+
+	Required:               http.StatusBadRequest,
+	InvalidValue:           http.StatusBadRequest,
+	TooSmall:               http.StatusBadRequest,
+	OutOfRange:             http.StatusBadRequest,
+	TooMany:                http.StatusBadRequest,
+	DuplicateID:            http.StatusBadRequest,
+	MissingID:              http.StatusBadRequest,
+	ForeignID:              http.StatusBadRequest,
+	SameLayer:              http.StatusBadRequest,
+	InvalidLocationCode:    http.StatusBadRequest,
+	InStockExceedsQuantity: http.StatusBadRequest,
+
+	BookNotFound:          http.StatusNotFound,
+	DuplicateBarcode:      http.StatusConflict,
+	BarcodeChecksum:       http.StatusBadRequest,
+	BarcodeUnknownFormat:  http.StatusBadRequest,
+	BookHasLoans:          http.StatusConflict,
+	CatalogEntryNotFound:  http.StatusNotFound,
+	AuthorNotFound:        http.StatusNotFound,
+	PublisherNotFound:     http.StatusNotFound,
+	CategoryNotFound:      http.StatusNotFound,
+	TagNotFound:           http.StatusNotFound,
+	DuplicateName:         http.StatusConflict,
+	ReferenceNotFound:     http.StatusBadRequest,
+	UnsupportedImportFile: http.StatusBadRequest,
+	MarcMissingBarcode:    http.StatusBadRequest,
+	MarcMissingTitle:      http.StatusBadRequest,
+	MarcRecordTooLong:     http.StatusBadRequest,
+
+	StockExhausted:    http.StatusConflict,
+	LoanLimitExceeded: http.StatusConflict,
+	LoanNotFound:      http.StatusNotFound,
+	BorrowerNotFound:  http.StatusNotFound,
+	BorrowerNotSet:    http.StatusConflict,
+	NoBooksSelected:   http.StatusBadRequest,
+	SessionExpired:    http.StatusGone,
+
+	AreaNotFound:       http.StatusNotFound,
+	BookshelfNotFound:  http.StatusNotFound,
+	ShelfLayerNotFound: http.StatusNotFound,
+	NoMatchingLayers:   http.StatusNotFound,
+	DuplicateCode:      http.StatusConflict,
+	LocationConflict:   http.StatusConflict,
+	LocationNotEmpty:   http.StatusConflict,
+	TargetInSubtree:    http.StatusBadRequest,
+
+	FileTooLarge:         http.StatusRequestEntityTooLarge,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	CoverNotImage:        http.StatusUnsupportedMediaType,
+	InvalidFile:          http.StatusBadRequest,
+	AttachmentNotFound:   http.StatusNotFound,
+	ThumbnailNotFound:    http.StatusNotFound,
+	BackupNotFound:       http.StatusNotFound,
+	BackupNotSupported:   http.StatusNotImplemented,
+	InvalidLabelLayout:   http.StatusBadRequest,
+	TooManyLabels:        http.StatusBadRequest,
+	BarcodeNotEncodable:  http.StatusBadRequest,
+	ExportTooLarge:       http.StatusBadRequest,
+}
+
+// status 错误码对应的 HTTP 状态码，不在目录中的错误码按内部错误处理
+func status(code Code) int {
+	if s, ok := statuses[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
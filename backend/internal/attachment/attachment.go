@@ -0,0 +1,102 @@
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	"golang.org/x/image/draw"
+
+	// 注册图片解码器
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// 支持的文件类型，按文件内容识别而不是扩展名或客户端声明的类型
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+	TypeWebP = "image/webp"
+	TypePDF  = "application/pdf"
+)
+
+// extensions 存储文件使用的扩展名
+var extensions = map[string]string{
+	TypeJPEG: ".jpg",
+	TypePNG:  ".png",
+	TypeGIF:  ".gif",
+	TypeWebP: ".webp",
+	TypePDF:  ".pdf",
+}
+
+// MaxPixels 生成缩略图的图片像素数上限。解码按像素分配内存，高度压缩的大尺寸图片文件很小，解码时却会占用数 GB 内存
+const MaxPixels = 40_000_000
+
+// ErrUnsupportedType 文件类型不在支持范围内
+var ErrUnsupportedType = errors.New("unsupported file type")
+
+// ErrTooManyPixels 图片像素数超过 MaxPixels
+var ErrTooManyPixels = fmt.Errorf("image exceeds %d pixels", MaxPixels)
+
+// Sniff 根据文件开头的内容识别类型，返回类型和存储扩展名
+func Sniff(head []byte) (contentType, ext string, err error) {
+	contentType = http.DetectContentType(head)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return contentType, "", ErrUnsupportedType
+	}
+	return contentType, ext, nil
+}
+
+// IsImage 是否为可生成缩略图的图片类型
+func IsImage(contentType string) bool {
+	return contentType != TypePDF && extensions[contentType] != ""
+}
+
+// Thumbnail 生成缩略图：等比缩放到不超过 maxSize×maxSize，透明部分填充白色，输出 JPEG。
+// 同时返回原图尺寸。解码前先读取图片头中的尺寸，超过 MaxPixels 时返回 ErrTooManyPixels。
+func Thumbnail(data []byte, maxSize int) (thumb []byte, width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, 0, 0, errors.New("empty image")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, 0, 0, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	b := src.Bounds()
+	width, height = b.Dx(), b.Dy()
+
+	tw, th := width, height
+	if tw > maxSize || th > maxSize {
+		if tw >= th {
+			tw, th = maxSize, max(1, height*maxSize/width)
+		} else {
+			tw, th = max(1, width*maxSize/height), maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), width, height, nil
+}
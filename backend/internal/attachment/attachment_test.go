@@ -0,0 +1,99 @@
+package attachment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// pngHeader 只有签名和 IHDR 块的 PNG，声明的尺寸为 width×height
+func pngHeader(width, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 0 // 8 位灰度
+	binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.Write(ihdr)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           []byte
+		width, height  int
+		thumbW, thumbH int
+		err            error
+	}{
+		{"landscape", encodePNG(t, 400, 100), 400, 100, 200, 50, nil},
+		{"portrait", encodePNG(t, 50, 300), 50, 300, 33, 200, nil},
+		{"small", encodePNG(t, 20, 10), 20, 10, 20, 10, nil},
+		{"too many pixels", pngHeader(30000, 30000), 0, 0, 0, 0, ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, width, height, err := Thumbnail(tt.data, 200)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if width != tt.width || height != tt.height {
+				t.Errorf("size = %d×%d, want %d×%d", width, height, tt.width, tt.height)
+			}
+			img, format, err := image.Decode(bytes.NewReader(thumb))
+			if err != nil || format != "jpeg" {
+				t.Fatalf("thumbnail format %q, err %v; want jpeg", format, err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.thumbW || b.Dy() != tt.thumbH {
+				t.Errorf("thumbnail = %d×%d, want %d×%d", b.Dx(), b.Dy(), tt.thumbW, tt.thumbH)
+			}
+		})
+	}
+
+	if _, _, _, err := Thumbnail([]byte("not an image"), 200); err == nil {
+		t.Error("Thumbnail of a non-image succeeded")
+	}
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		data        []byte
+		contentType string
+		ext         string
+		err         error
+	}{
+		{encodePNG(t, 1, 1), TypePNG, ".png", nil},
+		{[]byte("%PDF-1.7\n"), TypePDF, ".pdf", nil},
+		{[]byte("<html></html>"), "text/html; charset=utf-8", "", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		contentType, ext, err := Sniff(tt.data)
+		if contentType != tt.contentType || ext != tt.ext || !errors.Is(err, tt.err) {
+			t.Errorf("Sniff(%.8q) = %q, %q, %v; want %q, %q, %v", tt.data, contentType, ext, err, tt.contentType, tt.ext, tt.err)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Package backup SQLite 数据库的在线备份和恢复。
+// 备份使用 VACUUM INTO 在一个读事务中生成一致的快照，服务运行时也可以执行；恢复需在服务停止后进行。
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ErrNotFound 备份文件不存在
+var ErrNotFound = errors.New("backup: not found")
+
+// 备份文件名如 booksystem-20260102-150405.db，压缩后加 .gz
+const (
+	namePrefix = "booksystem-"
+	timeLayout = "20060102-150405"
+	dbExt      = ".db"
+	gzipExt    = ".gz"
+)
+
+var namePattern = regexp.MustCompile(`^booksystem-\d{8}-\d{6}\.db(\.gz)?$`)
+
+// Info 备份文件信息
+type Info struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Compressed bool      `json:"compressed"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Manager 管理备份目录中的备份文件
+type Manager struct {
+	db  *gorm.DB
+	dir string
+	mu  sync.Mutex // 同一时间只执行一个备份
+}
+
+// NewManager 创建 Manager，备份目录不存在时自动创建。db 必须是 SQLite 数据库。
+func NewManager(db *gorm.DB, dir string) (*Manager, error) {
+	if name := db.Dialector.Name(); name != "sqlite" {
+		return nil, fmt.Errorf("backup: only sqlite databases are supported, got %s", name)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Manager{db: db, dir: dir}, nil
+}
+
+// Create 生成一个备份，compress 为 true 时使用 gzip 压缩
+func (m *Manager) Create(compress bool) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	name := namePrefix + now.Format(timeLayout) + dbExt
+	path := filepath.Join(m.dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return Info{}, fmt.Errorf("backup: %s already exists", name)
+	}
+	if compress {
+		if _, err := os.Stat(path + gzipExt); err == nil {
+			return Info{}, fmt.Errorf("backup: %s already exists", name+gzipExt)
+		}
+	}
+
+	// 先写入临时文件，完成后再改名，备份目录中不会出现不完整的备份
+	tmp := filepath.Join(m.dir, "."+name+".tmp")
+	os.Remove(tmp)
+	if err := m.db.Exec("VACUUM INTO ?", tmp).Error; err != nil {
+		os.Remove(tmp)
+		return Info{}, fmt.Errorf("backup: vacuum into: %w", err)
+	}
+
+	if compress {
+		name += gzipExt
+		path += gzipExt
+		err := gzipFile(tmp, tmp+gzipExt)
+		os.Remove(tmp)
+		if err != nil {
+			os.Remove(tmp + gzipExt)
+			return Info{}, err
+		}
+		tmp += gzipExt
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Info{}, err
+	}
+	return stat(m.dir, name)
+}
+
+// List 返回备份目录中的备份，最新的在前
+func (m *Manager) List() ([]Info, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Info, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !namePattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := stat(m.dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name > list[j].Name })
+	return list, nil
+}
+
+// Open 打开备份文件用于下载，name 必须是 List 返回的文件名
+func (m *Manager) Open(name string) (*os.File, Info, error) {
+	if !namePattern.MatchString(name) {
+		return nil, Info{}, ErrNotFound
+	}
+	info, err := stat(m.dir, name)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	f, err := os.Open(filepath.Join(m.dir, name))
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return f, info, nil
+}
+
+// Prune 只保留最新的 keep 个备份，返回删除的文件名。keep 小于 1 时不删除。
+func (m *Manager) Prune(keep int) ([]string, error) {
+	if keep < 1 {
+		return nil, nil
+	}
+	list, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for i := keep; i < len(list); i++ {
+		if err := os.Remove(filepath.Join(m.dir, list[i].Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, list[i].Name)
+	}
+	return removed, nil
+}
+
+// Run 每隔 interval 生成一个备份并只保留最新的 keep 个，直到 ctx 结束。失败时只记录日志。
+func (m *Manager) Run(ctx context.Context, interval time.Duration, keep int, compress bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := m.Create(compress)
+		if err != nil {
+			slog.WarnContext(ctx, "Scheduled backup failed", "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "Created backup", "name", info.Name, "size", info.Size)
+		removed, err := m.Prune(keep)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to remove old backups", "error", err)
+		}
+		for _, name := range removed {
+			slog.InfoContext(ctx, "Removed old backup", "name", name)
+		}
+	}
+}
+
+// Restore 用备份文件 src 替换数据库文件 dbPath，src 可以是压缩的备份。
+// 先在数据库所在目录解压出候选文件并做完整性检查，通过后才替换；原数据库文件改名保留，返回其路径（原文件不存在时为空）。
+// 恢复时服务必须已停止。
+func Restore(src, dbPath string) (string, error) {
+	dir := filepath.Dir(dbPath)
+	candidate := filepath.Join(dir, "."+filepath.Base(dbPath)+".restore")
+	os.Remove(candidate)
+	var err error
+	if strings.HasSuffix(src, gzipExt) {
+		err = gunzipFile(src, candidate)
+	} else {
+		err = copyFile(src, candidate)
+	}
+	if err != nil {
+		os.Remove(candidate)
+		return "", err
+	}
+	if err := Check(candidate); err != nil {
+		os.Remove(candidate)
+		return "", err
+	}
+
+	var previous string
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = dbPath + ".before-restore-" + time.Now().Format(timeLayout)
+		if err := os.Rename(dbPath, previous); err != nil {
+			os.Remove(candidate)
+			return "", err
+		}
+	}
+	// 旧数据库的日志文件不属于恢复后的数据库，留着会被 SQLite 当作未完成的事务
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil {
+			if previous != "" {
+				os.Rename(dbPath+suffix, previous+suffix)
+			} else {
+				os.Remove(dbPath + suffix)
+			}
+		}
+	}
+	if err := os.Rename(candidate, dbPath); err != nil {
+		return previous, err
+	}
+	return previous, nil
+}
+
+// Check 对数据库文件做完整性检查，并确认它是本系统的数据库
+func Check(path string) error {
+	conn, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return fmt.Errorf("backup: open %s: %w", path, err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var results []string
+	if err := conn.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("backup: integrity check: %w", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("backup: integrity check failed: %s", strings.Join(results, "; "))
+	}
+	if !conn.Migrator().HasTable("books") {
+		return fmt.Errorf("backup: %s is not a booksystem database", path)
+	}
+	return nil
+}
+
+// SQLitePath 从 SQLite 的 DSN 中取出数据库文件路径（去掉 file: 前缀和查询参数）
+func SQLitePath(dsn string) string {
+	path := strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+func stat(dir, name string) (Info, error) {
+	fi, err := os.Stat(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return Info{}, ErrNotFound
+	} else if err != nil {
+		return Info{}, err
+	}
+	info := Info{Name: name, Size: fi.Size(), Compressed: strings.HasSuffix(name, gzipExt)}
+	stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), gzipExt), dbExt)
+	if t, err := time.ParseInLocation(timeLayout, stamp, time.Local); err == nil {
+		info.CreatedAt = t
+	} else {
+		info.CreatedAt = fi.ModTime()
+	}
+	return info, nil
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("backup: %s: %w", src, err)
+	}
+	defer zr.Close()
+	return writeFile(dst, zr)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in)
+}
+
+func writeFile(dst string, r io.Reader) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
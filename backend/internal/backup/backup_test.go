@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openDB 在 path 创建只有 books 表的 SQLite 数据库，names 为表中的书名
+func openDB(t *testing.T, path string, names ...string) *gorm.DB {
+	t.Helper()
+	conn, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(conn) })
+	if err := conn.Exec("CREATE TABLE IF NOT EXISTS books (id INTEGER PRIMARY KEY, name TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := conn.Exec("INSERT INTO books (name) VALUES (?)", name).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return conn
+}
+
+func closeDB(conn *gorm.DB) {
+	if sqlDB, err := conn.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// bookNames 读出 path 处数据库中的书名
+func bookNames(t *testing.T, path string) []string {
+	t.Helper()
+	conn, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(conn)
+	var names []string
+	if err := conn.Raw("SELECT name FROM books ORDER BY id").Scan(&names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		conn := openDB(t, filepath.Join(dir, "booksystem.db"), "a", "b")
+		m, err := NewManager(conn, filepath.Join(dir, "backups"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := m.Create(compress)
+		if err != nil {
+			t.Fatalf("Create(%v): %v", compress, err)
+		}
+		if !namePattern.MatchString(info.Name) || info.Compressed != compress || info.Size == 0 {
+			t.Errorf("Create(%v) = %+v", compress, info)
+		}
+
+		list, err := m.List()
+		if err != nil || len(list) != 1 || list[0].Name != info.Name {
+			t.Errorf("List() = %+v, %v; want only %s", list, err, info.Name)
+		}
+		entries, _ := os.ReadDir(m.dir)
+		if len(entries) != 1 {
+			t.Errorf("%d files in the backup directory, want no temporary files left", len(entries))
+		}
+
+		// 备份可以直接恢复
+		restored := filepath.Join(dir, "restored.db")
+		if _, err := Restore(filepath.Join(m.dir, info.Name), restored); err != nil {
+			t.Fatalf("Restore(%s): %v", info.Name, err)
+		}
+		if got := bookNames(t, restored); !reflect.DeepEqual(got, []string{"a", "b"}) {
+			t.Errorf("restored books = %q, want [a b]", got)
+		}
+	}
+}
+
+func TestListOpenPrune(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(openDB(t, filepath.Join(dir, "booksystem.db")), filepath.Join(dir, "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		"booksystem-20260101-000000.db",
+		"booksystem-20260102-000000.db.gz",
+		"booksystem-20260103-000000.db",
+	}
+	for _, name := range names {
+		writeTestFile(t, filepath.Join(m.dir, name), name)
+	}
+	// 不符合备份文件名的文件不列出、不删除
+	writeTestFile(t, filepath.Join(m.dir, "notes.txt"), "x")
+	writeTestFile(t, filepath.Join(m.dir, ".booksystem-20260104-000000.db.tmp"), "x")
+
+	list, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, info := range list {
+		got = append(got, info.Name)
+	}
+	if want := []string{names[2], names[1], names[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %q, want %q", got, want)
+	}
+	if !list[1].Compressed || list[0].CreatedAt.Format(timeLayout) != "20260103-000000" {
+		t.Errorf("List() infos = %+v", list)
+	}
+
+	for _, tt := range []struct {
+		name string
+		err  error
+	}{
+		{names[0], nil},
+		{"booksystem-20260105-000000.db", ErrNotFound},
+		{"notes.txt", ErrNotFound},
+		{"../booksystem.db", ErrNotFound},
+	} {
+		f, _, err := m.Open(tt.name)
+		if f != nil {
+			f.Close()
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Open(%q) error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	if removed, err := m.Prune(0); err != nil || len(removed) != 0 {
+		t.Errorf("Prune(0) = %q, %v; want nothing removed", removed, err)
+	}
+	removed, err := m.Prune(2)
+	if err != nil || !reflect.DeepEqual(removed, []string{names[0]}) {
+		t.Errorf("Prune(2) = %q, %v; want [%s]", removed, err, names[0])
+	}
+	if _, err := os.Stat(filepath.Join(m.dir, "notes.txt")); err != nil {
+		t.Errorf("Prune removed an unrelated file: %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "booksystem.db")
+	conn := openDB(t, dbPath, "old")
+	m, err := NewManager(conn, filepath.Join(dir, "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := m.Create(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Exec("INSERT INTO books (name) VALUES ('new')").Error; err != nil {
+		t.Fatal(err)
+	}
+	closeDB(conn)
+
+	// 未通过检查的文件不替换数据库
+	bad := filepath.Join(dir, "bad.db")
+	writeTestFile(t, bad, "not a database")
+	if _, err := Restore(bad, dbPath); err == nil {
+		t.Error("Restore of a corrupt file succeeded")
+	}
+	if got := bookNames(t, dbPath); !reflect.DeepEqual(got, []string{"old", "new"}) {
+		t.Errorf("books after a failed restore = %q, want the database untouched", got)
+	}
+
+	previous, err := Restore(filepath.Join(m.dir, info.Name), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bookNames(t, dbPath); !reflect.DeepEqual(got, []string{"old"}) {
+		t.Errorf("books after restore = %q, want [old]", got)
+	}
+	if previous == "" {
+		t.Fatal("Restore did not keep the previous database")
+	}
+	if got := bookNames(t, previous); !reflect.DeepEqual(got, []string{"old", "new"}) {
+		t.Errorf("books in %s = %q, want [old new]", previous, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".booksystem.db.restore")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("restore candidate left behind: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.db")
+	closeDB(openDB(t, valid))
+	other := filepath.Join(dir, "other.db")
+	conn, err := gorm.Open(sqlite.Open(other), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)")
+	closeDB(conn)
+	garbage := filepath.Join(dir, "garbage.db")
+	writeTestFile(t, garbage, "this is not an SQLite file, just some text long enough to fill a header")
+
+	for _, tt := range []struct {
+		path string
+		ok   bool
+	}{
+		{valid, true},
+		{other, false},
+		{garbage, false},
+	} {
+		if err := Check(tt.path); (err == nil) != tt.ok {
+			t.Errorf("Check(%s) = %v, want ok %v", filepath.Base(tt.path), err, tt.ok)
+		}
+	}
+}
+
+func TestSQLitePath(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"booksystem.db":                           "booksystem.db",
+		"file:data/booksystem.db":                 "data/booksystem.db",
+		"file:booksystem.db?_pragma=busy_timeout": "booksystem.db",
+	} {
+		if got := SQLitePath(dsn); got != want {
+			t.Errorf("SQLitePath(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package barcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind 一维码类型
+type Kind string
+
+const (
+	KindISBN10   Kind = "isbn10"
+	KindISBN13   Kind = "isbn13"
+	KindEAN13    Kind = "ean13"
+	KindInternal Kind = "internal" // 馆内自编条码
+)
+
+var (
+	ErrEmpty         = errors.New("barcode is empty")
+	ErrChecksum      = errors.New("barcode check digit mismatch")
+	ErrUnknownFormat = errors.New("barcode is not ISBN-10, ISBN-13, EAN-13 or a library-internal code")
+)
+
+// Result 一维码解析结果
+type Result struct {
+	Input      string `json:"input"`
+	Normalized string `json:"normalized"` // 入库使用的规范形式，ISBN-10 会转换为 ISBN-13
+	Kind       Kind   `json:"kind"`
+}
+
+// Validator 一维码校验器，馆内自编条码通过前缀识别
+type Validator struct {
+	internalPrefixes []string
+}
+
+// NewValidator 创建校验器，prefixes 为馆内自编条码前缀（如 "LIB"），大小写不敏感
+func NewValidator(prefixes []string) *Validator {
+	v := &Validator{}
+	for _, p := range prefixes {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p != "" {
+			v.internalPrefixes = append(v.internalPrefixes, p)
+		}
+	}
+	return v
+}
+
+// InternalPrefixes 返回已配置的馆内条码前缀
+func (v *Validator) InternalPrefixes() []string {
+	return v.internalPrefixes
+}
+
+// Parse 识别一维码类型并校验校验位。
+// 返回错误时 Result 仍包含清理后的输入，调用方可据此决定是报错还是仅提示。
+func (v *Validator) Parse(code string) (Result, error) {
+	code = strings.TrimSpace(code)
+	res := Result{Input: code, Normalized: code}
+	if code == "" {
+		return res, ErrEmpty
+	}
+
+	upper := strings.ToUpper(code)
+	for _, p := range v.internalPrefixes {
+		if strings.HasPrefix(upper, p) {
+			res.Normalized = upper
+			res.Kind = KindInternal
+			return res, nil
+		}
+	}
+
+	compact := clean(code)
+	switch {
+	case len(compact) == 10 && isISBN10Shape(compact):
+		res.Kind = KindISBN10
+		if !validISBN10(compact) {
+			return res, ErrChecksum
+		}
+		res.Normalized = ISBN10To13(compact)
+		return res, nil
+	case len(compact) == 13 && isDigits(compact):
+		res.Normalized = compact
+		if strings.HasPrefix(compact, "978") || strings.HasPrefix(compact, "979") {
+			res.Kind = KindISBN13
+		} else {
+			res.Kind = KindEAN13
+		}
+		if !validEAN13(compact) {
+			return res, ErrChecksum
+		}
+		return res, nil
+	}
+	return res, ErrUnknownFormat
+}
+
+// Candidates 返回查询一维码时应匹配的全部形式（原始输入、ISBN-13、ISBN-10），
+// 使按任一形式扫描都能找到以另一种形式录入的图书。
+func Candidates(code string) []string {
+	code = strings.TrimSpace(code)
+	out := []string{code}
+	add := func(s string) {
+		for _, existing := range out {
+			if existing == s {
+				return
+			}
+		}
+		out = append(out, s)
+	}
+
+	compact := clean(code)
+	switch {
+	case len(compact) == 10 && isISBN10Shape(compact) && validISBN10(compact):
+		add(compact)
+		add(ISBN10To13(compact))
+	case len(compact) == 13 && isDigits(compact):
+		add(compact)
+		if isbn10, ok := ISBN13To10(compact); ok {
+			add(isbn10)
+		}
+	}
+	return out
+}
+
+// ISBN10To13 将合法的 ISBN-10 转换为 978 前缀的 ISBN-13
+func ISBN10To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	return body + string(rune('0'+eanCheckDigit(body)))
+}
+
+// ISBN13To10 将 978 前缀的 ISBN-13 转换为 ISBN-10，979 前缀没有对应的 ISBN-10
+func ISBN13To10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") || !isDigits(isbn13) {
+		return "", false
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", true
+	}
+	return body + string(rune('0'+check)), true
+}
+
+// clean 去掉扫码或手工录入时常见的连字符和空格，并统一校验位 X 的大小写
+func clean(code string) string {
+	var b strings.Builder
+	for _, r := range code {
+		switch {
+		case r == '-' || r == ' ':
+			continue
+		case r == 'x':
+			b.WriteRune('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isISBN10Shape(s string) bool {
+	return isDigits(s[:9]) && (s[9] == 'X' || (s[9] >= '0' && s[9] <= '9'))
+}
+
+func validISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		d := int(s[i] - '0')
+		if s[i] == 'X' {
+			d = 10
+		}
+		sum += d * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// eanCheckDigit 计算 EAN-13 前12位对应的校验位
+func eanCheckDigit(body string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+func validEAN13(s string) bool {
+	return eanCheckDigit(s[:12]) == int(s[12]-'0')
+}
@@ -0,0 +1,119 @@
+package barcode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	v := NewValidator([]string{" lib ", "", "Cn"})
+	tests := []struct {
+		code       string
+		normalized string
+		kind       Kind
+		err        error
+	}{
+		{"0306406152", "9780306406157", KindISBN10, nil},
+		{"0-8044-2957-x", "9780804429573", KindISBN10, nil},
+		{"0306406153", "0306406153", KindISBN10, ErrChecksum},
+		{"978-0-306-40615-7", "9780306406157", KindISBN13, nil},
+		{"9791090636071", "9791090636071", KindISBN13, nil},
+		{"9780306406158", "9780306406158", KindISBN13, ErrChecksum},
+		{"4006381333931", "4006381333931", KindEAN13, nil},
+		{"6901234567892", "6901234567892", KindEAN13, nil},
+		{"6901234567893", "6901234567893", KindEAN13, ErrChecksum},
+		{" lib00000001 ", "LIB00000001", KindInternal, nil},
+		{"cn-42", "CN-42", KindInternal, nil},
+		{"", "", "", ErrEmpty},
+		{"12345", "12345", "", ErrUnknownFormat},
+		{"ABC0306406152", "ABC0306406152", "", ErrUnknownFormat},
+		{"030640615Y", "030640615Y", "", ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		res, err := v.Parse(tt.code)
+		if !errors.Is(err, tt.err) || res.Normalized != tt.normalized || res.Kind != tt.kind {
+			t.Errorf("Parse(%q) = %q %q, %v; want %q %q, %v", tt.code, res.Normalized, res.Kind, err, tt.normalized, tt.kind, tt.err)
+		}
+	}
+
+	if got := v.InternalPrefixes(); !reflect.DeepEqual(got, []string{"LIB", "CN"}) {
+		t.Errorf("InternalPrefixes() = %q, want [LIB CN]", got)
+	}
+}
+
+func TestISBNConversion(t *testing.T) {
+	tests := []struct {
+		isbn10 string
+		isbn13 string
+	}{
+		{"0306406152", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"7111234561", "9787111234562"},
+	}
+	for _, tt := range tests {
+		if got := ISBN10To13(tt.isbn10); got != tt.isbn13 {
+			t.Errorf("ISBN10To13(%s) = %s, want %s", tt.isbn10, got, tt.isbn13)
+		}
+		if got, ok := ISBN13To10(tt.isbn13); !ok || got != tt.isbn10 {
+			t.Errorf("ISBN13To10(%s) = %s, %v; want %s", tt.isbn13, got, ok, tt.isbn10)
+		}
+	}
+
+	// 979 前缀、非 ISBN 的 EAN-13 和格式不对的输入没有 ISBN-10
+	for _, code := range []string{"9791090636071", "6901234567892", "978030640615", "97803064061X7"} {
+		if got, ok := ISBN13To10(code); ok {
+			t.Errorf("ISBN13To10(%s) = %s, want no ISBN-10", code, got)
+		}
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"0306406152", []string{"0306406152", "9780306406157"}},
+		{"0-306-40615-2", []string{"0-306-40615-2", "0306406152", "9780306406157"}},
+		{"9780306406157", []string{"9780306406157", "0306406152"}},
+		{"978-0-8044-2957-3", []string{"978-0-8044-2957-3", "9780804429573", "080442957X"}},
+		{"9791090636071", []string{"9791090636071"}},
+		{" LIB00000001 ", []string{"LIB00000001"}},
+		// 校验位错误的 ISBN-10 不转换
+		{"0306406153", []string{"0306406153"}},
+	}
+	for _, tt := range tests {
+		if got := Candidates(tt.code); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Candidates(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDigits(t *testing.T) {
+	tests := []struct {
+		body  string
+		check int
+	}{
+		{"978030640615", 7},
+		{"400638133393", 1},
+		{"690123456789", 2},
+		{"000000000000", 0},
+	}
+	for _, tt := range tests {
+		if got := eanCheckDigit(tt.body); got != tt.check {
+			t.Errorf("eanCheckDigit(%s) = %d, want %d", tt.body, got, tt.check)
+		}
+	}
+
+	for code, want := range map[string]bool{
+		"0306406152": true,
+		"080442957X": true,
+		"0306406153": false,
+		"0000000000": true,
+		"X000000000": false,
+	} {
+		if got := validISBN10(code); got != want {
+			t.Errorf("validISBN10(%s) = %v, want %v", code, got, want)
+		}
+	}
+}
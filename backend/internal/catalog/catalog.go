@@ -0,0 +1,210 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"booksystem/internal/barcode"
+)
+
+// Entry 书目元数据，用于录入图书时预填表单
+type Entry struct {
+	ISBN        string   `json:"isbn"`
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	Publisher   string   `json:"publisher,omitempty"`
+	PublishYear *int     `json:"publish_year,omitempty"`
+	Edition     string   `json:"edition,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	ClassNumber string   `json:"class_number,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	Source      string   `json:"source"`
+}
+
+// Provider 元数据来源。未找到时返回 nil, nil。
+// 本地目录文件是默认来源，以后可以增加基于HTTP的在线来源。
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, isbn string) (*Entry, error)
+}
+
+// Chain 按顺序查询多个来源，返回第一个命中的结果
+type Chain []Provider
+
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) Lookup(ctx context.Context, isbn string) (*Entry, error) {
+	for _, p := range c {
+		entry, err := p.Lookup(ctx, isbn)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		if entry != nil {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// LocalProvider 基于本地目录文件（CSV/MARC）的内存索引，完全离线
+type LocalProvider struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+	files   []string            // 按加载顺序
+	sources map[string][]*Entry // 各文件的条目，重新加载同名文件时用于重建索引
+}
+
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{entries: make(map[string]*Entry), sources: make(map[string][]*Entry)}
+}
+
+func (p *LocalProvider) Name() string {
+	return "local"
+}
+
+// Lookup 按 ISBN/一维码查找，ISBN-10 与 ISBN-13 均可命中
+func (p *LocalProvider) Lookup(ctx context.Context, isbn string) (*Entry, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, key := range barcode.Candidates(isbn) {
+		if entry, ok := p.entries[key]; ok {
+			copied := *entry
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// Len 返回已加载的条目数
+func (p *LocalProvider) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := make(map[*Entry]bool)
+	for _, e := range p.entries {
+		seen[e] = true
+	}
+	return len(seen)
+}
+
+// Files 返回已加载的文件
+func (p *LocalProvider) Files() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.files...)
+}
+
+// Load 加载来源为 source 的条目，以其 ISBN 的各种形式建立索引，后加载的数据覆盖先加载的。
+// 同名来源已加载过时（如重新上传同名文件）替换其全部条目，视为最后加载
+func (p *LocalProvider) Load(entries []*Entry, source string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range entries {
+		e.Source = source
+	}
+	if _, ok := p.sources[source]; !ok {
+		p.sources[source] = entries
+		p.files = append(p.files, source)
+		p.index(entries)
+		return
+	}
+
+	p.sources[source] = entries
+	files := make([]string, 0, len(p.files))
+	for _, f := range p.files {
+		if f != source {
+			files = append(files, f)
+		}
+	}
+	p.files = append(files, source)
+	p.entries = make(map[string]*Entry)
+	for _, f := range p.files {
+		p.index(p.sources[f])
+	}
+}
+
+// index 将条目加入索引，调用方持有写锁
+func (p *LocalProvider) index(entries []*Entry) {
+	for _, e := range entries {
+		if e.ISBN == "" || e.Title == "" {
+			continue
+		}
+		for _, key := range barcode.Candidates(e.ISBN) {
+			p.entries[key] = e
+		}
+	}
+}
+
+// LoadFile 按扩展名加载 CSV（.csv）或 MARC（.mrc/.iso/.marc/.xml）目录文件，返回加载条数
+func (p *LocalProvider) LoadFile(path string) (int, error) {
+	entries, err := ReadFile(path, filepath.Base(path))
+	if err != nil {
+		return 0, err
+	}
+	p.Load(entries, filepath.Base(path))
+	return len(entries), nil
+}
+
+// ReadFile 解析目录文件 path 但不加载，格式按 name 的扩展名判断（path 可以是上传时的临时文件）
+func ReadFile(path, name string) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		entries, err = readCSV(f)
+	case ".mrc", ".iso", ".marc", ".xml":
+		entries, err = readMARC(f)
+	default:
+		return nil, fmt.Errorf("unsupported catalog file: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return entries, nil
+}
+
+// LoadDir 加载目录下所有支持的目录文件，目录不存在时不报错
+func (p *LocalProvider) LoadDir(dir string) (int, error) {
+	items, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, item := range items {
+		if item.IsDir() || !Supported(item.Name()) {
+			continue
+		}
+		n, err := p.LoadFile(filepath.Join(dir, item.Name()))
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Supported 判断文件扩展名是否为支持的目录格式
+func Supported(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".mrc", ".iso", ".marc", ".xml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"booksystem/internal/marc"
+)
+
+// csvColumns CSV表头别名，兼容英文表头和图书导出文件的中英文表头
+var csvColumns = map[string]string{
+	"isbn":         "isbn",
+	"barcode":      "isbn",
+	"一维码":          "isbn",
+	"title":        "title",
+	"name":         "title",
+	"书名":           "title",
+	"author":       "authors",
+	"authors":      "authors",
+	"作者":           "authors",
+	"publisher":    "publisher",
+	"出版社":          "publisher",
+	"publish_year": "publish_year",
+	"publish year": "publish_year",
+	"year":         "publish_year",
+	"出版年":          "publish_year",
+	"edition":      "edition",
+	"版次":           "edition",
+	"language":     "language",
+	"语种":           "language",
+	"class_number": "class_number",
+	"clc":          "class_number",
+	"class number": "class_number",
+	"分类号":          "class_number",
+	"price":        "price",
+	"价格":           "price",
+}
+
+// readCSV 读取带表头的CSV目录文件，多个作者用分号或顿号分隔
+func readCSV(r io.Reader) ([]*Entry, error) {
+	br := bufio.NewReader(r)
+	// 跳过UTF-8 BOM
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+		br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for i, h := range header {
+		if col, ok := csvColumns[strings.ToLower(strings.TrimSpace(h))]; ok {
+			index[col] = i
+		}
+	}
+	if _, ok := index["isbn"]; !ok {
+		return nil, fmt.Errorf("csv header has no isbn column")
+	}
+	if _, ok := index["title"]; !ok {
+		return nil, fmt.Errorf("csv header has no title column")
+	}
+
+	var entries []*Entry
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		get := func(col string) string {
+			if i, ok := index[col]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		e := &Entry{
+			ISBN:        get("isbn"),
+			Title:       get("title"),
+			Publisher:   get("publisher"),
+			Edition:     get("edition"),
+			Language:    get("language"),
+			ClassNumber: get("class_number"),
+		}
+		for _, a := range strings.FieldsFunc(get("authors"), func(r rune) bool { return r == ';' || r == '；' || r == '、' }) {
+			if a = strings.TrimSpace(a); a != "" {
+				e.Authors = append(e.Authors, a)
+			}
+		}
+		if y, err := strconv.Atoi(get("publish_year")); err == nil {
+			e.PublishYear = &y
+		}
+		if p, err := strconv.ParseFloat(get("price"), 64); err == nil {
+			e.Price = &p
+		}
+		entries = append(entries, e)
+	}
+}
+
+// readMARC 读取 ISO 2709 或 MARCXML 目录文件
+func readMARC(r io.Reader) ([]*Entry, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(64)
+	var records []marc.Record
+	var err error
+	if marc.IsXML(head) {
+		records, err = marc.ReadXML(br)
+	} else {
+		records, err = marc.ReadISO2709(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(records))
+	for _, rec := range records {
+		bib := marc.ToBib(rec)
+		entries = append(entries, &Entry{
+			ISBN:        bib.ISBN,
+			Title:       bib.Title,
+			Authors:     bib.Authors,
+			Publisher:   bib.Publisher,
+			PublishYear: bib.PublishYear,
+			Edition:     bib.Edition,
+			Language:    bib.Language,
+			ClassNumber: bib.ClassNumber,
+			Price:       bib.Price,
+		})
+	}
+	return entries, nil
+}
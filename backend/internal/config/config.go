@@ -0,0 +1,399 @@
+// Package config 服务和命令行工具共用的配置。
+// 依次应用默认值、YAML 配置文件和环境变量（环境变量优先），加载后统一校验，任何一项无效都返回错误。
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile 未设置 CONFIG_FILE 时读取的配置文件，不存在时只使用默认值和环境变量
+const DefaultFile = "config.yaml"
+
+// Config 全部配置，yaml 标签为配置文件中的键，env 标签为覆盖该项的环境变量
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Session  SessionConfig  `yaml:"session"`
+	Loan     LoanConfig     `yaml:"loan"`
+	Backup   BackupConfig   `yaml:"backup"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Catalog  CatalogConfig  `yaml:"catalog"`
+	Label    LabelConfig    `yaml:"label"`
+	Barcode  BarcodeConfig  `yaml:"barcode"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// ServerConfig HTTP 服务
+type ServerConfig struct {
+	Addr        string   `yaml:"addr" env:"SERVER_ADDR"`          // 监听地址
+	PublicURL   string   `yaml:"public_url" env:"PUBLIC_URL"`     // 前端对外访问地址，用于生成借阅二维码中的页面地址；为空时使用终端页面的地址
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ORIGINS"` // 允许跨域访问的来源，* 表示任意来源
+	// 停止服务时等待处理中的请求完成的最长时间
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// DatabaseConfig 数据库
+type DatabaseConfig struct {
+	Driver      string `yaml:"driver" env:"DB_DRIVER"`             // sqlite、mysql 或 postgres
+	DSN         string `yaml:"dsn" env:"DB_DSN"`                   // 连接串，SQLite 为空时使用 Path
+	Path        string `yaml:"path" env:"DB_PATH"`                 // SQLite 数据库文件
+	AutoMigrate bool   `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"` // 启动时执行未执行的迁移
+}
+
+// RedisConfig Redis 连接
+type RedisConfig struct {
+	Addr     string `yaml:"addr" env:"REDIS_ADDR"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env:"REDIS_DB"`
+}
+
+// SessionConfig Redis 中借还书会话的有效期
+type SessionConfig struct {
+	TTL      Duration `yaml:"ttl" env:"SESSION_TTL"`             // 借阅/归还用户信息
+	KioskTTL Duration `yaml:"kiosk_ttl" env:"KIOSK_SESSION_TTL"` // 终端扫码会话，每次使用后顺延
+}
+
+// LoanConfig 借阅规则
+type LoanConfig struct {
+	MaxBooks   int `yaml:"max_books" env:"LOAN_MAX_BOOKS"`     // 每位读者最多同时借阅的册数，0 表示不限
+	PeriodDays int `yaml:"period_days" env:"LOAN_PERIOD_DAYS"` // 借阅期限（天），超过后计为逾期，0 表示不计逾期
+}
+
+// BackupConfig SQLite 备份
+type BackupConfig struct {
+	Dir      string   `yaml:"dir" env:"BACKUP_DIR"`
+	Interval Duration `yaml:"interval" env:"BACKUP_INTERVAL"` // 定时备份间隔，0 表示不定时备份
+	Keep     int      `yaml:"keep" env:"BACKUP_KEEP"`         // 定时备份后保留的最新备份数
+	Compress bool     `yaml:"compress" env:"BACKUP_COMPRESS"`
+	API      bool     `yaml:"api" env:"BACKUP_API"` // 是否开放备份接口。接口没有鉴权，备份包含全部数据（含管理员密码哈希），默认关闭
+}
+
+// StorageConfig 图书封面和附件
+type StorageConfig struct {
+	UploadDir       string `yaml:"upload_dir" env:"UPLOAD_DIR"`
+	AttachmentMaxMB int    `yaml:"attachment_max_mb" env:"ATTACHMENT_MAX_MB"`
+}
+
+// CatalogConfig 本地书目目录
+type CatalogConfig struct {
+	Dir string `yaml:"dir" env:"CATALOG_DIR"`
+}
+
+// LabelConfig 标签打印
+type LabelConfig struct {
+	Font string `yaml:"font" env:"LABEL_FONT"` // 中文 TTF 字体，未配置时标签中的中文无法显示
+}
+
+// BarcodeConfig 一维码校验
+type BarcodeConfig struct {
+	InternalPrefixes []string `yaml:"internal_prefixes" env:"INTERNAL_BARCODE_PREFIXES"` // 馆内自编条码前缀，以字母开头，第一个用于分配新条码
+}
+
+// LogConfig 日志
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // debug、info、warn 或 error，debug 时输出 SQL
+	Format string `yaml:"format" env:"LOG_FORMAT"` // json 或 text
+}
+
+// 支持的数据库驱动，与 db 包中的常量一致
+var drivers = []string{"sqlite", "mysql", "postgres"}
+
+// LogLevels 支持的日志级别
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// LogFormats 支持的日志格式
+var LogFormats = []string{"json", "text"}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8089",
+			CORSOrigins:     []string{"*"},
+			ShutdownTimeout: Duration(10 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Driver:      "sqlite",
+			Path:        "booksystem.db",
+			AutoMigrate: true,
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+		Session: SessionConfig{
+			TTL:      Duration(2 * time.Hour),
+			KioskTTL: Duration(10 * time.Minute),
+		},
+		Loan: LoanConfig{
+			PeriodDays: 30,
+		},
+		Backup: BackupConfig{
+			Dir:  "backups",
+			Keep: 7,
+		},
+		Storage: StorageConfig{
+			UploadDir:       "uploads",
+			AttachmentMaxMB: 10,
+		},
+		Catalog: CatalogConfig{
+			Dir: "catalog",
+		},
+		Barcode: BarcodeConfig{
+			InternalPrefixes: []string{"LIB"},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load 加载配置：path 不为空时读取该 YAML 文件（文件中不认识的键视为错误），再用环境变量覆盖，最后校验
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	// 无法解析的环境变量和无效的配置项一起报告
+	if err := errors.Join(applyEnv(reflect.ValueOf(cfg).Elem(), os.LookupEnv), cfg.Validate()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 校验配置，返回全部无效项
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("config: %s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		fail("server.addr", "invalid listen address %q, expected host:port or :port", c.Server.Addr)
+	}
+	if c.Server.PublicURL != "" && !isHTTPURL(c.Server.PublicURL) {
+		fail("server.public_url", "invalid URL %q, expected http(s)://host[/path]", c.Server.PublicURL)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout", "must be positive")
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		fail("server.cors_origins", "must not be empty, use * to allow any origin")
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if origin != "*" && !isOrigin(origin) {
+			fail("server.cors_origins", "invalid origin %q, expected * or scheme://host[:port]", origin)
+		}
+	}
+
+	if !contains(drivers, c.Database.Driver) {
+		fail("database.driver", "unsupported driver %q (supported: %s)", c.Database.Driver, strings.Join(drivers, ", "))
+	} else if c.Database.DSN == "" && c.Database.Driver != "sqlite" {
+		fail("database.dsn", "required for driver %s", c.Database.Driver)
+	} else if c.Database.ConnString() == "" {
+		fail("database.path", "required when database.dsn is empty")
+	}
+
+	if c.Redis.Addr == "" {
+		fail("redis.addr", "must not be empty")
+	}
+	if c.Redis.DB < 0 {
+		fail("redis.db", "must not be negative")
+	}
+	if c.Session.TTL <= 0 {
+		fail("session.ttl", "must be positive")
+	}
+	if c.Session.KioskTTL <= 0 {
+		fail("session.kiosk_ttl", "must be positive")
+	}
+	if c.Loan.MaxBooks < 0 {
+		fail("loan.max_books", "must not be negative, use 0 for no limit")
+	}
+	if c.Loan.PeriodDays < 0 {
+		fail("loan.period_days", "must not be negative, use 0 to disable overdue tracking")
+	}
+
+	if c.Backup.Dir == "" {
+		fail("backup.dir", "must not be empty")
+	}
+	if c.Backup.Interval < 0 {
+		fail("backup.interval", "must not be negative, use 0 to disable scheduled backups")
+	}
+	if c.Backup.Keep < 1 {
+		fail("backup.keep", "must be at least 1")
+	}
+	if c.Storage.UploadDir == "" {
+		fail("storage.upload_dir", "must not be empty")
+	}
+	if c.Storage.AttachmentMaxMB <= 0 {
+		fail("storage.attachment_max_mb", "must be positive")
+	}
+	if c.Catalog.Dir == "" {
+		fail("catalog.dir", "must not be empty")
+	}
+	if len(c.Barcode.InternalPrefixes) == 0 {
+		fail("barcode.internal_prefixes", "must not be empty, internal barcodes are assigned with the first prefix")
+	}
+	for _, prefix := range c.Barcode.InternalPrefixes {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			fail("barcode.internal_prefixes", "must not contain empty prefixes")
+			break
+		}
+		// 以数字开头的前缀（如 978）会先于 ISBN/EAN-13 匹配，把图书的 ISBN 当作馆内条码
+		if c := prefix[0]; !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+			fail("barcode.internal_prefixes", "prefix %q must start with a letter so it cannot match ISBN or EAN-13 codes", prefix)
+		}
+	}
+	if !contains(LogLevels, c.Log.Level) {
+		fail("log.level", "unsupported level %q (supported: %s)", c.Log.Level, strings.Join(LogLevels, ", "))
+	}
+	if !contains(LogFormats, c.Log.Format) {
+		fail("log.format", "unsupported format %q (supported: %s)", c.Log.Format, strings.Join(LogFormats, ", "))
+	}
+	return errors.Join(errs...)
+}
+
+// ConnString 数据库连接串：DSN 为空且使用 SQLite 时为数据库文件路径
+func (d DatabaseConfig) ConnString() string {
+	if d.DSN == "" && d.Driver == "sqlite" {
+		return d.Path
+	}
+	return d.DSN
+}
+
+// 连接串中的密码：MySQL 的 user:pass@tcp(...)、URL 形式的 //user:pass@host 和 PostgreSQL 的 password=xxx
+var (
+	dsnUserPassword = regexp.MustCompile(`(^|//)([^:@/]*):[^@/]*@`)
+	dsnPasswordKey  = regexp.MustCompile(`password=\S*`)
+)
+
+// Redacted 返回隐藏了密码的副本，用于打印
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if redacted.Redis.Password != "" {
+		redacted.Redis.Password = "******"
+	}
+	dsn := dsnUserPassword.ReplaceAllString(c.Database.DSN, "$1$2:******@")
+	redacted.Database.DSN = dsnPasswordKey.ReplaceAllString(dsn, "password=******")
+	return &redacted
+}
+
+// YAML 以配置文件的格式输出
+func (c *Config) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// applyEnv 用环境变量覆盖带 env 标签的字段，空值视为未设置
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && t.Field(i).Tag.Get("env") == "" {
+			if err := applyEnv(field, lookup); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: invalid value %q: %w", name, value, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var durationType = reflect.TypeOf(Duration(0))
+
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		// 逗号分隔的列表
+		parts := strings.Split(value, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		field.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isOrigin(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.User == nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
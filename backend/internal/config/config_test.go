@@ -0,0 +1,211 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  addr: 127.0.0.1:9000
+  shutdown_timeout: 30s
+database:
+  driver: postgres
+  dsn: host=db user=booksystem password=secret dbname=booksystem
+session:
+  ttl: 1h
+barcode:
+  internal_prefixes: [LIB, CN]
+`)
+	t.Setenv("REDIS_ADDR", "redis:6380")
+	t.Setenv("LOAN_MAX_BOOKS", "5")
+	t.Setenv("BACKUP_API", "true")
+	t.Setenv("CORS_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("SESSION_TTL", "") // 空值视为未设置
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"server.addr", cfg.Server.Addr, "127.0.0.1:9000"},
+		{"server.shutdown_timeout", cfg.Server.ShutdownTimeout.D(), 30 * time.Second},
+		{"database.driver", cfg.Database.Driver, "postgres"},
+		{"session.ttl", cfg.Session.TTL.D(), time.Hour},
+		{"session.kiosk_ttl", cfg.Session.KioskTTL.D(), 10 * time.Minute},
+		{"barcode.internal_prefixes", cfg.Barcode.InternalPrefixes, []string{"LIB", "CN"}},
+		{"REDIS_ADDR", cfg.Redis.Addr, "redis:6380"},
+		{"LOAN_MAX_BOOKS", cfg.Loan.MaxBooks, 5},
+		{"BACKUP_API", cfg.Backup.API, true},
+		{"CORS_ORIGINS", cfg.Server.CORSOrigins, []string{"https://a.example", "https://b.example"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// 输出的配置可以重新加载
+	out, err := cfg.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Load(writeConfig(t, string(out)))
+	if err != nil {
+		t.Fatalf("reload YAML output: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(again, cfg) {
+		t.Errorf("reloaded config = %+v, want %+v", again, cfg)
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	cfg, err := Load("../../config.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Backup.API {
+		t.Error("backup.api is enabled in config.example.yaml")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		want    []string
+	}{
+		{"unknown key", "server:\n  port: 8089\n", nil, []string{"field port not found"}},
+		{"bad duration", "session:\n  ttl: 2 hours\n", nil, []string{"invalid duration"}},
+		{"bad env", "", map[string]string{"LOAN_MAX_BOOKS": "many", "BACKUP_INTERVAL": "daily"},
+			[]string{"LOAN_MAX_BOOKS", "BACKUP_INTERVAL"}},
+		// 环境变量错误和无效配置项一起报告
+		{"env and validation", "loan:\n  period_days: -1\n", map[string]string{"REDIS_DB": "x"},
+			[]string{"REDIS_DB", "loan.period_days"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil {
+				t.Fatal("Load succeeded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("default config: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		modify func(c *Config)
+	}{
+		{"server.addr", func(c *Config) { c.Server.Addr = "8089" }},
+		{"server.public_url", func(c *Config) { c.Server.PublicURL = "ftp://example.com" }},
+		{"server.shutdown_timeout", func(c *Config) { c.Server.ShutdownTimeout = 0 }},
+		{"server.cors_origins", func(c *Config) { c.Server.CORSOrigins = nil }},
+		{"server.cors_origins", func(c *Config) { c.Server.CORSOrigins = []string{"https://example.com/app"} }},
+		{"database.driver", func(c *Config) { c.Database.Driver = "oracle" }},
+		{"database.dsn", func(c *Config) { c.Database.Driver = "mysql" }},
+		{"database.path", func(c *Config) { c.Database.Path = "" }},
+		{"redis.addr", func(c *Config) { c.Redis.Addr = "" }},
+		{"redis.db", func(c *Config) { c.Redis.DB = -1 }},
+		{"session.ttl", func(c *Config) { c.Session.TTL = 0 }},
+		{"session.kiosk_ttl", func(c *Config) { c.Session.KioskTTL = -1 }},
+		{"loan.max_books", func(c *Config) { c.Loan.MaxBooks = -1 }},
+		{"loan.period_days", func(c *Config) { c.Loan.PeriodDays = -1 }},
+		{"backup.dir", func(c *Config) { c.Backup.Dir = "" }},
+		{"backup.interval", func(c *Config) { c.Backup.Interval = -1 }},
+		{"backup.keep", func(c *Config) { c.Backup.Keep = 0 }},
+		{"storage.upload_dir", func(c *Config) { c.Storage.UploadDir = "" }},
+		{"storage.attachment_max_mb", func(c *Config) { c.Storage.AttachmentMaxMB = 0 }},
+		{"catalog.dir", func(c *Config) { c.Catalog.Dir = "" }},
+		{"barcode.internal_prefixes", func(c *Config) { c.Barcode.InternalPrefixes = nil }},
+		{"barcode.internal_prefixes", func(c *Config) { c.Barcode.InternalPrefixes = []string{"LIB", " "} }},
+		{"barcode.internal_prefixes", func(c *Config) { c.Barcode.InternalPrefixes = []string{"978"} }},
+		{"barcode.internal_prefixes", func(c *Config) { c.Barcode.InternalPrefixes = []string{"LIB", "0A"} }},
+		{"log.level", func(c *Config) { c.Log.Level = "trace" }},
+		{"log.format", func(c *Config) { c.Log.Format = "xml" }},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		tt.modify(cfg)
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "config: "+tt.key+": ") {
+			t.Errorf("%s: Validate() = %v, want an error for %s", tt.key, err, tt.key)
+		}
+	}
+
+	// 全部无效项一起返回
+	cfg := Default()
+	cfg.Redis.Addr, cfg.Log.Level = "", "trace"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "redis.addr") || !strings.Contains(err.Error(), "log.level") {
+		t.Errorf("Validate() = %v, want both redis.addr and log.level", err)
+	}
+
+	// 允许的取值
+	cfg = Default()
+	cfg.Database = DatabaseConfig{Driver: "mysql", DSN: "user:pass@tcp(db:3306)/booksystem?parseTime=True"}
+	cfg.Server.PublicURL = "https://library.example/app"
+	cfg.Server.CORSOrigins = []string{"http://localhost:5173"}
+	cfg.Barcode.InternalPrefixes = []string{" lib ", "C9"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{"user:secret@tcp(db:3306)/booksystem", "user:******@tcp(db:3306)/booksystem"},
+		{"postgres://user:secret@db/booksystem", "postgres://user:******@db/booksystem"},
+		{"host=db user=booksystem password=secret dbname=booksystem", "host=db user=booksystem password=****** dbname=booksystem"},
+		{"booksystem.db", "booksystem.db"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Database.DSN = tt.dsn
+		cfg.Redis.Password = "secret"
+		redacted := cfg.Redacted()
+		if redacted.Database.DSN != tt.want || redacted.Redis.Password != "******" {
+			t.Errorf("Redacted(%q) = %q, redis %q; want %q", tt.dsn, redacted.Database.DSN, redacted.Redis.Password, tt.want)
+		}
+		if cfg.Database.DSN != tt.dsn || cfg.Redis.Password != "secret" {
+			t.Errorf("Redacted modified the original config")
+		}
+	}
+}
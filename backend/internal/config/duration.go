@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration 配置文件中写作 30s、10m、2h 等形式的时长
+type Duration time.Duration
+
+// D 转换为 time.Duration
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalYAML 输出为 2h0m0s 形式
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML 解析 2h 形式的时长，0 可以不带单位
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	v, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q, expected a value like 30s, 10m or 2h", node.Line, node.Value)
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// hookPool 包装 *sql.DB，开启的事务支持提交后回调（见 AfterCommit）
+type hookPool struct {
+	*sql.DB
+}
+
+// BeginTx 实现 gorm.ConnPoolBeginner，显式事务和 GORM 默认事务都经过这里
+func (p hookPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &hookTx{Tx: tx, db: p.DB}, nil
+}
+
+// GetDBConn 实现 gorm.GetDBConnector，使 (*gorm.DB).DB() 仍返回底层连接池
+func (p hookPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+// hookTx 包装 *sql.Tx，提交成功后依次执行登记的回调，回滚时丢弃
+type hookTx struct {
+	*sql.Tx
+	db *sql.DB
+
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (t *hookTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	hooks := t.hooks
+	t.hooks = nil
+	t.mu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+	return nil
+}
+
+func (t *hookTx) Rollback() error {
+	t.mu.Lock()
+	t.hooks = nil
+	t.mu.Unlock()
+	return t.Tx.Rollback()
+}
+
+func (t *hookTx) GetDBConn() (*sql.DB, error) {
+	return t.db, nil
+}
+
+// AfterCommit 在 tx 所在事务提交后执行 fn；事务回滚时不执行。
+// 不在事务中（语句已自动提交）时立即执行。用于缓存失效等必须在其他连接能看到新数据后才做的事。
+func AfterCommit(tx *gorm.DB, fn func()) {
+	t, ok := tx.Statement.ConnPool.(*hookTx)
+	if !ok {
+		fn()
+		return
+	}
+	t.mu.Lock()
+	t.hooks = append(t.hooks, fn)
+	t.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAfterCommit(t *testing.T) {
+	database, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Logger = logger.Discard
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("DB() on the wrapped pool: %v", err)
+	}
+	defer sqlDB.Close()
+	if err := database.AutoMigrate(&Area{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	record := func(name string) func(tx *gorm.DB) error {
+		return func(tx *gorm.DB) error {
+			if err := tx.Create(&Area{Name: name}).Error; err != nil {
+				return err
+			}
+			before := len(calls)
+			AfterCommit(tx, func() { calls = append(calls, name) })
+			if len(calls) != before {
+				t.Errorf("%s: hook ran before commit", name)
+			}
+			return nil
+		}
+	}
+
+	if err := database.Transaction(record("committed")); err != nil {
+		t.Fatal(err)
+	}
+	database.Transaction(func(tx *gorm.DB) error {
+		record("rolled back")(tx)
+		return gorm.ErrInvalidData
+	})
+	if len(calls) != 1 || calls[0] != "committed" {
+		t.Errorf("hooks run = %q, want [committed]", calls)
+	}
+
+	// 不在事务中时立即执行
+	ran := false
+	AfterCommit(database, func() { ran = true })
+	if !ran {
+		t.Error("AfterCommit outside a transaction did not run")
+	}
+}
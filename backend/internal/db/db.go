@@ -1,3 +1,48 @@
 package db
 
-// 数据库相关工具函数可以放在这里
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动
+const (
+	DriverSQLite   = "sqlite"
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+)
+
+// Open 按驱动打开数据库。dsn 的格式由驱动决定：
+//   - sqlite：数据库文件路径，如 booksystem.db
+//   - mysql：如 user:pass@tcp(127.0.0.1:3306)/booksystem?charset=utf8mb4&parseTime=True&loc=Local（必须带 parseTime=True）
+//   - postgres：如 host=127.0.0.1 user=booksystem password=secret dbname=booksystem port=5432 sslmode=disable
+//
+// 唯一索引冲突等错误会转换为 gorm.ErrDuplicatedKey 等统一错误，处理器不依赖各驱动的错误信息。
+func Open(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverSQLite, "":
+		dialector = sqlite.Open(dsn)
+	case DriverMySQL:
+		dialector = mysql.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (supported: sqlite, mysql, postgres)", driver)
+	}
+	database, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	// 包装连接池以支持 AfterCommit
+	if sqlDB, ok := database.ConnPool.(*sql.DB); ok {
+		database.ConnPool = hookPool{sqlDB}
+		database.Statement.ConnPool = database.ConnPool
+	}
+	return database, nil
+}
@@ -0,0 +1,272 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"booksystem/internal/db"
+)
+
+// 0001 基线：引入版本化迁移之前 AutoMigrate 生成的表结构。
+// 对已由 AutoMigrate 建好的数据库执行时只补齐缺少的列和索引，并为旧数据生成层位置码。
+
+type area0001 struct {
+	ID        int64   `gorm:"primaryKey;autoIncrement"`
+	Name      string  `gorm:"type:varchar(50);not null;unique"`
+	Code      *string `gorm:"type:varchar(16)"`
+	SortOrder int     `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (area0001) TableName() string { return "areas" }
+
+type bookshelf0001 struct {
+	ID        int64    `gorm:"primaryKey;autoIncrement"`
+	AreaID    int64    `gorm:"not null;index;uniqueIndex:uk_area_name,priority:1"`
+	Area      area0001 `gorm:"foreignKey:AreaID"`
+	Name      string   `gorm:"type:varchar(50);not null;uniqueIndex:uk_area_name,priority:2"`
+	Code      *string  `gorm:"type:varchar(16)"`
+	SortOrder int      `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (bookshelf0001) TableName() string { return "bookshelves" }
+
+type shelfLayer0001 struct {
+	ID          int64         `gorm:"primaryKey;autoIncrement"`
+	BookshelfID int64         `gorm:"not null;index;uniqueIndex:uk_bookshelf_name,priority:1"`
+	Bookshelf   bookshelf0001 `gorm:"foreignKey:BookshelfID"`
+	Name        string        `gorm:"type:varchar(50);not null;uniqueIndex:uk_bookshelf_name,priority:2"`
+	LabelCode   *string       `gorm:"type:varchar(32);uniqueIndex"`
+	Code        *string       `gorm:"type:varchar(16)"`
+	SortOrder   int           `gorm:"not null;default:0"`
+	Capacity    *int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (shelfLayer0001) TableName() string { return "shelf_layers" }
+
+type book0001 struct {
+	ID           int64          `gorm:"primaryKey;autoIncrement"`
+	Barcode      string         `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Name         string         `gorm:"type:varchar(200);not null"`
+	Quantity     int            `gorm:"not null;default:0"`
+	InStock      int            `gorm:"not null;default:0"`
+	ShelfLayerID *int64         `gorm:"index"`
+	ShelfLayer   shelfLayer0001 `gorm:"foreignKey:ShelfLayerID"`
+	Price        *float64       `gorm:"type:decimal(10,2)"`
+	Remark       *string        `gorm:"type:text"`
+	PublisherID  *int64         `gorm:"index"`
+	Publisher    *publisher0001 `gorm:"foreignKey:PublisherID"`
+	PublishYear  *int
+	Edition      *string          `gorm:"type:varchar(50)"`
+	Language     *string          `gorm:"type:varchar(20);index"`
+	ClassNumber  *string          `gorm:"type:varchar(50);index"`
+	MarcRaw      *string          `gorm:"type:text"`
+	Attachments  []attachment0001 `gorm:"foreignKey:BookID"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (book0001) TableName() string { return "books" }
+
+// 多对多关联表单独定义，列名和外键名与模型中的 many2many 关联生成的一致
+
+type bookAuthor0001 struct {
+	BookID   int64      `gorm:"primaryKey;autoIncrement:false"`
+	Book     book0001   `gorm:"foreignKey:BookID"`
+	AuthorID int64      `gorm:"primaryKey;autoIncrement:false"`
+	Author   author0001 `gorm:"foreignKey:AuthorID"`
+}
+
+func (bookAuthor0001) TableName() string { return "book_authors" }
+
+type bookTag0001 struct {
+	BookID int64    `gorm:"primaryKey;autoIncrement:false"`
+	Book   book0001 `gorm:"foreignKey:BookID"`
+	TagID  int64    `gorm:"primaryKey;autoIncrement:false"`
+	Tag    tag0001  `gorm:"foreignKey:TagID"`
+}
+
+func (bookTag0001) TableName() string { return "book_tags" }
+
+type bookCategory0001 struct {
+	BookID     int64        `gorm:"primaryKey;autoIncrement:false"`
+	Book       book0001     `gorm:"foreignKey:BookID"`
+	CategoryID int64        `gorm:"primaryKey;autoIncrement:false"`
+	Category   category0001 `gorm:"foreignKey:CategoryID"`
+}
+
+func (bookCategory0001) TableName() string { return "book_categories" }
+
+type attachment0001 struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement"`
+	BookID       int64  `gorm:"not null;index"`
+	Kind         string `gorm:"type:varchar(20);not null;index"`
+	FileName     string `gorm:"type:varchar(255);not null"`
+	ContentType  string `gorm:"type:varchar(100);not null"`
+	Size         int64  `gorm:"not null"`
+	Width        *int
+	Height       *int
+	StorageKey   string  `gorm:"type:varchar(255);not null"`
+	ThumbnailKey *string `gorm:"type:varchar(255)"`
+	CreatedAt    time.Time
+}
+
+func (attachment0001) TableName() string { return "attachments" }
+
+type author0001 struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (author0001) TableName() string { return "authors" }
+
+type publisher0001 struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(200);not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (publisher0001) TableName() string { return "publishers" }
+
+type tag0001 struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(50);not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (tag0001) TableName() string { return "tags" }
+
+type category0001 struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(50);not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (category0001) TableName() string { return "categories" }
+
+type barcodeSequence0001 struct {
+	Prefix    string `gorm:"type:varchar(20);primaryKey"`
+	NextValue int64  `gorm:"not null;default:1"`
+	UpdatedAt time.Time
+}
+
+func (barcodeSequence0001) TableName() string { return "barcode_sequences" }
+
+type borrowRecord0001 struct {
+	ID            int64              `gorm:"primaryKey;autoIncrement"`
+	BorrowerName  string             `gorm:"type:varchar(50);not null"`
+	BorrowerPhone string             `gorm:"type:varchar(20);not null;index"`
+	BorrowTime    time.Time          `gorm:"not null;index"`
+	Status        int8               `gorm:"not null;default:1"`
+	Details       []borrowDetail0001 `gorm:"foreignKey:BorrowRecordID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (borrowRecord0001) TableName() string { return "borrow_records" }
+
+type borrowDetail0001 struct {
+	ID             int64     `gorm:"primaryKey;autoIncrement"`
+	BorrowRecordID int64     `gorm:"not null;index"`
+	BookID         *int64    `gorm:"index"`
+	Book           *book0001 `gorm:"foreignKey:BookID"`
+	Barcode        string    `gorm:"type:varchar(100);not null;index"`
+	CreatedAt      time.Time
+}
+
+func (borrowDetail0001) TableName() string { return "borrow_details" }
+
+type borrower0001 struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(50);not null"`
+	Phone     string `gorm:"type:varchar(20);not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (borrower0001) TableName() string { return "borrowers" }
+
+type shelvingLog0001 struct {
+	ID          int64           `gorm:"primaryKey;autoIncrement"`
+	BatchID     string          `gorm:"type:varchar(32);not null;index"`
+	BookID      int64           `gorm:"not null;index"`
+	Book        *book0001       `gorm:"foreignKey:BookID"`
+	Barcode     string          `gorm:"type:varchar(100);not null"`
+	FromLayerID *int64          `gorm:"index"`
+	FromLayer   *shelfLayer0001 `gorm:"foreignKey:FromLayerID"`
+	ToLayerID   int64           `gorm:"not null;index"`
+	ToLayer     *shelfLayer0001 `gorm:"foreignKey:ToLayerID"`
+	CreatedAt   time.Time       `gorm:"index"`
+}
+
+func (shelvingLog0001) TableName() string { return "shelving_logs" }
+
+// baselineTables 基线中的表，按依赖顺序排列
+func baselineTables() []interface{} {
+	return []interface{}{
+		&area0001{},
+		&bookshelf0001{},
+		&shelfLayer0001{},
+		&author0001{},
+		&publisher0001{},
+		&tag0001{},
+		&category0001{},
+		&book0001{},
+		&bookAuthor0001{},
+		&bookTag0001{},
+		&bookCategory0001{},
+		&attachment0001{},
+		&barcodeSequence0001{},
+		&borrowRecord0001{},
+		&borrowDetail0001{},
+		&borrower0001{},
+		&shelvingLog0001{},
+	}
+}
+
+// baselineUp 创建基线表结构。添加同级名称唯一索引前先检查已有数据，有重名时返回 db.DuplicateNameError。
+func baselineUp(tx *gorm.DB) error {
+	if err := db.CheckDuplicateNames(tx, &bookshelf0001{}, "uk_area_name", "area_id"); err != nil {
+		return err
+	}
+	if err := db.CheckDuplicateNames(tx, &shelfLayer0001{}, "uk_bookshelf_name", "bookshelf_id"); err != nil {
+		return err
+	}
+	if err := tx.AutoMigrate(baselineTables()...); err != nil {
+		return err
+	}
+
+	// 为添加位置码之前创建的层生成位置码
+	var ids []int64
+	if err := tx.Model(&shelfLayer0001{}).Where("label_code IS NULL").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := tx.Model(&shelfLayer0001{}).Where("id = ?", id).
+			UpdateColumn("label_code", db.ShelfLayerCode(id)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// baselineDown 删除全部表，数据随之删除
+func baselineDown(tx *gorm.DB) error {
+	tables := baselineTables()
+	drop := make([]interface{}, 0, len(tables))
+	for i := len(tables) - 1; i >= 0; i-- {
+		drop = append(drop, tables[i])
+	}
+	return tx.Migrator().DropTable(drop...)
+}
@@ -0,0 +1,174 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 0002 外键删除规则和库存检查约束，与 database/init.sql 一致：
+// 删除区域/书架时级联删除下级，删除层或图书时相关记录的引用置空，删除借阅记录时级联删除明细，在库数量不超过总数量。
+// 基线中 AutoMigrate 创建的同名外键没有删除规则，这里先删除再按新规则重建。
+
+type area0002 struct {
+	ID int64
+}
+
+func (area0002) TableName() string { return "areas" }
+
+type bookshelf0002 struct {
+	ID     int64
+	AreaID int64
+	Area   area0002 `gorm:"foreignKey:AreaID;constraint:OnDelete:CASCADE"`
+}
+
+func (bookshelf0002) TableName() string { return "bookshelves" }
+
+type shelfLayer0002 struct {
+	ID          int64
+	BookshelfID int64
+	Bookshelf   bookshelf0002 `gorm:"foreignKey:BookshelfID;constraint:OnDelete:CASCADE"`
+}
+
+func (shelfLayer0002) TableName() string { return "shelf_layers" }
+
+type book0002 struct {
+	ID           int64
+	Quantity     int
+	InStock      int `gorm:"check:chk_books_in_stock,in_stock <= quantity"`
+	ShelfLayerID *int64
+	ShelfLayer   shelfLayer0002 `gorm:"foreignKey:ShelfLayerID;constraint:OnDelete:SET NULL"`
+}
+
+func (book0002) TableName() string { return "books" }
+
+type borrowRecord0002 struct {
+	ID      int64
+	Details []borrowDetail0002 `gorm:"foreignKey:BorrowRecordID;constraint:OnDelete:CASCADE"`
+}
+
+func (borrowRecord0002) TableName() string { return "borrow_records" }
+
+type borrowDetail0002 struct {
+	ID             int64
+	BorrowRecordID int64
+	BookID         *int64
+	Book           *book0002 `gorm:"foreignKey:BookID;constraint:OnDelete:SET NULL"`
+}
+
+func (borrowDetail0002) TableName() string { return "borrow_details" }
+
+// foreignKeyChange 一个外键在基线和 0002 中的定义，name 为关系字段名
+type foreignKeyChange struct {
+	table    string
+	baseline interface{}
+	current  interface{}
+	name     string
+}
+
+var foreignKeyChanges = []foreignKeyChange{
+	{"bookshelves", &bookshelf0001{}, &bookshelf0002{}, "Area"},
+	{"shelf_layers", &shelfLayer0001{}, &shelfLayer0002{}, "Bookshelf"},
+	{"books", &book0001{}, &book0002{}, "ShelfLayer"},
+	{"borrow_details", &borrowRecord0001{}, &borrowRecord0002{}, "Details"},
+	{"borrow_details", &borrowDetail0001{}, &borrowDetail0002{}, "Book"},
+}
+
+func foreignKeysUp(tx *gorm.DB) error {
+	var invalid int64
+	if err := tx.Model(&book0002{}).Where("in_stock > quantity").Count(&invalid).Error; err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d books have in_stock greater than quantity, fix them before adding chk_books_in_stock", invalid)
+	}
+
+	for _, fk := range foreignKeyChanges {
+		if err := replaceConstraint(tx, fk.table, fk.baseline, fk.current, fk.name); err != nil {
+			return err
+		}
+	}
+	return keepTableState(tx, "books", func() error {
+		return tx.Migrator().CreateConstraint(&book0002{}, "chk_books_in_stock")
+	})
+}
+
+func foreignKeysDown(tx *gorm.DB) error {
+	if err := keepTableState(tx, "books", func() error {
+		return tx.Migrator().DropConstraint(&book0002{}, "chk_books_in_stock")
+	}); err != nil {
+		return err
+	}
+	for i := len(foreignKeyChanges) - 1; i >= 0; i-- {
+		fk := foreignKeyChanges[i]
+		if err := replaceConstraint(tx, fk.table, fk.current, fk.baseline, fk.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceConstraint 删除 from 中定义的外键（存在时），按 to 中的定义重新创建
+func replaceConstraint(tx *gorm.DB, table string, from, to interface{}, name string) error {
+	return keepTableState(tx, table, func() error {
+		migrator := tx.Migrator()
+		if migrator.HasConstraint(from, name) {
+			if err := migrator.DropConstraint(from, name); err != nil {
+				return err
+			}
+		}
+		return migrator.CreateConstraint(to, name)
+	})
+}
+
+// keepTableState SQLite 不支持修改约束，驱动通过重建表实现，重建会丢失表上的索引和自增序号。
+// 这里在 fn 前后保存并恢复它们，其他数据库直接执行 fn。
+// 启用了外键检查时删除旧表会触发级联删除，因此拒绝执行。
+func keepTableState(tx *gorm.DB, table string, fn func() error) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return fn()
+	}
+
+	var foreignKeys int
+	if err := tx.Raw("PRAGMA foreign_keys").Scan(&foreignKeys).Error; err != nil {
+		return err
+	}
+	if foreignKeys != 0 {
+		return fmt.Errorf("rebuilding %s with foreign_keys enabled would cascade deletes, remove the foreign_keys pragma from DB_DSN and retry", table)
+	}
+
+	type index struct {
+		Name string
+		SQL  string
+	}
+	var indexes []index
+	if err := tx.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table).
+		Scan(&indexes).Error; err != nil {
+		return err
+	}
+	var seq []int64
+	if err := tx.Raw("SELECT seq FROM sqlite_sequence WHERE name = ?", table).Scan(&seq).Error; err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		var count int64
+		if err := tx.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = ?", idx.Name).
+			Scan(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := tx.Exec(idx.SQL).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if len(seq) > 0 {
+		return tx.Exec("UPDATE sqlite_sequence SET seq = ? WHERE name = ? AND seq < ?", seq[0], table, seq[0]).Error
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0003 管理员账号表，账号通过 booksystem create-admin 创建
+
+type admin0003 struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement"`
+	Username     string `gorm:"type:varchar(50);not null;uniqueIndex"`
+	PasswordHash string `gorm:"type:varchar(100);not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (admin0003) TableName() string { return "admins" }
+
+func adminsUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&admin0003{})
+}
+
+func adminsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&admin0003{})
+}
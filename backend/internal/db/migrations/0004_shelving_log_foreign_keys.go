@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 0004 上架记录的外键删除规则：删除图书时级联删除其上架记录（记录只描述该书的位置变更），
+// 删除层时记录中的原位置/新位置置空，保留记录本身。新位置因此改为可空。
+// 基线中这三个外键没有删除规则，启用外键检查后删除有上架记录的图书或层会失败。
+
+type shelvingLog0004 struct {
+	ID          int64
+	BookID      int64
+	Book        *book0002 `gorm:"foreignKey:BookID;constraint:OnDelete:CASCADE"`
+	FromLayerID *int64
+	FromLayer   *shelfLayer0002 `gorm:"foreignKey:FromLayerID;constraint:OnDelete:SET NULL"`
+	ToLayerID   *int64          `gorm:"index"`
+	ToLayer     *shelfLayer0002 `gorm:"foreignKey:ToLayerID;constraint:OnDelete:SET NULL"`
+}
+
+func (shelvingLog0004) TableName() string { return "shelving_logs" }
+
+// shelvingLogRelations shelving_logs 上的外键，按关系字段名
+var shelvingLogRelations = []string{"Book", "FromLayer", "ToLayer"}
+
+func shelvingLogForeignKeysUp(tx *gorm.DB) error {
+	return replaceShelvingLogConstraints(tx, &shelvingLog0001{}, &shelvingLog0004{})
+}
+
+func shelvingLogForeignKeysDown(tx *gorm.DB) error {
+	var orphaned int64
+	if err := tx.Model(&shelvingLog0004{}).Where("to_layer_id IS NULL").Count(&orphaned).Error; err != nil {
+		return err
+	}
+	if orphaned > 0 {
+		return fmt.Errorf("%d shelving logs point to deleted layers, delete them before making to_layer_id NOT NULL", orphaned)
+	}
+	return replaceShelvingLogConstraints(tx, &shelvingLog0004{}, &shelvingLog0001{})
+}
+
+// replaceShelvingLogConstraints 删除 from 中定义的外键，按 to 修改 to_layer_id 后重建外键。
+// MySQL 不允许修改外键引用的列，因此先删除外键再修改列。
+func replaceShelvingLogConstraints(tx *gorm.DB, from, to interface{}) error {
+	return keepTableState(tx, "shelving_logs", func() error {
+		migrator := tx.Migrator()
+		for _, name := range shelvingLogRelations {
+			if migrator.HasConstraint(from, name) {
+				if err := migrator.DropConstraint(from, name); err != nil {
+					return err
+				}
+			}
+		}
+		if err := migrator.AlterColumn(to, "ToLayerID"); err != nil {
+			return err
+		}
+		for _, name := range shelvingLogRelations {
+			if err := migrator.CreateConstraint(to, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
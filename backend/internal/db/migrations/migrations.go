@@ -0,0 +1,25 @@
+// Package migrations 数据库表结构的版本化迁移。
+// 每个迁移使用自己的表结构快照（而不是 db 包中的模型），模型以后变化时已有迁移的行为不变。
+// 新增迁移时在 All 末尾追加，版本号递增，已发布的迁移不再修改。
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"booksystem/internal/migrate"
+)
+
+// All 全部迁移，按版本号排列
+func All() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+		{Version: 2, Name: "foreign_keys", Up: foreignKeysUp, Down: foreignKeysDown},
+		{Version: 3, Name: "admins", Up: adminsUp, Down: adminsDown},
+		{Version: 4, Name: "shelving_log_foreign_keys", Up: shelvingLogForeignKeysUp, Down: shelvingLogForeignKeysDown},
+	}
+}
+
+// New 创建执行全部迁移的 Migrator
+func New(db *gorm.DB) (*migrate.Migrator, error) {
+	return migrate.New(db, All())
+}
@@ -0,0 +1,139 @@
+package migrations
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"booksystem/internal/db"
+)
+
+// openSQLite 打开 path 处的 SQLite 数据库，foreignKeys 为 true 时启用外键检查
+func openSQLite(t *testing.T, path string, foreignKeys bool) *gorm.DB {
+	t.Helper()
+	dsn := path
+	if foreignKeys {
+		dsn += "?_pragma=foreign_keys(1)"
+	}
+	database, err := db.Open(db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return database
+}
+
+func mustCreate(t *testing.T, database *gorm.DB, values ...interface{}) {
+	t.Helper()
+	for _, v := range values {
+		if err := database.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	database := openSQLite(t, filepath.Join(t.TempDir(), "test.db"), false)
+	migrator, err := New(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := All()
+	if applied, err := migrator.Up(0); err != nil || len(applied) != len(all) {
+		t.Fatalf("Up(0) applied %d migrations, err %v; want %d", len(applied), err, len(all))
+	}
+	if _, err := migrator.Down(len(all)); err != nil {
+		t.Fatal(err)
+	}
+	if database.Migrator().HasTable("books") {
+		t.Error("books table left after rolling back every migration")
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up after a full rollback: %v", err)
+	}
+}
+
+func TestShelvingLogForeignKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	plain := openSQLite(t, path, false)
+	migrator, err := New(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0004 之前已有的上架记录在重建表后保留
+	area := db.Area{Name: "A"}
+	mustCreate(t, plain, &area)
+	shelf := db.Bookshelf{AreaID: area.ID, Name: "1"}
+	mustCreate(t, plain, &shelf)
+	from, to := db.ShelfLayer{BookshelfID: shelf.ID, Name: "1"}, db.ShelfLayer{BookshelfID: shelf.ID, Name: "2"}
+	mustCreate(t, plain, &from, &to)
+	book := db.Book{Barcode: "LIB000001", Name: "x", Quantity: 1, InStock: 1, ShelfLayerID: &to.ID}
+	mustCreate(t, plain, &book)
+	log := db.ShelvingLog{BatchID: "b", BookID: book.ID, Barcode: book.Barcode, FromLayerID: &from.ID, ToLayerID: &to.ID}
+	mustCreate(t, plain, &log)
+
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+
+	enforced := openSQLite(t, path, true)
+	loaded := func() (db.ShelvingLog, bool) {
+		t.Helper()
+		var got []db.ShelvingLog
+		if err := enforced.Where("id = ?", log.ID).Find(&got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(got) == 0 {
+			return db.ShelvingLog{}, false
+		}
+		return got[0], true
+	}
+	if got, ok := loaded(); !ok || got.FromLayerID == nil || got.ToLayerID == nil {
+		t.Fatalf("log after migration = %+v (found %v), want both layers set", got, ok)
+	}
+
+	// 删除层：记录保留，对应位置置空
+	if err := enforced.Delete(&from).Error; err != nil {
+		t.Fatalf("delete from layer: %v", err)
+	}
+	if got, _ := loaded(); got.FromLayerID != nil || got.ToLayerID == nil {
+		t.Errorf("after deleting from layer: from %v to %v, want from nil", got.FromLayerID, got.ToLayerID)
+	}
+	if err := enforced.Delete(&to).Error; err != nil {
+		t.Fatalf("delete to layer: %v", err)
+	}
+	if got, ok := loaded(); !ok || got.ToLayerID != nil {
+		t.Errorf("after deleting to layer: log %+v (found %v), want to nil", got, ok)
+	}
+
+	// 新位置为空时不能回滚
+	if _, err := migrator.Down(1); err == nil {
+		t.Error("Down(1) with a NULL to_layer_id succeeded, want an error")
+	}
+
+	// 删除图书：记录随之删除
+	if err := enforced.Delete(&book).Error; err != nil {
+		t.Fatalf("delete book: %v", err)
+	}
+	if _, ok := loaded(); ok {
+		t.Error("log left after deleting its book")
+	}
+
+	if _, err := migrator.Down(1); err != nil {
+		t.Fatalf("Down(1): %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up after Down(1): %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package db
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -10,6 +13,8 @@ import (
 type Area struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name      string    `gorm:"type:varchar(50);not null;unique" json:"name"`
+	Code      *string   `gorm:"type:varchar(16)" json:"code"`         // 简码，用于组成位置编号，如 A-03-2 中的 A
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"` // 排序值，相同时按名称自然排序
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -17,9 +22,11 @@ type Area struct {
 // Bookshelf 书架表
 type Bookshelf struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	AreaID    int64     `gorm:"not null;index" json:"area_id"`
+	AreaID    int64     `gorm:"not null;index;uniqueIndex:uk_area_name,priority:1" json:"area_id"`
 	Area      Area      `gorm:"foreignKey:AreaID" json:"area,omitempty"`
-	Name      string    `gorm:"type:varchar(50);not null" json:"name"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_area_name,priority:2" json:"name"` // 同一区域内唯一
+	Code      *string   `gorm:"type:varchar(16)" json:"code"`
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -27,26 +34,110 @@ type Bookshelf struct {
 // ShelfLayer 层数表
 type ShelfLayer struct {
 	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	BookshelfID int64     `gorm:"not null;index" json:"bookshelf_id"`
+	BookshelfID int64     `gorm:"not null;index;uniqueIndex:uk_bookshelf_name,priority:1" json:"bookshelf_id"`
 	Bookshelf   Bookshelf `gorm:"foreignKey:BookshelfID" json:"bookshelf,omitempty"`
-	Name        string    `gorm:"type:varchar(50);not null" json:"name"`
+	Name        string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_bookshelf_name,priority:2" json:"name"` // 同一书架内唯一
+	LabelCode   *string   `gorm:"type:varchar(32);uniqueIndex" json:"label_code"`                                 // 位置码，创建后自动生成且不再变化
+	Code        *string   `gorm:"type:varchar(16)" json:"code"`
+	SortOrder   int       `gorm:"not null;default:0" json:"sort_order"`
+	Capacity    *int      `json:"capacity"` // 容量（册），为空表示不限
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// ShelfLayerCodePrefix 层位置码前缀，用于和图书一维码区分
+const ShelfLayerCodePrefix = "LOC"
+
+// ShelfLayerCode 按层ID生成位置码，如 LOC000012
+func ShelfLayerCode(id int64) string {
+	return fmt.Sprintf("%s%06d", ShelfLayerCodePrefix, id)
+}
+
+// AfterCreate 新建层后生成位置码
+func (l *ShelfLayer) AfterCreate(tx *gorm.DB) error {
+	code := ShelfLayerCode(l.ID)
+	l.LabelCode = &code
+	return tx.Model(l).UpdateColumn("label_code", code).Error
+}
+
 // Book 图书表
 type Book struct {
-	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Barcode      string     `gorm:"type:varchar(100);not null;uniqueIndex" json:"barcode"`
-	Name         string     `gorm:"type:varchar(200);not null" json:"name"`
-	Quantity     int        `gorm:"not null;default:0" json:"quantity"`
-	InStock      int        `gorm:"not null;default:0" json:"in_stock"`
-	ShelfLayerID *int64     `gorm:"index" json:"shelf_layer_id,omitempty"`
-	ShelfLayer   ShelfLayer `gorm:"foreignKey:ShelfLayerID" json:"shelf_layer,omitempty"`
-	Price        *float64   `gorm:"type:decimal(10,2)" json:"price,omitempty"`
-	Remark       *string    `gorm:"type:text" json:"remark,omitempty"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID           int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Barcode      string       `gorm:"type:varchar(100);not null;uniqueIndex" json:"barcode"`
+	Name         string       `gorm:"type:varchar(200);not null" json:"name"`
+	Quantity     int          `gorm:"not null;default:0" json:"quantity"`
+	InStock      int          `gorm:"not null;default:0" json:"in_stock"`
+	ShelfLayerID *int64       `gorm:"index" json:"shelf_layer_id,omitempty"`
+	ShelfLayer   ShelfLayer   `gorm:"foreignKey:ShelfLayerID" json:"shelf_layer,omitempty"`
+	Price        *float64     `gorm:"type:decimal(10,2)" json:"price,omitempty"`
+	Remark       *string      `gorm:"type:text" json:"remark,omitempty"`
+	Authors      []Author     `gorm:"many2many:book_authors" json:"authors,omitempty"`
+	PublisherID  *int64       `gorm:"index" json:"publisher_id,omitempty"`
+	Publisher    *Publisher   `gorm:"foreignKey:PublisherID" json:"publisher,omitempty"`
+	PublishYear  *int         `json:"publish_year,omitempty"`
+	Edition      *string      `gorm:"type:varchar(50)" json:"edition,omitempty"`
+	Language     *string      `gorm:"type:varchar(20);index" json:"language,omitempty"`
+	ClassNumber  *string      `gorm:"type:varchar(50);index" json:"class_number,omitempty"` // 分类号（如中图法）
+	Tags         []Tag        `gorm:"many2many:book_tags" json:"tags,omitempty"`
+	Categories   []Category   `gorm:"many2many:book_categories" json:"categories,omitempty"`
+	MarcRaw      *string      `gorm:"type:text" json:"-"` // 导入的原始MARC记录（JSON），导出时保留未映射字段
+	Attachments  []Attachment `gorm:"foreignKey:BookID" json:"attachments,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// Attachment 图书附件表（封面、目录扫描件等），文件保存在附件存储中
+type Attachment struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	BookID       int64     `gorm:"not null;index" json:"book_id"`
+	Kind         string    `gorm:"type:varchar(20);not null;index" json:"kind"` // cover:封面，toc:目录，other:其他
+	FileName     string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType  string    `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Width        *int      `json:"width,omitempty"` // 图片尺寸，PDF 为空
+	Height       *int      `json:"height,omitempty"`
+	StorageKey   string    `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbnailKey *string   `gorm:"type:varchar(255)" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Author 作者表
+type Author struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Publisher 出版社表
+type Publisher struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(200);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Tag 标签表
+type Tag struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Category 分类表
+type Category struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BarcodeSequence 馆内条码序号表，每个前缀一行
+type BarcodeSequence struct {
+	Prefix    string    `gorm:"type:varchar(20);primaryKey" json:"prefix"`
+	NextValue int64     `gorm:"not null;default:1" json:"next_value"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // BorrowRecord 借阅记录表
@@ -71,6 +162,21 @@ type BorrowDetail struct {
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// ShelvingLog 上架记录表，记录扫码上架时图书位置的变更。
+// 删除图书时其记录随之删除，删除层时原位置/新位置置空
+type ShelvingLog struct {
+	ID          int64       `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchID     string      `gorm:"type:varchar(32);not null;index" json:"batch_id"` // 同一次提交的记录共用批次号
+	BookID      int64       `gorm:"not null;index" json:"book_id"`
+	Book        *Book       `gorm:"foreignKey:BookID" json:"book,omitempty"`
+	Barcode     string      `gorm:"type:varchar(100);not null" json:"barcode"`
+	FromLayerID *int64      `gorm:"index" json:"from_layer_id,omitempty"`
+	FromLayer   *ShelfLayer `gorm:"foreignKey:FromLayerID" json:"from_layer,omitempty"`
+	ToLayerID   *int64      `gorm:"index" json:"to_layer_id,omitempty"`
+	ToLayer     *ShelfLayer `gorm:"foreignKey:ToLayerID" json:"to_layer,omitempty"`
+	CreatedAt   time.Time   `gorm:"index" json:"created_at"`
+}
+
 // Borrower 用户表
 type Borrower struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -80,15 +186,95 @@ type Borrower struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// AutoMigrate 自动迁移数据库表
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&Area{},
-		&Bookshelf{},
-		&ShelfLayer{},
-		&Book{},
-		&BorrowRecord{},
-		&BorrowDetail{},
-		&Borrower{},
-	)
+// Admin 管理员账号，密码只保存 bcrypt 哈希。接口尚未接入管理员认证，目前只有 create-admin 命令使用
+type Admin struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"username"`
+	PasswordHash string    `gorm:"type:varchar(100);not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AdminPasswordMinLength 管理员密码的最短长度
+const AdminPasswordMinLength = 8
+
+// SetPassword 校验密码长度并保存 bcrypt 哈希
+func (a *Admin) SetPassword(password string) error {
+	if len(password) < AdminPasswordMinLength {
+		return fmt.Errorf("password must be at least %d characters", AdminPasswordMinLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	a.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword 验证密码是否正确
+func (a *Admin) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) == nil
+}
+
+// DuplicateName 同一上级下重名的一组节点
+type DuplicateName struct {
+	ParentID int64
+	Name     string
+	IDs      []int64
+}
+
+// DuplicateNameError 已有数据违反同级名称唯一约束，需先改名或合并后才能建立唯一索引
+type DuplicateNameError struct {
+	Table        string
+	ParentColumn string
+	Duplicates   []DuplicateName
+}
+
+func (e *DuplicateNameError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s 中有 %d 组同级重名，请先改名或合并后再执行迁移:", e.Table, len(e.Duplicates))
+	for _, d := range e.Duplicates {
+		fmt.Fprintf(&b, " [%s=%d name=%q ids=%v]", e.ParentColumn, d.ParentID, d.Name, d.IDs)
+	}
+	return b.String()
+}
+
+// CheckDuplicateNames 唯一索引 index 尚未建立时，检查表中同一 parentColumn 下是否有重名
+func CheckDuplicateNames(db *gorm.DB, model interface{}, index, parentColumn string) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(model) || migrator.HasIndex(model, index) {
+		return nil
+	}
+
+	type group struct {
+		ParentID int64
+		Name     string
+	}
+	var groups []group
+	if err := db.Model(model).
+		Select(parentColumn + " AS parent_id, name").
+		Group(parentColumn + ", name").
+		Having("COUNT(*) > 1").
+		Order(parentColumn + ", name").
+		Scan(&groups).Error; err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	dupErr := &DuplicateNameError{Table: stmt.Schema.Table, ParentColumn: parentColumn}
+	for _, g := range groups {
+		d := DuplicateName{ParentID: g.ParentID, Name: g.Name}
+		if err := db.Model(model).Where(parentColumn+" = ? AND name = ?", g.ParentID, g.Name).
+			Order("id").Pluck("id", &d.IDs).Error; err != nil {
+			return err
+		}
+		dupErr.Duplicates = append(dupErr.Duplicates, d)
+	}
+	return dupErr
 }
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotEmpty 数据库中已有数据，不写入演示数据
+var ErrNotEmpty = errors.New("database already contains books or locations")
+
+// SeedResult 写入的演示数据数量
+type SeedResult struct {
+	Areas         int
+	Bookshelves   int
+	ShelfLayers   int
+	Books         int
+	Borrowers     int
+	BorrowRecords int
+}
+
+// demoBook 演示图书，layer 为层在 layers 中的下标
+type demoBook struct {
+	barcode   string
+	name      string
+	author    string
+	publisher string
+	year      int
+	class     string
+	tags      []string
+	quantity  int
+	layer     int
+}
+
+var demoBooks = []demoBook{
+	{"9787020002207", "红楼梦", "曹雪芹", "人民文学出版社", 1996, "I242.4", []string{"古典文学", "小说"}, 3, 0},
+	{"9787020011902", "西游记", "吴承恩", "人民文学出版社", 1980, "I242.4", []string{"古典文学", "小说"}, 2, 0},
+	{"9787536692930", "三体", "刘慈欣", "重庆出版社", 2008, "I247.5", []string{"科幻", "小说"}, 4, 1},
+	{"9787544253994", "百年孤独", "加西亚·马尔克斯", "南海出版公司", 2011, "I775.45", []string{"外国文学", "小说"}, 2, 1},
+	{"9787108009821", "万历十五年", "黄仁宇", "生活·读书·新知三联书店", 1997, "K248.3", []string{"历史"}, 2, 2},
+	{"9787111111115", "计算机程序设计艺术", "高德纳", "机械工业出版社", 2002, "TP311.1", []string{"计算机"}, 1, 3},
+	{"9787302123453", "数据结构", "严蔚敏", "清华大学出版社", 2007, "TP311.12", []string{"计算机", "教材"}, 5, 3},
+	{"9787513356787", "人类简史", "尤瓦尔·赫拉利", "中信出版社", 2014, "K02", []string{"历史", "外国文学"}, 2, 4},
+}
+
+// SeedDemo 写入演示数据：两个区域的书架和层、若干图书和一条未归还的借阅记录。
+// 数据库中已有图书或区域时返回 ErrNotEmpty，避免和真实数据混在一起。
+func SeedDemo(database *gorm.DB) (SeedResult, error) {
+	var result SeedResult
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var books, areas int64
+		if err := tx.Model(&Book{}).Count(&books).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Area{}).Count(&areas).Error; err != nil {
+			return err
+		}
+		if books > 0 || areas > 0 {
+			return ErrNotEmpty
+		}
+
+		var layers []ShelfLayer
+		for i, areaName := range []string{"一楼阅览室", "二楼书库"} {
+			area := Area{Name: areaName, Code: strPtr(string(rune('A' + i)))}
+			if err := tx.Create(&area).Error; err != nil {
+				return err
+			}
+			result.Areas++
+			for j, shelfName := range []string{"A架", "B架"} {
+				shelf := Bookshelf{AreaID: area.ID, Name: shelfName, Code: strPtr(string(rune('1' + j)))}
+				if err := tx.Create(&shelf).Error; err != nil {
+					return err
+				}
+				result.Bookshelves++
+				for k, layerName := range []string{"第1层", "第2层", "第3层"} {
+					capacity := 50
+					layer := ShelfLayer{BookshelfID: shelf.ID, Name: layerName, Code: strPtr(string(rune('1' + k))), Capacity: &capacity}
+					if err := tx.Create(&layer).Error; err != nil {
+						return err
+					}
+					layers = append(layers, layer)
+					result.ShelfLayers++
+				}
+			}
+		}
+
+		var created []Book
+		for _, demo := range demoBooks {
+			author := Author{Name: demo.author}
+			if err := tx.Where("name = ?", author.Name).FirstOrCreate(&author).Error; err != nil {
+				return err
+			}
+			publisher := Publisher{Name: demo.publisher}
+			if err := tx.Where("name = ?", publisher.Name).FirstOrCreate(&publisher).Error; err != nil {
+				return err
+			}
+			tags := make([]Tag, len(demo.tags))
+			for i, name := range demo.tags {
+				tags[i] = Tag{Name: name}
+				if err := tx.Where("name = ?", name).FirstOrCreate(&tags[i]).Error; err != nil {
+					return err
+				}
+			}
+			year := demo.year
+			book := Book{
+				Barcode:      demo.barcode,
+				Name:         demo.name,
+				Quantity:     demo.quantity,
+				InStock:      demo.quantity,
+				ShelfLayerID: &layers[demo.layer].ID,
+				Authors:      []Author{author},
+				PublisherID:  &publisher.ID,
+				PublishYear:  &year,
+				Language:     strPtr("chi"),
+				ClassNumber:  strPtr(demo.class),
+				Tags:         tags,
+			}
+			if err := tx.Omit("ShelfLayer", "Publisher").Create(&book).Error; err != nil {
+				return err
+			}
+			created = append(created, book)
+			result.Books++
+		}
+
+		borrower := Borrower{Name: "张三", Phone: "13800000000"}
+		if err := tx.Where("phone = ?", borrower.Phone).FirstOrCreate(&borrower).Error; err != nil {
+			return err
+		}
+		result.Borrowers++
+
+		borrowed := created[:2]
+		record := BorrowRecord{
+			BorrowerName:  borrower.Name,
+			BorrowerPhone: borrower.Phone,
+			BorrowTime:    time.Now().AddDate(0, 0, -3),
+			Status:        1,
+		}
+		for i := range borrowed {
+			record.Details = append(record.Details, BorrowDetail{BookID: &borrowed[i].ID, Barcode: borrowed[i].Barcode})
+		}
+		if err := tx.Omit("Details.Book").Create(&record).Error; err != nil {
+			return err
+		}
+		result.BorrowRecords++
+		for _, book := range borrowed {
+			if err := tx.Model(&Book{}).Where("id = ?", book.ID).
+				Update("in_stock", gorm.Expr("in_stock - 1")).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func strPtr(s string) *string {
+	return &s
+}
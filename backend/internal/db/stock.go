@@ -0,0 +1,52 @@
+package db
+
+import "gorm.io/gorm"
+
+// StockMismatch 在库数量与借阅明细不一致的图书
+type StockMismatch struct {
+	BookID   int64  `json:"book_id"`
+	Barcode  string `json:"barcode"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Borrowed int    `json:"borrowed"` // 未归还的借阅明细数
+	InStock  int    `json:"in_stock"` // 当前记录的在库数量
+	Expected int    `json:"expected"` // 按借阅明细计算的在库数量
+}
+
+// ReconcileStock 按未归还的借阅明细重新计算每本图书的在库数量（总数量减去借出数，不小于 0），
+// 返回不一致的图书；apply 为 true 时同时修正。归还时借阅明细会被删除，因此剩余的明细都是借出中的。
+func ReconcileStock(database *gorm.DB, apply bool) ([]StockMismatch, error) {
+	var mismatches []StockMismatch
+	err := database.Transaction(func(tx *gorm.DB) error {
+		borrowed := tx.Model(&BorrowDetail{}).
+			Select("book_id, COUNT(*) AS borrowed").
+			Where("book_id IS NOT NULL").
+			Group("book_id")
+		if err := tx.Table("books").
+			Select("books.id AS book_id, books.barcode, books.name, books.quantity, books.in_stock, COALESCE(b.borrowed, 0) AS borrowed").
+			Joins("LEFT JOIN (?) AS b ON b.book_id = books.id", borrowed).
+			Order("books.id").
+			Scan(&mismatches).Error; err != nil {
+			return err
+		}
+
+		n := 0
+		for _, m := range mismatches {
+			m.Expected = max(m.Quantity-m.Borrowed, 0)
+			if m.Expected == m.InStock {
+				continue
+			}
+			if apply {
+				if err := tx.Model(&Book{}).Where("id = ?", m.BookID).
+					Update("in_stock", m.Expected).Error; err != nil {
+					return err
+				}
+			}
+			mismatches[n] = m
+			n++
+		}
+		mismatches = mismatches[:n]
+		return nil
+	})
+	return mismatches, err
+}
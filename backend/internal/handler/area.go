@@ -7,6 +7,7 @@ import (
 	"github.com/cloudwego/hertz/pkg/app"
 	"gorm.io/gorm"
 
+	"booksystem/internal/apperr"
 	"booksystem/internal/db"
 )
 
@@ -21,37 +22,57 @@ func NewAreaHandler(db *gorm.DB) *AreaHandler {
 // CreateRequest 创建区域请求
 type CreateAreaRequest struct {
 	Name string `json:"name" binding:"required"`
+	Code string `json:"code"` // 简码，如 A
 }
 
 // UpdateAreaRequest 更新区域请求
 type UpdateAreaRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name string  `json:"name" binding:"required"`
+	Code *string `json:"code"` // 不传时保持不变，传空串表示清除
 }
 
 // Create 创建区域
 func (h *AreaHandler) Create(ctx context.Context, c *app.RequestContext) {
 	var req CreateAreaRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	code, err := normalizeLocationCode(req.Code)
+	if err != nil {
+		Fail(c, err)
 		return
 	}
 
-	area := db.Area{Name: req.Name}
+	area := db.Area{Name: req.Name, Code: code}
+	if taken, err := locationCodeTaken(h.db, &db.Area{}, "", 0, code, 0); err != nil {
+		Fail(c, err)
+		return
+	} else if taken {
+		Fail(c, apperr.New(apperr.DuplicateCode, "code", *code))
+		return
+	}
+	if area.SortOrder, err = nextSortOrder(h.db, &db.Area{}, "", 0); err != nil {
+		Fail(c, err)
+		return
+	}
 	if err := h.db.Create(&area).Error; err != nil {
-		Error(c, 400, "创建失败: "+err.Error())
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
 		return
 	}
 
 	Success(c, area)
 }
 
-// List 查询区域列表
+// List 查询区域列表，按排序值和名称排列
 func (h *AreaHandler) List(ctx context.Context, c *app.RequestContext) {
 	var areas []db.Area
 	if err := h.db.Find(&areas).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
+	sortAreas(areas)
 	Success(c, areas)
 }
 
@@ -59,28 +80,41 @@ func (h *AreaHandler) List(ctx context.Context, c *app.RequestContext) {
 func (h *AreaHandler) Update(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
 	var req UpdateAreaRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
 	var area db.Area
 	if err := h.db.First(&area, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			Error(c, 404, "区域不存在")
-		} else {
-			Error(c, 500, "查询失败: "+err.Error())
-		}
+		Fail(c, notFound(err, apperr.AreaNotFound))
 		return
 	}
 
-	if err := h.db.Model(&area).Update("name", req.Name).Error; err != nil {
-		Error(c, 400, "更新失败: "+err.Error())
+	updates := map[string]interface{}{"name": req.Name}
+	if req.Code != nil {
+		code, err := normalizeLocationCode(*req.Code)
+		if err != nil {
+			Fail(c, err)
+			return
+		}
+		if taken, err := locationCodeTaken(h.db, &db.Area{}, "", 0, code, id); err != nil {
+			Fail(c, err)
+			return
+		} else if taken {
+			Fail(c, apperr.New(apperr.DuplicateCode, "code", *code))
+			return
+		}
+		updates["code"] = code
+	}
+
+	if err := h.db.Model(&area).Updates(updates).Error; err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
 		return
 	}
 
@@ -92,14 +126,19 @@ func (h *AreaHandler) Update(ctx context.Context, c *app.RequestContext) {
 func (h *AreaHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
-	if err := h.db.Delete(&db.Area{}, id).Error; err != nil {
-		Error(c, 500, "删除失败: "+err.Error())
-		return
-	}
-
-	Success(c, nil)
+	deleteLocation(c, h.db, apperr.AreaNotFound, func(tx *gorm.DB) (*locationSubtree, error) {
+		var area db.Area
+		if err := tx.Select("id").First(&area, id).Error; err != nil {
+			return nil, err
+		}
+		subtree := &locationSubtree{areaIDs: []int64{id}}
+		if err := tx.Model(&db.Bookshelf{}).Where("area_id = ?", id).Pluck("id", &subtree.bookshelfIDs).Error; err != nil {
+			return nil, err
+		}
+		return subtree, collectLayers(tx, subtree)
+	})
 }
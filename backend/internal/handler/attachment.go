@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/attachment"
+	"booksystem/internal/db"
+	"booksystem/internal/storage"
+)
+
+// 附件类型
+const (
+	AttachmentKindCover = "cover" // 封面，每本书只保留一张
+	AttachmentKindTOC   = "toc"   // 目录扫描件
+	AttachmentKindOther = "other"
+)
+
+// thumbnailSize 缩略图最大边长（像素）
+const thumbnailSize = 300
+
+type AttachmentHandler struct {
+	db      *gorm.DB
+	store   storage.Storage
+	maxSize int64
+}
+
+// NewAttachmentHandler maxSize 为单个附件的大小上限（字节）
+func NewAttachmentHandler(db *gorm.DB, store storage.Storage, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{db: db, store: store, maxSize: maxSize}
+}
+
+// AttachmentResponse 附件信息及访问地址
+type AttachmentResponse struct {
+	db.Attachment
+	URL          string  `json:"url"`
+	ThumbnailURL *string `json:"thumbnail_url,omitempty"`
+}
+
+func attachmentResponse(a db.Attachment) AttachmentResponse {
+	resp := AttachmentResponse{Attachment: a, URL: fmt.Sprintf("/api/v1/attachments/%d", a.ID)}
+	if a.ThumbnailKey != nil {
+		thumb := resp.URL + "/thumbnail"
+		resp.ThumbnailURL = &thumb
+	}
+	return resp
+}
+
+// Upload 上传图书附件，表单字段 file 为文件，kind 为附件类型（cover/toc/other，默认 other）。
+// 文件类型按内容识别，支持 JPEG/PNG/GIF/WebP 图片和 PDF；图片同时生成缩略图。
+// 上传新封面时替换原有封面。
+func (h *AttachmentHandler) Upload(ctx context.Context, c *app.RequestContext) {
+	bookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+	kind := c.DefaultPostForm("kind", AttachmentKindOther)
+	if kind != AttachmentKindCover && kind != AttachmentKindTOC && kind != AttachmentKindOther {
+		Fail(c, apperr.Invalid("kind", apperr.InvalidValue))
+		return
+	}
+
+	var book db.Book
+	if err := h.db.Select("id").First(&book, bookID).Error; err != nil {
+		Fail(c, notFound(err, apperr.BookNotFound))
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		Fail(c, apperr.Invalid("file", apperr.Required))
+		return
+	}
+	if fileHeader.Size > h.maxSize {
+		Fail(c, apperr.New(apperr.FileTooLarge, "max", h.maxSize>>20))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		Fail(c, apperr.Wrap(apperr.BadRequest, err))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.maxSize+1))
+	if err != nil {
+		Fail(c, apperr.Wrap(apperr.BadRequest, err))
+		return
+	}
+	if int64(len(data)) > h.maxSize {
+		Fail(c, apperr.New(apperr.FileTooLarge, "max", h.maxSize>>20))
+		return
+	}
+
+	contentType, ext, err := attachment.Sniff(data)
+	if err != nil {
+		Fail(c, apperr.New(apperr.UnsupportedMediaType, "type", contentType))
+		return
+	}
+	if kind == AttachmentKindCover && !attachment.IsImage(contentType) {
+		Fail(c, apperr.New(apperr.CoverNotImage))
+		return
+	}
+
+	record := db.Attachment{
+		BookID:      bookID,
+		Kind:        kind,
+		FileName:    uploadFileName(fileHeader.Filename, ext),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	var thumb []byte
+	if attachment.IsImage(contentType) {
+		var width, height int
+		if thumb, width, height, err = attachment.Thumbnail(data, thumbnailSize); err != nil {
+			Fail(c, apperr.Wrap(apperr.InvalidFile, err, "reason", err.Error()))
+			return
+		}
+		record.Width, record.Height = &width, &height
+	}
+
+	name, err := randomName()
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	record.StorageKey = fmt.Sprintf("books/%d/%s%s", bookID, name, ext)
+	if err := h.store.Put(ctx, record.StorageKey, bytes.NewReader(data)); err != nil {
+		Fail(c, err)
+		return
+	}
+	if thumb != nil {
+		key := fmt.Sprintf("books/%d/%s_thumb.jpg", bookID, name)
+		record.ThumbnailKey = &key
+		if err := h.store.Put(ctx, key, bytes.NewReader(thumb)); err != nil {
+			deleteAttachmentFiles(ctx, h.store, []db.Attachment{record})
+			Fail(c, err)
+			return
+		}
+	}
+
+	var replaced []db.Attachment
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if kind == AttachmentKindCover {
+			if err := tx.Where("book_id = ? AND kind = ?", bookID, AttachmentKindCover).Find(&replaced).Error; err != nil {
+				return err
+			}
+			if len(replaced) > 0 {
+				if err := tx.Delete(&replaced).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Create(&record).Error
+	}); err != nil {
+		deleteAttachmentFiles(ctx, h.store, []db.Attachment{record})
+		Fail(c, err)
+		return
+	}
+	deleteAttachmentFiles(ctx, h.store, replaced)
+
+	Success(c, attachmentResponse(record))
+}
+
+// List 查询图书的附件列表
+func (h *AttachmentHandler) List(ctx context.Context, c *app.RequestContext) {
+	bookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+
+	var attachments []db.Attachment
+	if err := h.db.Where("book_id = ?", bookID).Order("id").Find(&attachments).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+
+	list := make([]AttachmentResponse, len(attachments))
+	for i, a := range attachments {
+		list[i] = attachmentResponse(a)
+	}
+	Success(c, list)
+}
+
+// Download 下载附件原文件
+func (h *AttachmentHandler) Download(ctx context.Context, c *app.RequestContext) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+	h.serve(ctx, c, a.StorageKey, a.ContentType, a.FileName)
+}
+
+// Thumbnail 获取附件缩略图，只有图片附件有缩略图
+func (h *AttachmentHandler) Thumbnail(ctx context.Context, c *app.RequestContext) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if a.ThumbnailKey == nil {
+		Fail(c, apperr.New(apperr.ThumbnailNotFound))
+		return
+	}
+	h.serve(ctx, c, *a.ThumbnailKey, "image/jpeg", "")
+}
+
+// Delete 删除附件及其文件
+func (h *AttachmentHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if err := h.db.Delete(&a).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+	deleteAttachmentFiles(ctx, h.store, []db.Attachment{a})
+	Success(c, nil)
+}
+
+func (h *AttachmentHandler) find(c *app.RequestContext) (db.Attachment, bool) {
+	var a db.Attachment
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return a, false
+	}
+	if err := h.db.First(&a, id).Error; err != nil {
+		Fail(c, notFound(err, apperr.AttachmentNotFound))
+		return a, false
+	}
+	return a, true
+}
+
+// serve 输出存储中的文件。存储键包含随机名，内容不会变化，允许客户端长期缓存。
+func (h *AttachmentHandler) serve(ctx context.Context, c *app.RequestContext, key, contentType, fileName string) {
+	r, err := h.store.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			Fail(c, apperr.Wrap(apperr.AttachmentNotFound, err))
+		} else {
+			Fail(c, err)
+		}
+		return
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if fileName != "" {
+		c.Header("Content-Disposition", "inline; filename*=UTF-8''"+url.PathEscape(fileName))
+	}
+	c.Data(200, contentType, data)
+}
+
+// deleteAttachmentFiles 删除附件的原文件和缩略图，失败时只记录日志
+func deleteAttachmentFiles(ctx context.Context, store storage.Storage, attachments []db.Attachment) {
+	for _, a := range attachments {
+		keys := []string{a.StorageKey}
+		if a.ThumbnailKey != nil {
+			keys = append(keys, *a.ThumbnailKey)
+		}
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil {
+				slog.WarnContext(ctx, "Failed to delete attachment file", "key", key, "error", err)
+			}
+		}
+	}
+}
+
+// coverAttachments 查询图书的封面，按图书ID索引
+func coverAttachments(tx *gorm.DB, bookIDs []int64) (map[int64]AttachmentResponse, error) {
+	covers := make(map[int64]AttachmentResponse, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return covers, nil
+	}
+	var attachments []db.Attachment
+	if err := tx.Where("book_id IN ? AND kind = ?", bookIDs, AttachmentKindCover).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		covers[a.BookID] = attachmentResponse(a)
+	}
+	return covers, nil
+}
+
+// uploadFileName 取上传文件名的最后一段，没有文件名时按类型生成
+func uploadFileName(name, ext string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file" + ext
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
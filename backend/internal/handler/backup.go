@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/backup"
+)
+
+type BackupHandler struct {
+	backups  *backup.Manager
+	compress bool
+}
+
+// NewBackupHandler backups 为空表示当前数据库不支持在线备份（非 SQLite），compress 为默认是否压缩
+func NewBackupHandler(backups *backup.Manager, compress bool) *BackupHandler {
+	return &BackupHandler{backups: backups, compress: compress}
+}
+
+// Create 立即备份数据库，服务运行中也能得到一致的快照。compress=true|false 覆盖默认的压缩设置。
+func (h *BackupHandler) Create(ctx context.Context, c *app.RequestContext) {
+	if !h.enabled(c) {
+		return
+	}
+	compress := h.compress
+	if v := c.Query("compress"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			Fail(c, apperr.Invalid("compress", apperr.InvalidValue))
+			return
+		}
+		compress = b
+	}
+
+	info, err := h.backups.Create(compress)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, info)
+}
+
+// List 查询备份文件，最新的在前
+func (h *BackupHandler) List(ctx context.Context, c *app.RequestContext) {
+	if !h.enabled(c) {
+		return
+	}
+	list, err := h.backups.List()
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, list)
+}
+
+// Download 下载备份文件
+func (h *BackupHandler) Download(ctx context.Context, c *app.RequestContext) {
+	if !h.enabled(c) {
+		return
+	}
+	f, info, err := h.backups.Open(c.Param("name"))
+	if errors.Is(err, backup.ErrNotFound) {
+		Fail(c, apperr.New(apperr.BackupNotFound))
+		return
+	} else if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+info.Name+`"`)
+	c.SetContentType("application/octet-stream")
+	c.SetBodyStream(f, int(info.Size))
+}
+
+func (h *BackupHandler) enabled(c *app.RequestContext) bool {
+	if h.backups == nil {
+		Fail(c, apperr.New(apperr.BackupNotSupported))
+		return false
+	}
+	return true
+}
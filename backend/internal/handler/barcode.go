@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/barcode"
+	"booksystem/internal/db"
+)
+
+// barcodeError 将一维码校验错误转换为错误码，为空时为字段 field 校验失败
+func barcodeError(field string, err error) *apperr.Error {
+	switch {
+	case errors.Is(err, barcode.ErrEmpty):
+		return apperr.Invalid(field, apperr.Required)
+	case errors.Is(err, barcode.ErrChecksum):
+		return apperr.Wrap(apperr.BarcodeChecksum, err)
+	default:
+		return apperr.Wrap(apperr.BarcodeUnknownFormat, err)
+	}
+}
+
+// findBookByBarcode 按一维码查找图书，ISBN-10 与 ISBN-13 两种形式均可匹配
+func findBookByBarcode(tx *gorm.DB, code string) (db.Book, error) {
+	var book db.Book
+	err := tx.Where("barcode IN ?", barcode.Candidates(code)).First(&book).Error
+	return book, err
+}
+
+// internalBarcodeDigits 馆内条码序号位数
+const internalBarcodeDigits = 8
+
+// maxSequenceAttempts 分配馆内条码时更新序号的最多尝试次数
+const maxSequenceAttempts = 5
+
+// nextInternalBarcode 从序号表分配下一个馆内条码（如 LIB00000001），跳过已被占用的条码。
+// 读取序号时锁定该行（SELECT ... FOR UPDATE，SQLite 的写事务本身是串行的），并发分配时后到的事务等待先到的提交，
+// 不会在 MySQL 可重复读的快照中反复读到旧值；更新时仍校验旧值，失败时最多重试 maxSequenceAttempts 次。
+func nextInternalBarcode(tx *gorm.DB, barcodes *barcode.Validator) (string, error) {
+	prefixes := barcodes.InternalPrefixes()
+	if len(prefixes) == 0 {
+		// 配置校验要求至少一个前缀，这里只在直接构造 Validator 时出现
+		return "", errors.New("no internal barcode prefix configured")
+	}
+	prefix := prefixes[0]
+
+	for attempt := 0; attempt < maxSequenceAttempts; attempt++ {
+		var seq db.BarcodeSequence
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(db.BarcodeSequence{Prefix: prefix}).Attrs(db.BarcodeSequence{NextValue: 1}).FirstOrCreate(&seq).Error; err != nil {
+			return "", err
+		}
+
+		next := seq.NextValue
+		var code string
+		for {
+			code = fmt.Sprintf("%s%0*d", prefix, internalBarcodeDigits, next)
+			next++
+			var count int64
+			if err := tx.Model(&db.Book{}).Where("barcode = ?", code).Count(&count).Error; err != nil {
+				return "", err
+			}
+			if count == 0 {
+				break
+			}
+		}
+
+		result := tx.Model(&db.BarcodeSequence{}).
+			Where("prefix = ? AND next_value = ?", prefix, seq.NextValue).
+			Update("next_value", next)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected == 1 {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("barcode sequence %q changed concurrently %d times", prefix, maxSequenceAttempts)
+}
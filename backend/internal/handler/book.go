@@ -3,117 +3,167 @@ package handler
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"gorm.io/gorm"
 
+	"booksystem/internal/apperr"
+	"booksystem/internal/barcode"
 	"booksystem/internal/db"
+	"booksystem/internal/i18n"
+	"booksystem/internal/storage"
 )
 
 type BookHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	barcodes *barcode.Validator
+	store    storage.Storage
 }
 
-func NewBookHandler(db *gorm.DB) *BookHandler {
-	return &BookHandler{db: db}
+func NewBookHandler(db *gorm.DB, barcodes *barcode.Validator, store storage.Storage) *BookHandler {
+	return &BookHandler{db: db, barcodes: barcodes, store: store}
 }
 
 // CreateRequest 创建图书请求
 type CreateBookRequest struct {
-	Barcode      string   `json:"barcode" binding:"required"`
-	Name         string   `json:"name" binding:"required"`
-	Quantity     int      `json:"quantity" binding:"required"`
-	InStock      *int     `json:"in_stock"`
-	ShelfLayerID *int64   `json:"shelf_layer_id"`
-	Price        *float64 `json:"price"`
-	Remark       *string  `json:"remark"`
+	Barcode       string   `json:"barcode"` // 为空时自动分配馆内条码
+	Name          string   `json:"name" binding:"required"`
+	Quantity      *int     `json:"quantity" binding:"required,min=0"` // 必须传入，可以为 0
+	InStock       *int     `json:"in_stock" binding:"min=0"`
+	ShelfLayerID  *int64   `json:"shelf_layer_id"`
+	Price         *float64 `json:"price"`
+	Remark        *string  `json:"remark"`
+	AuthorIDs     []int64  `json:"author_ids"`
+	AuthorNames   []string `json:"author_names"` // 按姓名关联作者，不存在时自动创建
+	PublisherID   *int64   `json:"publisher_id"`
+	PublisherName *string  `json:"publisher_name"` // 按名称关联出版社，不存在时自动创建
+	PublishYear   *int     `json:"publish_year"`
+	Edition       *string  `json:"edition"`
+	Language      *string  `json:"language"`
+	ClassNumber   *string  `json:"class_number"`
+	TagIDs        []int64  `json:"tag_ids"`
+	CategoryIDs   []int64  `json:"category_ids"`
 }
 
 // UpdateBookRequest 更新图书请求
 type UpdateBookRequest struct {
-	Name         *string  `json:"name"`
-	Quantity     *int     `json:"quantity"`
-	InStock      *int     `json:"in_stock"`
-	ShelfLayerID *int64   `json:"shelf_layer_id"`
-	Price        *float64 `json:"price"`
-	Remark       *string  `json:"remark"`
+	Name          *string  `json:"name"`
+	Quantity      *int     `json:"quantity" binding:"min=0"`
+	InStock       *int     `json:"in_stock" binding:"min=0"`
+	ShelfLayerID  *int64   `json:"shelf_layer_id"`
+	Price         *float64 `json:"price"`
+	Remark        *string  `json:"remark"`
+	AuthorIDs     *[]int64 `json:"author_ids"` // 传入时整体替换，传空数组表示清空
+	AuthorNames   []string `json:"author_names"`
+	PublisherID   *int64   `json:"publisher_id"`
+	PublisherName *string  `json:"publisher_name"`
+	PublishYear   *int     `json:"publish_year"`
+	Edition       *string  `json:"edition"`
+	Language      *string  `json:"language"`
+	ClassNumber   *string  `json:"class_number"`
+	TagIDs        *[]int64 `json:"tag_ids"`
+	CategoryIDs   *[]int64 `json:"category_ids"`
 }
 
 // Create 创建图书
 func (h *BookHandler) Create(ctx context.Context, c *app.RequestContext) {
 	var req CreateBookRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
+	// 校验一维码格式，ISBN-10 统一转换为 ISBN-13 存储；没有一维码的图书在保存时分配馆内条码
+	var code string
+	if strings.TrimSpace(req.Barcode) != "" {
+		parsed, err := h.barcodes.Parse(req.Barcode)
+		if err != nil {
+			Fail(c, barcodeError("barcode", err))
+			return
+		}
+
+		// 同一本书可能已以另一种形式（ISBN-10/ISBN-13）录入
+		if existing, err := findBookByBarcode(h.db, parsed.Normalized); err == nil {
+			Fail(c, apperr.New(apperr.DuplicateBarcode, "barcode", existing.Barcode))
+			return
+		}
+		code = parsed.Normalized
+	}
+
 	// 如果未指定在库数量，默认等于数量
-	inStock := req.Quantity
+	quantity := *req.Quantity
+	inStock := quantity
 	if req.InStock != nil {
 		inStock = *req.InStock
 	}
 
 	// 验证在库数量不能大于总数量
-	if inStock > req.Quantity {
-		Error(c, 400, "在库数量不能大于总数量")
+	if inStock > quantity {
+		Fail(c, apperr.Invalid("in_stock", apperr.InStockExceedsQuantity))
 		return
 	}
 
 	book := db.Book{
-		Barcode:      req.Barcode,
+		Barcode:      code,
 		Name:         req.Name,
-		Quantity:     req.Quantity,
+		Quantity:     quantity,
 		InStock:      inStock,
 		ShelfLayerID: req.ShelfLayerID,
 		Price:        req.Price,
 		Remark:       req.Remark,
+		PublisherID:  req.PublisherID,
+		PublishYear:  req.PublishYear,
+		Edition:      req.Edition,
+		Language:     req.Language,
+		ClassNumber:  req.ClassNumber,
 	}
 
-	if err := h.db.Create(&book).Error; err != nil {
-		Error(c, 400, "创建失败: "+err.Error())
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if book.Barcode == "" {
+			code, err := nextInternalBarcode(tx, h.barcodes)
+			if err != nil {
+				return err
+			}
+			book.Barcode = code
+		}
+		if req.PublisherName != nil && *req.PublisherName != "" && book.PublisherID == nil {
+			publisherID, err := findOrCreatePublisher(tx, *req.PublisherName)
+			if err != nil {
+				return err
+			}
+			book.PublisherID = &publisherID
+		}
+		if err := tx.Create(&book).Error; err != nil {
+			return err
+		}
+		authorIDs := req.AuthorIDs
+		if len(req.AuthorNames) > 0 {
+			ids, err := findOrCreateAuthors(tx, req.AuthorNames)
+			if err != nil {
+				return err
+			}
+			authorIDs = append(authorIDs, ids...)
+		}
+		return replaceBookAssociations(tx, &book, &authorIDs, &req.TagIDs, &req.CategoryIDs)
+	}); err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateBarcode, "barcode", book.Barcode)))
 		return
 	}
 
-	Success(c, book)
+	preloadBookMetadata(h.db).First(&book, book.ID)
+	resp, err := withCapacityWarning(h.db, book, requestLang(c))
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, resp)
 }
 
 // List 查询图书列表
 func (h *BookHandler) List(ctx context.Context, c *app.RequestContext) {
 	var books []db.Book
-	query := h.db.Model(&db.Book{})
-
-	// 书名模糊匹配
-	if name := c.Query("name"); name != "" {
-		query = query.Where("name LIKE ?", "%"+name+"%")
-	}
-
-	// 一维码精确匹配
-	if barcode := c.Query("barcode"); barcode != "" {
-		query = query.Where("barcode = ?", barcode)
-	}
-
-	// 位置查询
-	if areaID := c.Query("area_id"); areaID != "" {
-		query = query.Joins("JOIN shelf_layer ON book.shelf_layer_id = shelf_layer.id").
-			Joins("JOIN bookshelf ON shelf_layer.bookshelf_id = bookshelf.id").
-			Where("bookshelf.area_id = ?", areaID)
-	}
-	if bookshelfID := c.Query("bookshelf_id"); bookshelfID != "" {
-		query = query.Joins("JOIN shelf_layer ON book.shelf_layer_id = shelf_layer.id").
-			Where("shelf_layer.bookshelf_id = ?", bookshelfID)
-	}
-	if shelfLayerID := c.Query("shelf_layer_id"); shelfLayerID != "" {
-		query = query.Where("shelf_layer_id = ?", shelfLayerID)
-	}
-
-	// 在库状态
-	if status := c.Query("in_stock_status"); status != "" {
-		if status == "1" {
-			query = query.Where("in_stock > 0")
-		} else if status == "2" {
-			query = query.Where("in_stock < quantity")
-		}
-	}
+	query := applyBookFilters(h.db.Model(&db.Book{}), c)
 
 	// 分页
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -123,31 +173,48 @@ func (h *BookHandler) List(ctx context.Context, c *app.RequestContext) {
 	var total int64
 	query.Count(&total)
 
-	if err := query.Preload("ShelfLayer.Bookshelf.Area").Offset(offset).Limit(pageSize).Find(&books).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+	if err := preloadBookMetadata(query.Preload("ShelfLayer.Bookshelf.Area")).Offset(offset).Limit(pageSize).Find(&books).Error; err != nil {
+		Fail(c, err)
 		return
 	}
 
 	// 构建响应数据
 	type BookResponse struct {
-		ID             int64   `json:"id"`
-		Barcode        string  `json:"barcode"`
-		Name           string  `json:"name"`
-		Quantity       int     `json:"quantity"`
-		InStock        int     `json:"in_stock"`
-		ShelfLayerID   *int64  `json:"shelf_layer_id"`
-		ShelfLayerName *string `json:"shelf_layer_name"`
-		Price          *float64 `json:"price"`
-		Remark         *string `json:"remark"`
+		ID             int64         `json:"id"`
+		Barcode        string        `json:"barcode"`
+		Name           string        `json:"name"`
+		Quantity       int           `json:"quantity"`
+		InStock        int           `json:"in_stock"`
+		ShelfLayerID   *int64        `json:"shelf_layer_id"`
+		ShelfLayerName *string       `json:"shelf_layer_name"`
+		LocationCode   *string       `json:"location_code"`
+		Price          *float64      `json:"price"`
+		Remark         *string       `json:"remark"`
+		Authors        []db.Author   `json:"authors"`
+		PublisherID    *int64        `json:"publisher_id"`
+		PublisherName  *string       `json:"publisher_name"`
+		PublishYear    *int          `json:"publish_year"`
+		Edition        *string       `json:"edition"`
+		Language       *string       `json:"language"`
+		ClassNumber    *string       `json:"class_number"`
+		Tags           []db.Tag      `json:"tags"`
+		Categories     []db.Category `json:"categories"`
+		CoverURL       *string       `json:"cover_url"`
+		CoverThumbURL  *string       `json:"cover_thumbnail_url"`
+	}
+
+	bookIDs := make([]int64, len(books))
+	for i, book := range books {
+		bookIDs[i] = book.ID
+	}
+	covers, err := coverAttachments(h.db, bookIDs)
+	if err != nil {
+		Fail(c, err)
+		return
 	}
 
 	list := make([]BookResponse, len(books))
 	for i, book := range books {
-		var shelfLayerName *string
-		if book.ShelfLayerID != nil && book.ShelfLayer.ID != 0 {
-			name := book.ShelfLayer.Bookshelf.Area.Name + "-" + book.ShelfLayer.Bookshelf.Name + "-" + book.ShelfLayer.Name
-			shelfLayerName = &name
-		}
 		list[i] = BookResponse{
 			ID:             book.ID,
 			Barcode:        book.Barcode,
@@ -155,9 +222,23 @@ func (h *BookHandler) List(ctx context.Context, c *app.RequestContext) {
 			Quantity:       book.Quantity,
 			InStock:        book.InStock,
 			ShelfLayerID:   book.ShelfLayerID,
-			ShelfLayerName: shelfLayerName,
+			ShelfLayerName: shelfLayerName(book),
+			LocationCode:   bookLocationCode(book),
 			Price:          book.Price,
 			Remark:         book.Remark,
+			Authors:        book.Authors,
+			PublisherID:    book.PublisherID,
+			PublisherName:  publisherName(book),
+			PublishYear:    book.PublishYear,
+			Edition:        book.Edition,
+			Language:       book.Language,
+			ClassNumber:    book.ClassNumber,
+			Tags:           book.Tags,
+			Categories:     book.Categories,
+		}
+		if cover, ok := covers[book.ID]; ok {
+			list[i].CoverURL = &cover.URL
+			list[i].CoverThumbURL = cover.ThumbnailURL
 		}
 	}
 
@@ -169,42 +250,230 @@ func (h *BookHandler) List(ctx context.Context, c *app.RequestContext) {
 	})
 }
 
+// likeLower 不区分大小写的 LIKE 条件。SQLite 和 MySQL（默认排序规则）的 LIKE 不区分大小写，
+// PostgreSQL 的 LIKE 区分，两边都转为小写后比较，各数据库的结果一致
+func likeLower(column string) string {
+	return "LOWER(" + column + ") LIKE LOWER(?)"
+}
+
+// applyBookFilters 根据查询参数构建图书筛选条件（列表与导出共用）
+func applyBookFilters(query *gorm.DB, c *app.RequestContext) *gorm.DB {
+	// 书名模糊匹配
+	if name := c.Query("name"); name != "" {
+		query = query.Where(likeLower("name"), "%"+name+"%")
+	}
+
+	// 一维码精确匹配
+	if code := c.Query("barcode"); code != "" {
+		query = query.Where("barcode IN ?", barcode.Candidates(code))
+	}
+
+	// 位置查询。用子查询而不是 JOIN，避免 name、id 等列与位置表重名，表名也由模型决定
+	sub := query.Session(&gorm.Session{NewDB: true})
+	if areaID := c.Query("area_id"); areaID != "" {
+		query = query.Where("shelf_layer_id IN (?)", sub.Model(&db.ShelfLayer{}).Select("id").
+			Where("bookshelf_id IN (?)", sub.Model(&db.Bookshelf{}).Select("id").Where("area_id = ?", areaID)))
+	}
+	if bookshelfID := c.Query("bookshelf_id"); bookshelfID != "" {
+		query = query.Where("shelf_layer_id IN (?)", sub.Model(&db.ShelfLayer{}).Select("id").Where("bookshelf_id = ?", bookshelfID))
+	}
+	if shelfLayerID := c.Query("shelf_layer_id"); shelfLayerID != "" {
+		query = query.Where("shelf_layer_id = ?", shelfLayerID)
+	}
+
+	// 在库状态
+	if status := c.Query("in_stock_status"); status != "" {
+		if status == "1" {
+			query = query.Where("in_stock > 0")
+		} else if status == "2" {
+			query = query.Where("in_stock < quantity")
+		}
+	}
+
+	// 书目信息
+	if authorID := c.Query("author_id"); authorID != "" {
+		query = query.Where("id IN (?)", sub.Table("book_authors").Select("book_id").Where("author_id = ?", authorID))
+	}
+	if tagID := c.Query("tag_id"); tagID != "" {
+		query = query.Where("id IN (?)", sub.Table("book_tags").Select("book_id").Where("tag_id = ?", tagID))
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		query = query.Where("id IN (?)", sub.Table("book_categories").Select("book_id").Where("category_id = ?", categoryID))
+	}
+	if publisherID := c.Query("publisher_id"); publisherID != "" {
+		query = query.Where("publisher_id = ?", publisherID)
+	}
+	if year := c.Query("publish_year"); year != "" {
+		query = query.Where("publish_year = ?", year)
+	}
+	if language := c.Query("language"); language != "" {
+		query = query.Where("language = ?", language)
+	}
+	// 分类号前缀匹配，如 TP3 可查出 TP311、TP312 等
+	if classNumber := c.Query("class_number"); classNumber != "" {
+		query = query.Where(likeLower("class_number"), classNumber+"%")
+	}
+
+	// 关键词检索：书名、一维码、分类号、作者、出版社、标签、分类
+	if keyword := c.Query("keyword"); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where(sub.Where(likeLower("name"), like).
+			Or(likeLower("barcode"), like).
+			Or(likeLower("class_number"), like).
+			Or("id IN (?)", sub.Table("book_authors").Select("book_id").
+				Where("author_id IN (?)", sub.Model(&db.Author{}).Select("id").Where(likeLower("name"), like))).
+			Or("publisher_id IN (?)", sub.Model(&db.Publisher{}).Select("id").Where(likeLower("name"), like)).
+			Or("id IN (?)", sub.Table("book_tags").Select("book_id").
+				Where("tag_id IN (?)", sub.Model(&db.Tag{}).Select("id").Where(likeLower("name"), like))).
+			Or("id IN (?)", sub.Table("book_categories").Select("book_id").
+				Where("category_id IN (?)", sub.Model(&db.Category{}).Select("id").Where(likeLower("name"), like))))
+	}
+
+	return query
+}
+
+// preloadBookMetadata 预加载图书的作者、出版社、标签和分类
+func preloadBookMetadata(query *gorm.DB) *gorm.DB {
+	return query.Preload("Authors").Preload("Publisher").Preload("Tags").Preload("Categories")
+}
+
+// replaceBookAssociations 替换图书的作者、标签和分类关联，传入nil的项保持不变。
+// 有不存在的ID时返回 AUTHOR_NOT_FOUND、TAG_NOT_FOUND 或 CATEGORY_NOT_FOUND，不会静默丢弃
+func replaceBookAssociations(tx *gorm.DB, book *db.Book, authorIDs, tagIDs, categoryIDs *[]int64) error {
+	if authorIDs != nil {
+		authors, err := findNamed[db.Author](tx, *authorIDs, apperr.AuthorNotFound)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(book).Association("Authors").Replace(authors); err != nil {
+			return err
+		}
+	}
+	if tagIDs != nil {
+		tags, err := findNamed[db.Tag](tx, *tagIDs, apperr.TagNotFound)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(book).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+	}
+	if categoryIDs != nil {
+		categories, err := findNamed[db.Category](tx, *categoryIDs, apperr.CategoryNotFound)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(book).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findOrCreateAuthors 按姓名查找作者，不存在时创建，返回ID列表
+func findOrCreateAuthors(tx *gorm.DB, names []string) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		author := db.Author{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&author).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, author.ID)
+	}
+	return ids, nil
+}
+
+// findOrCreatePublisher 按名称查找出版社，不存在时创建
+func findOrCreatePublisher(tx *gorm.DB, name string) (int64, error) {
+	publisher := db.Publisher{Name: name}
+	err := tx.Where("name = ?", name).FirstOrCreate(&publisher).Error
+	return publisher.ID, err
+}
+
+// findOrCreateTags 按名称查找标签，不存在时创建，返回ID列表
+func findOrCreateTags(tx *gorm.DB, names []string) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		tag := db.Tag{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, tag.ID)
+	}
+	return ids, nil
+}
+
+// publisherName 返回图书的出版社名称，未设置时返回nil
+func publisherName(book db.Book) *string {
+	if book.Publisher == nil {
+		return nil
+	}
+	return &book.Publisher.Name
+}
+
+// shelfLayerName 拼接图书的完整位置名称（区域-书架-层数），未设置位置时返回nil
+func shelfLayerName(book db.Book) *string {
+	if book.ShelfLayerID == nil || book.ShelfLayer.ID == 0 {
+		return nil
+	}
+	name := layerLocation(book.ShelfLayer)
+	return &name
+}
+
+// bookLocationCode 图书所在层的位置编号，未设置位置或简码不全时返回nil
+func bookLocationCode(book db.Book) *string {
+	if book.ShelfLayerID == nil || book.ShelfLayer.ID == 0 {
+		return nil
+	}
+	if code := layerLocationCode(book.ShelfLayer); code != "" {
+		return &code
+	}
+	return nil
+}
+
 // GetByBarcode 根据一维码查询图书
 func (h *BookHandler) GetByBarcode(ctx context.Context, c *app.RequestContext) {
-	barcode := c.Param("barcode")
-	var book db.Book
-	if err := h.db.Where("barcode = ?", barcode).Preload("ShelfLayer.Bookshelf.Area").First(&book).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			Error(c, 404, "图书不存在")
-		} else {
-			Error(c, 500, "查询失败: "+err.Error())
-		}
+	code := c.Param("barcode")
+	book, err := findBookByBarcode(preloadBookMetadata(h.db.Preload("ShelfLayer.Bookshelf.Area")), code)
+	if err != nil {
+		Fail(c, notFound(err, apperr.BookNotFound))
 		return
 	}
-	Success(c, book)
+
+	covers, err := coverAttachments(h.db, []int64{book.ID})
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	resp := struct {
+		db.Book
+		CoverURL      *string `json:"cover_url"`
+		CoverThumbURL *string `json:"cover_thumbnail_url"`
+	}{Book: book}
+	if cover, ok := covers[book.ID]; ok {
+		resp.CoverURL = &cover.URL
+		resp.CoverThumbURL = cover.ThumbnailURL
+	}
+	Success(c, resp)
 }
 
 // Update 更新图书信息
 func (h *BookHandler) Update(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
 	var req UpdateBookRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
 	var book db.Book
 	if err := h.db.First(&book, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			Error(c, 404, "图书不存在")
-		} else {
-			Error(c, 500, "查询失败: "+err.Error())
-		}
+		Fail(c, notFound(err, apperr.BookNotFound))
 		return
 	}
 
@@ -227,31 +496,100 @@ func (h *BookHandler) Update(ctx context.Context, c *app.RequestContext) {
 	if req.Remark != nil {
 		updates["remark"] = *req.Remark
 	}
+	if req.PublisherID != nil {
+		updates["publisher_id"] = *req.PublisherID
+	}
+	if req.PublishYear != nil {
+		updates["publish_year"] = *req.PublishYear
+	}
+	if req.Edition != nil {
+		updates["edition"] = *req.Edition
+	}
+	if req.Language != nil {
+		updates["language"] = *req.Language
+	}
+	if req.ClassNumber != nil {
+		updates["class_number"] = *req.ClassNumber
+	}
 
 	// 验证在库数量不能大于总数量
 	if req.InStock != nil && req.Quantity != nil && *req.InStock > *req.Quantity {
-		Error(c, 400, "在库数量不能大于总数量")
+		Fail(c, apperr.Invalid("in_stock", apperr.InStockExceedsQuantity))
 		return
 	}
 	if req.InStock != nil && req.Quantity == nil && *req.InStock > book.Quantity {
-		Error(c, 400, "在库数量不能大于总数量")
+		Fail(c, apperr.Invalid("in_stock", apperr.InStockExceedsQuantity))
 		return
 	}
 
-	if err := h.db.Model(&book).Updates(updates).Error; err != nil {
-		Error(c, 500, "更新失败: "+err.Error())
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if req.PublisherName != nil && req.PublisherID == nil {
+			if *req.PublisherName == "" {
+				updates["publisher_id"] = nil
+			} else {
+				publisherID, err := findOrCreatePublisher(tx, *req.PublisherName)
+				if err != nil {
+					return err
+				}
+				updates["publisher_id"] = publisherID
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&book).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		authorIDs := req.AuthorIDs
+		if req.AuthorNames != nil {
+			ids, err := findOrCreateAuthors(tx, req.AuthorNames)
+			if err != nil {
+				return err
+			}
+			if authorIDs != nil {
+				ids = append(*authorIDs, ids...)
+			}
+			authorIDs = &ids
+		}
+		return replaceBookAssociations(tx, &book, authorIDs, req.TagIDs, req.CategoryIDs)
+	}); err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateBarcode, "barcode", book.Barcode)))
+		return
+	}
+
+	preloadBookMetadata(h.db).First(&book, id)
+	if req.ShelfLayerID == nil && req.Quantity == nil {
+		Success(c, book)
 		return
 	}
+	resp, err := withCapacityWarning(h.db, book, requestLang(c))
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, resp)
+}
 
-	h.db.First(&book, id)
-	Success(c, book)
+// bookWithWarning 保存图书后的响应，所在层超出容量时附带提醒（不阻止保存）
+type bookWithWarning struct {
+	db.Book
+	CapacityWarning *CapacityWarning `json:"capacity_warning,omitempty"`
+}
+
+func withCapacityWarning(tx *gorm.DB, book db.Book, lang i18n.Lang) (bookWithWarning, error) {
+	resp := bookWithWarning{Book: book}
+	if book.ShelfLayerID == nil {
+		return resp, nil
+	}
+	warning, err := layerCapacityWarning(tx, *book.ShelfLayerID, lang)
+	resp.CapacityWarning = warning
+	return resp, err
 }
 
 // Delete 删除图书
 func (h *BookHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
@@ -259,17 +597,22 @@ func (h *BookHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	var count int64
 	h.db.Model(&db.BorrowDetail{}).Where("book_id = ?", id).Count(&count)
 	if count > 0 {
-		Error(c, 400, "该图书存在借阅记录，无法删除")
+		Fail(c, apperr.New(apperr.BookHasLoans))
 		return
 	}
 
-	if err := h.db.Delete(&db.Book{}, id).Error; err != nil {
-		Error(c, 500, "删除失败: "+err.Error())
+	var attachments []db.Attachment
+	if err := h.db.Where("book_id = ?", id).Find(&attachments).Error; err != nil {
+		Fail(c, err)
 		return
 	}
 
+	// 同时删除与作者、标签、分类的关联及附件
+	if err := h.db.Select("Authors", "Tags", "Categories", "Attachments").Delete(&db.Book{ID: id}).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+	deleteAttachmentFiles(ctx, h.store, attachments)
+
 	Success(c, nil)
 }
-
-
-
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"booksystem/internal/db"
+)
+
+func TestApplyBookFilters(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	// 两个区域各一个书架、一层
+	var areas []db.Area
+	var shelves []db.Bookshelf
+	var layers []db.ShelfLayer
+	for _, name := range []string{"A", "B"} {
+		area := db.Area{Name: name}
+		if err := s.db.Create(&area).Error; err != nil {
+			t.Fatal(err)
+		}
+		shelf := db.Bookshelf{AreaID: area.ID, Name: "1"}
+		if err := s.db.Create(&shelf).Error; err != nil {
+			t.Fatal(err)
+		}
+		layer := db.ShelfLayer{BookshelfID: shelf.ID, Name: "1"}
+		if err := s.db.Create(&layer).Error; err != nil {
+			t.Fatal(err)
+		}
+		areas, shelves, layers = append(areas, area), append(shelves, shelf), append(layers, layer)
+	}
+	tag := db.Tag{Name: "Golang"}
+	if err := s.db.Create(&tag).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s.createBook(map[string]interface{}{
+		"barcode":        "9780134190440",
+		"name":           "The Go Programming Language",
+		"quantity":       2,
+		"shelf_layer_id": layers[0].ID,
+		"author_names":   []string{"Alan Donovan"},
+		"publisher_name": "Addison-Wesley",
+		"class_number":   "TP312GO",
+		"language":       "eng",
+		"tag_ids":        []int64{tag.ID},
+	})
+	s.createBook(map[string]interface{}{
+		"name":           "数据结构",
+		"quantity":       1,
+		"in_stock":       0,
+		"shelf_layer_id": layers[1].ID,
+		"author_names":   []string{"严蔚敏"},
+		"publisher_name": "清华大学出版社",
+		"class_number":   "TP311.12",
+		"language":       "chi",
+	})
+	s.createBook(map[string]interface{}{
+		"name":         "A History of China",
+		"quantity":     1,
+		"class_number": "K20",
+	})
+
+	var donovan db.Author
+	if err := s.db.Where("name = ?", "Alan Donovan").First(&donovan).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	const goBook, dsBook, historyBook = "The Go Programming Language", "数据结构", "A History of China"
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{goBook, dsBook, historyBook}},
+		// 模糊匹配不区分大小写（PostgreSQL 的 LIKE 区分大小写）
+		{"name=go+programming", []string{goBook}},
+		{"name=HISTORY", []string{historyBook}},
+		{"keyword=donovan", []string{goBook}},
+		{"keyword=ADDISON", []string{goBook}},
+		{"keyword=golang", []string{goBook}},
+		{"keyword=清华", []string{dsBook}},
+		{"keyword=k20", []string{historyBook}},
+		{"keyword=nothing", nil},
+		// 分类号前缀匹配
+		{"class_number=tp31", []string{goBook, dsBook}},
+		{"class_number=TP311", []string{dsBook}},
+		// ISBN-10 与 ISBN-13 均可命中
+		{"barcode=0134190440", []string{goBook}},
+		{"in_stock_status=1", []string{goBook, historyBook}},
+		{"in_stock_status=2", []string{dsBook}},
+		{fmt.Sprintf("area_id=%d", areas[1].ID), []string{dsBook}},
+		{fmt.Sprintf("bookshelf_id=%d", shelves[0].ID), []string{goBook}},
+		{fmt.Sprintf("shelf_layer_id=%d", layers[0].ID), []string{goBook}},
+		{fmt.Sprintf("author_id=%d", donovan.ID), []string{goBook}},
+		{fmt.Sprintf("tag_id=%d", tag.ID), []string{goBook}},
+		{"language=chi", []string{dsBook}},
+		{"keyword=go&in_stock_status=1", []string{goBook}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			s := s.sub(t)
+			var page struct {
+				List []struct {
+					ID   int64  `json:"id"`
+					Name string `json:"name"`
+				} `json:"list"`
+				Total int64 `json:"total"`
+			}
+			s.ok("GET", "/api/v1/books?"+tt.query, nil).decode(t, &page)
+
+			var got []string
+			for _, book := range page.List {
+				got = append(got, book.Name)
+			}
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if strings.Join(got, "|") != strings.Join(want, "|") || page.Total != int64(len(want)) {
+				t.Errorf("got %q (total %d), want %q", got, page.Total, want)
+			}
+		})
+	}
+}
+
+func TestCreateBookUnknownAssociation(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	for field, code := range map[string]string{
+		"author_ids":   "AUTHOR_NOT_FOUND",
+		"tag_ids":      "TAG_NOT_FOUND",
+		"category_ids": "CATEGORY_NOT_FOUND",
+	} {
+		r := s.do("POST", "/api/v1/books", map[string]interface{}{"name": "x", "quantity": 1, field: []int64{42}})
+		if r.Status != 404 || r.ErrorCode != code {
+			t.Errorf("%s: got %d %s, want 404 %s", field, r.Status, r.ErrorCode, code)
+		}
+	}
+	var n int64
+	s.db.Model(&db.Book{}).Count(&n)
+	if n != 0 {
+		t.Errorf("%d books created, want none", n)
+	}
+}
+
+func TestCreateBookQuantity(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		code string
+	}{
+		{"zero", map[string]interface{}{"name": "x", "quantity": 0}, ""},
+		{"missing", map[string]interface{}{"name": "x"}, "VALIDATION_FAILED"},
+		{"negative", map[string]interface{}{"name": "x", "quantity": -1}, "VALIDATION_FAILED"},
+		{"negative in stock", map[string]interface{}{"name": "x", "quantity": 1, "in_stock": -1}, "VALIDATION_FAILED"},
+	}
+	for _, tt := range tests {
+		r := s.do("POST", "/api/v1/books", tt.body)
+		if tt.code == "" && r.Status != 200 || r.ErrorCode != tt.code {
+			t.Errorf("%s: got %d %s, want %q", tt.name, r.Status, r.ErrorCode, tt.code)
+		}
+	}
+
+	id, _ := s.createBook(map[string]interface{}{"name": "y", "quantity": 1})
+	if r := s.do("PUT", fmt.Sprintf("/api/v1/books/%d", id), map[string]interface{}{"quantity": -1}); r.ErrorCode != "VALIDATION_FAILED" {
+		t.Errorf("update with negative quantity: got %d %s, want VALIDATION_FAILED", r.Status, r.ErrorCode)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/cloudwego/hertz/pkg/app"
 	"gorm.io/gorm"
 
+	"booksystem/internal/apperr"
 	"booksystem/internal/db"
 )
 
@@ -22,36 +23,60 @@ func NewBookshelfHandler(db *gorm.DB) *BookshelfHandler {
 type CreateBookshelfRequest struct {
 	AreaID int64  `json:"area_id" binding:"required"`
 	Name   string `json:"name" binding:"required"`
+	Code   string `json:"code"` // 简码，如 03
 }
 
 // UpdateBookshelfRequest 更新书架请求
 type UpdateBookshelfRequest struct {
-	AreaID int64  `json:"area_id" binding:"required"`
-	Name   string `json:"name" binding:"required"`
+	AreaID int64   `json:"area_id" binding:"required"`
+	Name   string  `json:"name" binding:"required"`
+	Code   *string `json:"code"` // 不传时保持不变，传空串表示清除
 }
 
 // Create 创建书架
 func (h *BookshelfHandler) Create(ctx context.Context, c *app.RequestContext) {
 	var req CreateBookshelfRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	code, err := normalizeLocationCode(req.Code)
+	if err != nil {
+		Fail(c, err)
 		return
 	}
 
 	bookshelf := db.Bookshelf{
 		AreaID: req.AreaID,
 		Name:   req.Name,
+		Code:   code,
+	}
+	if err := checkNameConflict(h.db, &db.Bookshelf{}, "area_id", req.AreaID, req.Name, 0); err != nil {
+		Fail(c, err)
+		return
+	}
+	if taken, err := locationCodeTaken(h.db, &db.Bookshelf{}, "area_id", req.AreaID, code, 0); err != nil {
+		Fail(c, err)
+		return
+	} else if taken {
+		Fail(c, apperr.New(apperr.DuplicateCode, "code", *code))
+		return
+	}
+	if bookshelf.SortOrder, err = nextSortOrder(h.db, &db.Bookshelf{}, "area_id", req.AreaID); err != nil {
+		Fail(c, err)
+		return
 	}
 
 	if err := h.db.Create(&bookshelf).Error; err != nil {
-		Error(c, 400, "创建失败: "+err.Error())
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
 		return
 	}
 
 	Success(c, bookshelf)
 }
 
-// List 查询书架列表
+// List 查询书架列表，按排序值和名称排列
 func (h *BookshelfHandler) List(ctx context.Context, c *app.RequestContext) {
 	var bookshelves []db.Bookshelf
 	query := h.db.Model(&db.Bookshelf{})
@@ -61,9 +86,10 @@ func (h *BookshelfHandler) List(ctx context.Context, c *app.RequestContext) {
 	}
 
 	if err := query.Find(&bookshelves).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
+	sortBookshelves(bookshelves)
 
 	Success(c, bookshelves)
 }
@@ -72,31 +98,48 @@ func (h *BookshelfHandler) List(ctx context.Context, c *app.RequestContext) {
 func (h *BookshelfHandler) Update(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
 	var req UpdateBookshelfRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
 	var bookshelf db.Bookshelf
 	if err := h.db.First(&bookshelf, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			Error(c, 404, "书架不存在")
-		} else {
-			Error(c, 500, "查询失败: "+err.Error())
-		}
+		Fail(c, notFound(err, apperr.BookshelfNotFound))
 		return
 	}
 
-	if err := h.db.Model(&bookshelf).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"area_id": req.AreaID,
 		"name":    req.Name,
-	}).Error; err != nil {
-		Error(c, 400, "更新失败: "+err.Error())
+	}
+	code := bookshelf.Code
+	if req.Code != nil {
+		if code, err = normalizeLocationCode(*req.Code); err != nil {
+			Fail(c, err)
+			return
+		}
+		updates["code"] = code
+	}
+	if err := checkNameConflict(h.db, &db.Bookshelf{}, "area_id", req.AreaID, req.Name, id); err != nil {
+		Fail(c, err)
+		return
+	}
+	if taken, err := locationCodeTaken(h.db, &db.Bookshelf{}, "area_id", req.AreaID, code, id); err != nil {
+		Fail(c, err)
+		return
+	} else if taken {
+		Fail(c, apperr.New(apperr.DuplicateCode, "code", *code))
+		return
+	}
+
+	if err := h.db.Model(&bookshelf).Updates(updates).Error; err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
 		return
 	}
 
@@ -108,14 +151,16 @@ func (h *BookshelfHandler) Update(ctx context.Context, c *app.RequestContext) {
 func (h *BookshelfHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
-		return
-	}
-
-	if err := h.db.Delete(&db.Bookshelf{}, id).Error; err != nil {
-		Error(c, 500, "删除失败: "+err.Error())
+		Fail(c, invalidID("id"))
 		return
 	}
 
-	Success(c, nil)
+	deleteLocation(c, h.db, apperr.BookshelfNotFound, func(tx *gorm.DB) (*locationSubtree, error) {
+		var bookshelf db.Bookshelf
+		if err := tx.Select("id").First(&bookshelf, id).Error; err != nil {
+			return nil, err
+		}
+		subtree := &locationSubtree{bookshelfIDs: []int64{id}}
+		return subtree, collectLayers(tx, subtree)
+	})
 }
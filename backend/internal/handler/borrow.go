@@ -2,23 +2,31 @@ package handler
 
 import (
 	"context"
+	"log/slog"
 	"strconv"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"gorm.io/gorm"
 
+	"booksystem/internal/apperr"
+	"booksystem/internal/barcode"
 	"booksystem/internal/db"
+	"booksystem/internal/i18n"
+	"booksystem/internal/metrics"
 	"booksystem/internal/service"
 )
 
 type BorrowHandler struct {
-	db    *gorm.DB
-	redis *service.RedisService
+	db       *gorm.DB
+	redis    *service.RedisService
+	barcodes *barcode.Validator
+	maxBooks int
 }
 
-func NewBorrowHandler(db *gorm.DB, redis *service.RedisService) *BorrowHandler {
-	return &BorrowHandler{db: db, redis: redis}
+// NewBorrowHandler maxBooks 为每位读者最多同时借阅的册数，0 表示不限
+func NewBorrowHandler(db *gorm.DB, redis *service.RedisService, barcodes *barcode.Validator, maxBooks int) *BorrowHandler {
+	return &BorrowHandler{db: db, redis: redis, barcodes: barcodes, maxBooks: maxBooks}
 }
 
 // CreateBorrowRequest 创建借阅记录请求
@@ -57,7 +65,7 @@ type AddBorrowBookRequest struct {
 
 // RemoveBorrowBookRequest 删除借阅图书请求
 type RemoveBorrowBookRequest struct {
-	Index int `json:"index" binding:"required"`
+	Index int `json:"index" binding:"min=0"`
 }
 
 // CompleteBorrowRequest 完成借阅请求（兼容旧接口，也支持从Redis读取）
@@ -81,7 +89,7 @@ type AddReturnBookRequest struct {
 
 // RemoveReturnBookRequest 删除归还图书请求
 type RemoveReturnBookRequest struct {
-	Index int `json:"index" binding:"required"`
+	Index int `json:"index" binding:"min=0"`
 }
 
 // CompleteReturnRequest 完成归还请求
@@ -93,7 +101,10 @@ type CompleteReturnRequest struct {
 func (h *BorrowHandler) Create(ctx context.Context, c *app.RequestContext) {
 	var req CreateBorrowRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+	if !h.checkLoanLimit(c, req.BorrowerPhone, len(req.Barcodes)) {
 		return
 	}
 
@@ -107,11 +118,11 @@ func (h *BorrowHandler) Create(ctx context.Context, c *app.RequestContext) {
 				Phone: req.BorrowerPhone,
 			}
 			if err := h.db.Create(&borrower).Error; err != nil {
-				Error(c, 500, "创建用户失败: "+err.Error())
+				Fail(c, err)
 				return
 			}
 		} else {
-			Error(c, 500, "查询用户失败: "+err.Error())
+			Fail(c, err)
 			return
 		}
 	} else {
@@ -139,27 +150,28 @@ func (h *BorrowHandler) Create(ctx context.Context, c *app.RequestContext) {
 
 	if err := tx.Create(&record).Error; err != nil {
 		tx.Rollback()
-		Error(c, 500, "创建借阅记录失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
 	// 创建借阅明细并更新图书在库数量
 	details := make([]db.BorrowDetail, 0, len(req.Barcodes))
-	for _, barcode := range req.Barcodes {
+	for _, code := range req.Barcodes {
 		// 查找图书（可能不存在）
-		var book db.Book
-		tx.Where("barcode = ?", barcode).First(&book)
+		book, _ := findBookByBarcode(tx, code)
 
 		// 创建借阅明细
 		detail := db.BorrowDetail{
 			BorrowRecordID: record.ID,
-			Barcode:        barcode,
+			Barcode:        code,
 		}
 		if book.ID != 0 {
 			detail.BookID = &book.ID
 			// 更新图书在库数量
-			if book.InStock > 0 {
-				tx.Model(&book).Update("in_stock", gorm.Expr("in_stock - 1"))
+			if err := takeCopy(tx, book); err != nil {
+				tx.Rollback()
+				Fail(c, err)
+				return
 			}
 		}
 		details = append(details, detail)
@@ -167,15 +179,16 @@ func (h *BorrowHandler) Create(ctx context.Context, c *app.RequestContext) {
 
 	if err := tx.Create(&details).Error; err != nil {
 		tx.Rollback()
-		Error(c, 500, "创建借阅明细失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
-		Error(c, 500, "提交失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
+	metrics.Borrows.Add(float64(len(details)))
 
 	// 查询完整的借阅记录
 	h.db.Preload("Details.Book").First(&record, record.ID)
@@ -211,17 +224,19 @@ func (h *BorrowHandler) Create(ctx context.Context, c *app.RequestContext) {
 func (h *BorrowHandler) Scan(ctx context.Context, c *app.RequestContext) {
 	var req ScanBorrowRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
 	// 查找图书（可能不存在）
-	var book db.Book
-	h.db.Where("barcode = ?", req.Barcode).First(&book)
+	book, _ := findBookByBarcode(h.db, req.Barcode)
 
 	// 如果图书存在，更新在库数量
-	if book.ID != 0 && book.InStock > 0 {
-		h.db.Model(&book).Update("in_stock", gorm.Expr("in_stock - 1"))
+	if book.ID != 0 {
+		if err := takeCopy(h.db, book); err != nil {
+			Fail(c, err)
+			return
+		}
 		h.db.First(&book, book.ID)
 	}
 
@@ -242,36 +257,7 @@ func (h *BorrowHandler) Scan(ctx context.Context, c *app.RequestContext) {
 // List 查询借阅记录列表
 func (h *BorrowHandler) List(ctx context.Context, c *app.RequestContext) {
 	var records []db.BorrowRecord
-	query := h.db.Model(&db.BorrowRecord{})
-
-	// 借阅人姓名（精确匹配）
-	if name := c.Query("borrower_name"); name != "" {
-		query = query.Where("borrower_name = ?", name)
-	}
-
-	// 借阅人电话（精确匹配）
-	if phone := c.Query("borrower_phone"); phone != "" {
-		query = query.Where("borrower_phone = ?", phone)
-	}
-
-	// 图书一维码（精确匹配）
-	if barcode := c.Query("barcode"); barcode != "" {
-		query = query.Joins("JOIN borrow_detail ON borrow_record.id = borrow_detail.borrow_record_id").
-			Where("borrow_detail.barcode = ?", barcode)
-	}
-
-	// 时间范围
-	if startTime := c.Query("start_time"); startTime != "" {
-		query = query.Where("borrow_time >= ?", startTime)
-	}
-	if endTime := c.Query("end_time"); endTime != "" {
-		query = query.Where("borrow_time <= ?", endTime)
-	}
-
-	// 只查询正在借阅的记录（根据需求文档2.3.2）
-	if c.Query("status") == "" {
-		query = query.Where("status = 1")
-	}
+	query := applyBorrowRecordFilters(h.db.Model(&db.BorrowRecord{}), c)
 
 	// 分页
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -282,7 +268,7 @@ func (h *BorrowHandler) List(ctx context.Context, c *app.RequestContext) {
 	query.Count(&total)
 
 	if err := query.Preload("Details.Book").Offset(offset).Limit(pageSize).Find(&records).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
@@ -330,11 +316,46 @@ func (h *BorrowHandler) List(ctx context.Context, c *app.RequestContext) {
 	})
 }
 
+// applyBorrowRecordFilters 根据查询参数构建借阅记录筛选条件（列表与导出共用）
+func applyBorrowRecordFilters(query *gorm.DB, c *app.RequestContext) *gorm.DB {
+	// 借阅人姓名（精确匹配）
+	if name := c.Query("borrower_name"); name != "" {
+		query = query.Where("borrower_name = ?", name)
+	}
+
+	// 借阅人电话（精确匹配）
+	if phone := c.Query("borrower_phone"); phone != "" {
+		query = query.Where("borrower_phone = ?", phone)
+	}
+
+	// 图书一维码（精确匹配）
+	if code := c.Query("barcode"); code != "" {
+		sub := query.Session(&gorm.Session{NewDB: true})
+		query = query.Where("id IN (?)", sub.Model(&db.BorrowDetail{}).Select("borrow_record_id").
+			Where("barcode IN ?", barcode.Candidates(code)))
+	}
+
+	// 时间范围
+	if startTime := c.Query("start_time"); startTime != "" {
+		query = query.Where("borrow_time >= ?", startTime)
+	}
+	if endTime := c.Query("end_time"); endTime != "" {
+		query = query.Where("borrow_time <= ?", endTime)
+	}
+
+	// 只查询正在借阅的记录（根据需求文档2.3.2）
+	if c.Query("status") == "" {
+		query = query.Where("status = 1")
+	}
+
+	return query
+}
+
 // GetBorrowerByPhone 根据电话查询归还人信息
 func (h *BorrowHandler) GetBorrowerByPhone(ctx context.Context, c *app.RequestContext) {
 	var req GetBorrowerByPhoneRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
@@ -343,12 +364,12 @@ func (h *BorrowHandler) GetBorrowerByPhone(ctx context.Context, c *app.RequestCo
 	if err := h.db.Where("borrower_phone = ? AND status = 1", req.Phone).
 		Preload("Details.Book").
 		Find(&records).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
 	if len(records) == 0 {
-		Error(c, 404, "未找到该电话的借阅记录")
+		Fail(c, apperr.New(apperr.BorrowerNotFound))
 		return
 	}
 
@@ -385,20 +406,16 @@ func (h *BorrowHandler) GetBorrowerByPhone(ctx context.Context, c *app.RequestCo
 func (h *BorrowHandler) Return(ctx context.Context, c *app.RequestContext) {
 	var req ReturnBorrowRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
 	// 查找该一维码的借阅明细（状态为借出，且归还人电话匹配）
 	var detail db.BorrowDetail
-	if err := h.db.Joins("JOIN borrow_record ON borrow_detail.borrow_record_id = borrow_record.id").
-		Where("borrow_detail.barcode = ? AND borrow_record.status = 1 AND borrow_record.borrower_phone = ?", req.Barcode, req.BorrowerPhone).
+	if err := h.db.Joins("JOIN borrow_records ON borrow_details.borrow_record_id = borrow_records.id").
+		Where("borrow_details.barcode IN ? AND borrow_records.status = 1 AND borrow_records.borrower_phone = ?", barcode.Candidates(req.Barcode), req.BorrowerPhone).
 		First(&detail).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			Error(c, 404, "未找到该图书的借阅记录或归还人信息不匹配")
-		} else {
-			Error(c, 500, "查询失败: "+err.Error())
-		}
+		Fail(c, notFound(err, apperr.LoanNotFound))
 		return
 	}
 
@@ -412,9 +429,10 @@ func (h *BorrowHandler) Return(ctx context.Context, c *app.RequestContext) {
 
 	// 更新图书在库数量（如果图书存在）
 	if detail.BookID != nil {
-		var book db.Book
-		if err := tx.First(&book, *detail.BookID).Error; err == nil {
-			tx.Model(&book).Update("in_stock", gorm.Expr("in_stock + 1"))
+		if err := returnCopy(tx, *detail.BookID); err != nil {
+			tx.Rollback()
+			Fail(c, err)
+			return
 		}
 	}
 
@@ -422,14 +440,14 @@ func (h *BorrowHandler) Return(ctx context.Context, c *app.RequestContext) {
 	var record db.BorrowRecord
 	if err := tx.First(&record, detail.BorrowRecordID).Error; err != nil {
 		tx.Rollback()
-		Error(c, 500, "查询借阅记录失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
 	// 删除该借阅明细（表示已归还）
 	if err := tx.Delete(&detail).Error; err != nil {
 		tx.Rollback()
-		Error(c, 500, "删除借阅明细失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
@@ -443,20 +461,78 @@ func (h *BorrowHandler) Return(ctx context.Context, c *app.RequestContext) {
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		Error(c, 500, "提交失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
+	metrics.Returns.Inc()
 
 	Success(c, map[string]interface{}{
-		"message": "归还成功",
+		"message": message(c, i18n.MsgReturned),
 	})
 }
 
+// checkLoanLimit 检查读者再借 adding 册后是否超过借阅上限，按未归还的借阅明细计算已借册数。
+// 超过上限或查询失败时写入错误响应并返回 false
+func (h *BorrowHandler) checkLoanLimit(c *app.RequestContext, phone string, adding int) bool {
+	if h.maxBooks <= 0 {
+		return true
+	}
+	var borrowed int64
+	if err := h.db.Model(&db.BorrowDetail{}).
+		Joins("JOIN borrow_records ON borrow_records.id = borrow_details.borrow_record_id").
+		Where("borrow_records.status = 1 AND borrow_records.borrower_phone = ?", phone).
+		Count(&borrowed).Error; err != nil {
+		Fail(c, err)
+		return false
+	}
+	if int(borrowed)+adding > h.maxBooks {
+		Fail(c, apperr.New(apperr.LoanLimitExceeded, "max", h.maxBooks, "borrowed", borrowed))
+		return false
+	}
+	return true
+}
+
+// returnCopy 归还一册，在库数量加一，已等于总数量（如总数量被调低）时不再增加
+func returnCopy(tx *gorm.DB, bookID int64) error {
+	return tx.Model(&db.Book{}).Where("id = ? AND in_stock < quantity", bookID).
+		Update("in_stock", gorm.Expr("in_stock + 1")).Error
+}
+
+// takeCopy 借出一册，在库数量减一。借阅不校验库存，已全部借出时不再减少，也不阻止借阅
+func takeCopy(tx *gorm.DB, book db.Book) error {
+	return tx.Model(&db.Book{}).Where("id = ? AND in_stock > 0", book.ID).
+		Update("in_stock", gorm.Expr("in_stock - 1")).Error
+}
+
+// scanBarcode 校验扫码得到的一维码，返回规范化后的一维码和 lang 语言的提示信息。
+// 校验失败时原样返回输入，不阻止借阅/归还。
+func (h *BorrowHandler) scanBarcode(lang i18n.Lang, code string) (string, string) {
+	parsed, err := h.barcodes.Parse(code)
+	if err != nil {
+		return parsed.Input, barcodeError("barcode", err).Message(lang)
+	}
+	return parsed.Normalized, ""
+}
+
+// publishBasket 向终端和读者手机推送当前的借阅/归还列表，推送失败不影响接口结果
+func (h *BorrowHandler) publishBasket(ctx context.Context, kiosk, mode, eventType string) {
+	basket, err := loadBasket(ctx, h.redis, kiosk, mode)
+	if err != nil {
+		return
+	}
+	h.redis.PublishKioskEvent(ctx, kiosk, mode, eventType, basket)
+}
+
 // SetBorrowUser 设置借阅用户信息（存入Redis）
 func (h *BorrowHandler) SetBorrowUser(ctx context.Context, c *app.RequestContext) {
 	var req SetBorrowUserRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeBorrow)
+	if !ok {
 		return
 	}
 
@@ -470,11 +546,11 @@ func (h *BorrowHandler) SetBorrowUser(ctx context.Context, c *app.RequestContext
 				Phone: req.Phone,
 			}
 			if err := h.db.Create(&borrower).Error; err != nil {
-				Error(c, 500, "创建用户失败: "+err.Error())
+				Fail(c, err)
 				return
 			}
 		} else {
-			Error(c, 500, "查询用户失败: "+err.Error())
+			Fail(c, err)
 			return
 		}
 	} else {
@@ -489,22 +565,29 @@ func (h *BorrowHandler) SetBorrowUser(ctx context.Context, c *app.RequestContext
 		Name:  req.Name,
 		Phone: req.Phone,
 	}
-	if err := h.redis.SetBorrowUser(ctx, user); err != nil {
-		Error(c, 500, "保存用户信息失败: "+err.Error())
+	if err := h.redis.SetBorrowUser(ctx, kiosk, user); err != nil {
+		Fail(c, err)
 		return
 	}
 
+	h.publishBasket(ctx, kiosk, service.KioskModeBorrow, service.KioskEventUser)
+
 	Success(c, map[string]interface{}{
-		"message": "用户信息已保存",
+		"message": message(c, i18n.MsgUserSaved),
 		"user":    user,
 	})
 }
 
 // GetBorrowUser 获取当前借阅用户和图书列表
 func (h *BorrowHandler) GetBorrowUser(ctx context.Context, c *app.RequestContext) {
-	user, err := h.redis.GetBorrowUser(ctx)
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeBorrow)
+	if !ok {
+		return
+	}
+
+	user, err := h.redis.GetBorrowUser(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取用户信息失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 	if user == nil {
@@ -515,9 +598,9 @@ func (h *BorrowHandler) GetBorrowUser(ctx context.Context, c *app.RequestContext
 		return
 	}
 
-	books, err := h.redis.GetBorrowBooks(ctx)
+	books, err := h.redis.GetBorrowBooks(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取图书列表失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
@@ -531,59 +614,97 @@ func (h *BorrowHandler) GetBorrowUser(ctx context.Context, c *app.RequestContext
 func (h *BorrowHandler) AddBorrowBook(ctx context.Context, c *app.RequestContext) {
 	var req AddBorrowBookRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeBorrow)
+	if !ok {
 		return
 	}
 
 	// 检查用户是否存在
-	user, err := h.redis.GetBorrowUser(ctx)
+	user, err := h.redis.GetBorrowUser(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取用户信息失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 	if user == nil {
-		Error(c, 400, "请先设置用户信息")
+		Fail(c, apperr.New(apperr.BorrowerNotSet))
 		return
 	}
 
-	// 查找图书信息
-	var book db.Book
-	h.db.Where("barcode = ?", req.Barcode).First(&book)
+	// 加入后超出借阅上限时不再加入
+	if h.maxBooks > 0 {
+		books, err := h.redis.GetBorrowBooks(ctx, kiosk)
+		if err != nil {
+			Fail(c, err)
+			return
+		}
+		if !h.checkLoanLimit(c, user.Phone, len(books)+1) {
+			return
+		}
+	}
+
+	// 校验一维码，借阅扫码无需系统验证，格式有误时仅提示
+	code, warning := h.scanBarcode(requestLang(c), req.Barcode)
+
+	// 查找图书信息，已全部借出的图书仍可加入，仅提示
+	book, _ := findBookByBarcode(h.db, code)
+	if book.ID != 0 {
+		code = book.Barcode
+		if book.InStock <= 0 && warning == "" {
+			warning = apperr.New(apperr.StockExhausted, "name", book.Name).Message(requestLang(c))
+		}
+	}
 
 	// 添加到Redis
 	borrowBook := &service.BorrowBook{
-		Barcode: req.Barcode,
+		Barcode: code,
 	}
 	if book.ID != 0 {
 		borrowBook.Name = &book.Name
 	}
 
-	if err := h.redis.AddBorrowBook(ctx, borrowBook); err != nil {
-		Error(c, 500, "添加图书失败: "+err.Error())
+	if err := h.redis.AddBorrowBook(ctx, kiosk, borrowBook); err != nil {
+		Fail(c, err)
 		return
 	}
 
-	Success(c, map[string]interface{}{
-		"message": "添加成功",
+	h.publishBasket(ctx, kiosk, service.KioskModeBorrow, service.KioskEventBookAdded)
+
+	result := map[string]interface{}{
+		"message": message(c, i18n.MsgBookAdded),
 		"book":    borrowBook,
-	})
+	}
+	if warning != "" {
+		result["warning"] = warning
+	}
+	Success(c, result)
 }
 
 // RemoveBorrowBook 删除借阅图书
 func (h *BorrowHandler) RemoveBorrowBook(ctx context.Context, c *app.RequestContext) {
 	var req RemoveBorrowBookRequest
 	if err := c.Bind(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeBorrow)
+	if !ok {
 		return
 	}
 
-	if err := h.redis.RemoveBorrowBook(ctx, req.Index); err != nil {
-		Error(c, 500, "删除图书失败: "+err.Error())
+	if err := h.redis.RemoveBorrowBook(ctx, kiosk, req.Index); err != nil {
+		Fail(c, err)
 		return
 	}
 
+	h.publishBasket(ctx, kiosk, service.KioskModeBorrow, service.KioskEventBookRemoved)
+
 	Success(c, map[string]interface{}{
-		"message": "删除成功",
+		"message": message(c, i18n.MsgBookRemoved),
 	})
 }
 
@@ -594,22 +715,32 @@ func (h *BorrowHandler) CompleteBorrow(ctx context.Context, c *app.RequestContex
 
 	var borrowerName, borrowerPhone string
 	var barcodes []string
+	kiosk := service.DefaultKiosk
 
 	if req.UseRedis {
+		var ok bool
+		if kiosk, ok = resolveKiosk(ctx, c, h.redis, service.KioskModeBorrow); !ok {
+			return
+		}
+
 		// 从Redis读取数据
-		user, err := h.redis.GetBorrowUser(ctx)
-		if err != nil || user == nil {
-			Error(c, 400, "请先设置用户信息")
+		user, err := h.redis.GetBorrowUser(ctx, kiosk)
+		if err != nil {
+			Fail(c, err)
+			return
+		}
+		if user == nil {
+			Fail(c, apperr.New(apperr.BorrowerNotSet))
 			return
 		}
 
-		books, err := h.redis.GetBorrowBooks(ctx)
+		books, err := h.redis.GetBorrowBooks(ctx, kiosk)
 		if err != nil {
-			Error(c, 500, "获取图书列表失败: "+err.Error())
+			Fail(c, err)
 			return
 		}
 		if len(books) == 0 {
-			Error(c, 400, "请至少添加一本图书")
+			Fail(c, apperr.New(apperr.NoBooksSelected))
 			return
 		}
 
@@ -621,14 +752,27 @@ func (h *BorrowHandler) CompleteBorrow(ctx context.Context, c *app.RequestContex
 		}
 	} else {
 		// 兼容旧接口，从请求体读取
-		if req.BorrowerName == "" || req.BorrowerPhone == "" || len(req.Barcodes) == 0 {
-			Error(c, 400, "参数不完整")
+		var missing []apperr.FieldError
+		if req.BorrowerName == "" {
+			missing = append(missing, apperr.Field("borrower_name", apperr.Required))
+		}
+		if req.BorrowerPhone == "" {
+			missing = append(missing, apperr.Field("borrower_phone", apperr.Required))
+		}
+		if len(req.Barcodes) == 0 {
+			missing = append(missing, apperr.Field("barcodes", apperr.Required))
+		}
+		if len(missing) > 0 {
+			Fail(c, apperr.Validation(missing...))
 			return
 		}
 		borrowerName = req.BorrowerName
 		borrowerPhone = req.BorrowerPhone
 		barcodes = req.Barcodes
 	}
+	if !h.checkLoanLimit(c, borrowerPhone, len(barcodes)) {
+		return
+	}
 
 	// 保存或更新用户信息
 	var borrower db.Borrower
@@ -639,11 +783,11 @@ func (h *BorrowHandler) CompleteBorrow(ctx context.Context, c *app.RequestContex
 				Phone: borrowerPhone,
 			}
 			if err := h.db.Create(&borrower).Error; err != nil {
-				Error(c, 500, "创建用户失败: "+err.Error())
+				Fail(c, err)
 				return
 			}
 		} else {
-			Error(c, 500, "查询用户失败: "+err.Error())
+			Fail(c, err)
 			return
 		}
 	} else {
@@ -670,24 +814,25 @@ func (h *BorrowHandler) CompleteBorrow(ctx context.Context, c *app.RequestContex
 
 	if err := tx.Create(&record).Error; err != nil {
 		tx.Rollback()
-		Error(c, 500, "创建借阅记录失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
 	// 创建借阅明细并更新图书在库数量
 	details := make([]db.BorrowDetail, 0, len(barcodes))
-	for _, barcode := range barcodes {
-		var book db.Book
-		tx.Where("barcode = ?", barcode).First(&book)
+	for _, code := range barcodes {
+		book, _ := findBookByBarcode(tx, code)
 
 		detail := db.BorrowDetail{
 			BorrowRecordID: record.ID,
-			Barcode:        barcode,
+			Barcode:        code,
 		}
 		if book.ID != 0 {
 			detail.BookID = &book.ID
-			if book.InStock > 0 {
-				tx.Model(&book).Update("in_stock", gorm.Expr("in_stock - 1"))
+			if err := takeCopy(tx, book); err != nil {
+				tx.Rollback()
+				Fail(c, err)
+				return
 			}
 		}
 		details = append(details, detail)
@@ -695,31 +840,44 @@ func (h *BorrowHandler) CompleteBorrow(ctx context.Context, c *app.RequestContex
 
 	if err := tx.Create(&details).Error; err != nil {
 		tx.Rollback()
-		Error(c, 500, "创建借阅明细失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		Error(c, 500, "提交失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
+	metrics.Borrows.Add(float64(len(details)))
+
+	result := map[string]interface{}{
+		"message": message(c, i18n.MsgBorrowed),
+		"id":      record.ID,
+	}
 
-	// 清除Redis数据
+	// 清除Redis数据，终端换用新的二维码，并通知终端和读者手机
 	if req.UseRedis {
-		h.redis.ClearBorrowData(ctx)
+		h.redis.ClearBorrowData(ctx, kiosk)
+		// 新会话只返回给终端，不推送给读者手机；读者手机用会话令牌完成时也不返回
+		if session, err := h.redis.RotateKioskSession(ctx, kiosk, service.KioskModeBorrow); err == nil && session != nil && sessionToken(c) == "" {
+			result["session"] = session
+		}
+		h.redis.PublishKioskEvent(ctx, kiosk, service.KioskModeBorrow, service.KioskEventCompleted, map[string]interface{}{"id": record.ID})
 	}
 
-	Success(c, map[string]interface{}{
-		"message": "借阅成功",
-		"id":      record.ID,
-	})
+	Success(c, result)
 }
 
 // SetReturnUser 设置归还用户信息（存入Redis）
 func (h *BorrowHandler) SetReturnUser(ctx context.Context, c *app.RequestContext) {
 	var req SetReturnUserRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeReturn)
+	if !ok {
 		return
 	}
 
@@ -732,11 +890,11 @@ func (h *BorrowHandler) SetReturnUser(ctx context.Context, c *app.RequestContext
 				Phone: req.Phone,
 			}
 			if err := h.db.Create(&borrower).Error; err != nil {
-				Error(c, 500, "创建用户失败: "+err.Error())
+				Fail(c, err)
 				return
 			}
 		} else {
-			Error(c, 500, "查询用户失败: "+err.Error())
+			Fail(c, err)
 			return
 		}
 	} else {
@@ -750,22 +908,29 @@ func (h *BorrowHandler) SetReturnUser(ctx context.Context, c *app.RequestContext
 		Name:  req.Name,
 		Phone: req.Phone,
 	}
-	if err := h.redis.SetReturnUser(ctx, user); err != nil {
-		Error(c, 500, "保存用户信息失败: "+err.Error())
+	if err := h.redis.SetReturnUser(ctx, kiosk, user); err != nil {
+		Fail(c, err)
 		return
 	}
 
+	h.publishBasket(ctx, kiosk, service.KioskModeReturn, service.KioskEventUser)
+
 	Success(c, map[string]interface{}{
-		"message": "用户信息已保存",
+		"message": message(c, i18n.MsgUserSaved),
 		"user":    user,
 	})
 }
 
 // GetReturnUser 获取当前归还用户和图书列表
 func (h *BorrowHandler) GetReturnUser(ctx context.Context, c *app.RequestContext) {
-	user, err := h.redis.GetReturnUser(ctx)
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeReturn)
+	if !ok {
+		return
+	}
+
+	user, err := h.redis.GetReturnUser(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取用户信息失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 	if user == nil {
@@ -776,9 +941,9 @@ func (h *BorrowHandler) GetReturnUser(ctx context.Context, c *app.RequestContext
 		return
 	}
 
-	books, err := h.redis.GetReturnBooks(ctx)
+	books, err := h.redis.GetReturnBooks(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取图书列表失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 
@@ -792,59 +957,82 @@ func (h *BorrowHandler) GetReturnUser(ctx context.Context, c *app.RequestContext
 func (h *BorrowHandler) AddReturnBook(ctx context.Context, c *app.RequestContext) {
 	var req AddReturnBookRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeReturn)
+	if !ok {
 		return
 	}
 
 	// 检查用户是否存在
-	user, err := h.redis.GetReturnUser(ctx)
+	user, err := h.redis.GetReturnUser(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取用户信息失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 	if user == nil {
-		Error(c, 400, "请先设置用户信息")
+		Fail(c, apperr.New(apperr.BorrowerNotSet))
 		return
 	}
 
+	// 校验一维码，归还扫码无需系统验证，格式有误时仅提示
+	code, warning := h.scanBarcode(requestLang(c), req.Barcode)
+
 	// 查找图书信息
-	var book db.Book
-	h.db.Where("barcode = ?", req.Barcode).First(&book)
+	book, _ := findBookByBarcode(h.db, code)
+	if book.ID != 0 {
+		code = book.Barcode
+	}
 
 	// 添加到Redis
 	returnBook := &service.BorrowBook{
-		Barcode: req.Barcode,
+		Barcode: code,
 	}
 	if book.ID != 0 {
 		returnBook.Name = &book.Name
 	}
 
-	if err := h.redis.AddReturnBook(ctx, returnBook); err != nil {
-		Error(c, 500, "添加图书失败: "+err.Error())
+	if err := h.redis.AddReturnBook(ctx, kiosk, returnBook); err != nil {
+		Fail(c, err)
 		return
 	}
 
-	Success(c, map[string]interface{}{
-		"message": "添加成功",
+	h.publishBasket(ctx, kiosk, service.KioskModeReturn, service.KioskEventBookAdded)
+
+	result := map[string]interface{}{
+		"message": message(c, i18n.MsgBookAdded),
 		"book":    returnBook,
-	})
+	}
+	if warning != "" {
+		result["warning"] = warning
+	}
+	Success(c, result)
 }
 
 // RemoveReturnBook 删除归还图书
 func (h *BorrowHandler) RemoveReturnBook(ctx context.Context, c *app.RequestContext) {
 	var req RemoveReturnBookRequest
 	if err := c.Bind(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeReturn)
+	if !ok {
 		return
 	}
 
-	if err := h.redis.RemoveReturnBook(ctx, req.Index); err != nil {
-		Error(c, 500, "删除图书失败: "+err.Error())
+	if err := h.redis.RemoveReturnBook(ctx, kiosk, req.Index); err != nil {
+		Fail(c, err)
 		return
 	}
 
+	h.publishBasket(ctx, kiosk, service.KioskModeReturn, service.KioskEventBookRemoved)
+
 	Success(c, map[string]interface{}{
-		"message": "删除成功",
+		"message": message(c, i18n.MsgBookRemoved),
 	})
 }
 
@@ -854,24 +1042,33 @@ func (h *BorrowHandler) CompleteReturn(ctx context.Context, c *app.RequestContex
 	c.Bind(&req)
 
 	if !req.UseRedis {
-		Error(c, 400, "归还必须使用Redis数据")
+		Fail(c, apperr.Invalid("use_redis", apperr.InvalidValue))
+		return
+	}
+
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, service.KioskModeReturn)
+	if !ok {
 		return
 	}
 
 	// 从Redis读取数据
-	user, err := h.redis.GetReturnUser(ctx)
-	if err != nil || user == nil {
-		Error(c, 400, "请先设置用户信息")
+	user, err := h.redis.GetReturnUser(ctx, kiosk)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	if user == nil {
+		Fail(c, apperr.New(apperr.BorrowerNotSet))
 		return
 	}
 
-	books, err := h.redis.GetReturnBooks(ctx)
+	books, err := h.redis.GetReturnBooks(ctx, kiosk)
 	if err != nil {
-		Error(c, 500, "获取图书列表失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
 	if len(books) == 0 {
-		Error(c, 400, "请至少添加一本图书")
+		Fail(c, apperr.New(apperr.NoBooksSelected))
 		return
 	}
 
@@ -883,11 +1080,11 @@ func (h *BorrowHandler) CompleteReturn(ctx context.Context, c *app.RequestContex
 		}
 		// 调用原有的归还逻辑
 		var detail db.BorrowDetail
-		if err := h.db.Joins("JOIN borrow_record ON borrow_detail.borrow_record_id = borrow_record.id").
-			Where("borrow_detail.barcode = ? AND borrow_record.status = 1 AND borrow_record.borrower_phone = ?", returnReq.Barcode, returnReq.BorrowerPhone).
+		if err := h.db.Joins("JOIN borrow_records ON borrow_details.borrow_record_id = borrow_records.id").
+			Where("borrow_details.barcode IN ? AND borrow_records.status = 1 AND borrow_records.borrower_phone = ?", barcode.Candidates(returnReq.Barcode), returnReq.BorrowerPhone).
 			First(&detail).Error; err != nil {
 			if err != gorm.ErrRecordNotFound {
-				Error(c, 500, "查询失败: "+err.Error())
+				Fail(c, err)
 				return
 			}
 			// 图书不存在或已归还，跳过
@@ -896,9 +1093,10 @@ func (h *BorrowHandler) CompleteReturn(ctx context.Context, c *app.RequestContex
 
 		tx := h.db.Begin()
 		if detail.BookID != nil {
-			var book db.Book
-			if err := tx.First(&book, *detail.BookID).Error; err == nil {
-				tx.Model(&book).Update("in_stock", gorm.Expr("in_stock + 1"))
+			if err := returnCopy(tx, *detail.BookID); err != nil {
+				tx.Rollback()
+				slog.ErrorContext(ctx, "Failed to update stock on return", "barcode", detail.Barcode, "error", err)
+				continue
 			}
 		}
 
@@ -919,13 +1117,21 @@ func (h *BorrowHandler) CompleteReturn(ctx context.Context, c *app.RequestContex
 			tx.Model(&record).Update("status", 2)
 		}
 
-		tx.Commit()
+		if tx.Commit().Error == nil {
+			metrics.Returns.Inc()
+		}
 	}
 
-	// 清除Redis数据
-	h.redis.ClearReturnData(ctx)
+	// 清除Redis数据，终端换用新的二维码，并通知终端和读者手机
+	h.redis.ClearReturnData(ctx, kiosk)
 
-	Success(c, map[string]interface{}{
-		"message": "归还成功",
-	})
+	result := map[string]interface{}{
+		"message": message(c, i18n.MsgReturned),
+	}
+	// 新会话只返回给终端，读者手机用会话令牌完成时不返回
+	if session, err := h.redis.RotateKioskSession(ctx, kiosk, service.KioskModeReturn); err == nil && session != nil && sessionToken(c) == "" {
+		result["session"] = session
+	}
+	h.redis.PublishKioskEvent(ctx, kiosk, service.KioskModeReturn, service.KioskEventCompleted, nil)
+	Success(c, result)
 }
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/ut"
+
+	"booksystem/internal/db"
+)
+
+// stock 返回图书的在库数量
+func (s *testServer) stock(id int64) int {
+	s.t.Helper()
+	var book db.Book
+	if err := s.db.First(&book, id).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	return book.InStock
+}
+
+func TestBorrowAndReturn(t *testing.T) {
+	s := newTestServer(t, nil)
+	id, code := s.createBook(map[string]interface{}{"name": "x", "quantity": 1})
+
+	borrow := map[string]interface{}{"borrower_name": "张三", "borrower_phone": "13800000001", "barcodes": []string{code}}
+	var record struct {
+		ID    int64 `json:"id"`
+		Books []struct {
+			ID      *int64 `json:"id"`
+			Barcode string `json:"barcode"`
+		} `json:"books"`
+	}
+	s.ok("POST", "/api/v1/borrow", borrow).decode(t, &record)
+	if len(record.Books) != 1 || record.Books[0].ID == nil || *record.Books[0].ID != id {
+		t.Fatalf("borrowed books = %+v, want book %d", record.Books, id)
+	}
+	if got := s.stock(id); got != 0 {
+		t.Errorf("in_stock after borrow = %d, want 0", got)
+	}
+
+	// 借阅不校验库存：全部借出后仍可借，在库数量不再减少
+	s.ok("POST", "/api/v1/borrow", map[string]interface{}{"borrower_name": "李四", "borrower_phone": "13800000002", "barcodes": []string{code}})
+	if got := s.stock(id); got != 0 {
+		t.Errorf("in_stock after borrowing an exhausted book = %d, want 0", got)
+	}
+
+	// 电话不匹配时找不到借阅
+	if r := s.do("POST", "/api/v1/borrow/return", map[string]interface{}{"barcode": code, "borrower_phone": "13800000009"}); r.Status != 404 || r.ErrorCode != "LOAN_NOT_FOUND" {
+		t.Errorf("return with wrong phone: got %d %s, want 404 LOAN_NOT_FOUND", r.Status, r.ErrorCode)
+	}
+
+	s.ok("POST", "/api/v1/borrow/return", map[string]interface{}{"barcode": code, "borrower_phone": "13800000001"})
+	if got := s.stock(id); got != 1 {
+		t.Errorf("in_stock after return = %d, want 1", got)
+	}
+	var status int8
+	s.db.Model(&db.BorrowRecord{}).Where("id = ?", record.ID).Pluck("status", &status)
+	if status != 2 {
+		t.Errorf("record status after return = %d, want 2", status)
+	}
+
+	// 在库数量已等于总数量时归还不再增加，不违反 in_stock <= quantity 约束
+	s.ok("POST", "/api/v1/borrow/return", map[string]interface{}{"barcode": code, "borrower_phone": "13800000002"})
+	if got := s.stock(id); got != 1 {
+		t.Errorf("in_stock after second return = %d, want 1", got)
+	}
+
+	// 已归还的借阅不能重复归还
+	if r := s.do("POST", "/api/v1/borrow/return", map[string]interface{}{"barcode": code, "borrower_phone": "13800000001"}); r.Status != 404 {
+		t.Errorf("repeated return: got %d, want 404", r.Status)
+	}
+}
+
+func TestKioskBorrowAndReturn(t *testing.T) {
+	s := newTestServer(t, newTestRedis(t))
+	id, code := s.createBook(map[string]interface{}{"name": "x", "quantity": 2})
+
+	kiosk := ut.Header{Key: KioskIDHeader, Value: "k1"}
+	newSession := func(mode string) ut.Header {
+		var session struct {
+			Token string `json:"token"`
+		}
+		s.ok("POST", "/api/v1/kiosk/sessions", map[string]interface{}{"mode": mode}, kiosk).decode(t, &session)
+		return ut.Header{Key: KioskSessionHeader, Value: session.Token}
+	}
+	rotated := func(r testResponse) bool {
+		var result map[string]json.RawMessage
+		r.decode(t, &result)
+		_, ok := result["session"]
+		return ok
+	}
+
+	// 读者手机扫码填写信息并添加图书，终端完成借阅，新会话返回给终端
+	phone := newSession("borrow")
+	s.ok("POST", "/api/v1/borrow/user", map[string]interface{}{"name": "张三", "phone": "13800000001"}, phone)
+	s.ok("POST", "/api/v1/borrow/book", map[string]interface{}{"barcode": code}, phone)
+	if r := s.ok("POST", "/api/v1/borrow/complete", map[string]interface{}{"use_redis": true}, kiosk); !rotated(r) {
+		t.Error("kiosk completing a borrow did not receive the new session")
+	}
+	if got := s.stock(id); got != 1 {
+		t.Errorf("in_stock after kiosk borrow = %d, want 1", got)
+	}
+
+	// 完成后旧二维码失效
+	if r := s.do("POST", "/api/v1/borrow/user", map[string]interface{}{"name": "张三", "phone": "13800000001"}, phone); r.ErrorCode != "SESSION_EXPIRED" {
+		t.Errorf("old session after completion: got %d %s, want SESSION_EXPIRED", r.Status, r.ErrorCode)
+	}
+
+	// 读者手机自己完成时不返回新会话
+	phone = newSession("borrow")
+	s.ok("POST", "/api/v1/borrow/user", map[string]interface{}{"name": "李四", "phone": "13800000002"}, phone)
+	s.ok("POST", "/api/v1/borrow/book", map[string]interface{}{"barcode": code}, phone)
+	if r := s.ok("POST", "/api/v1/borrow/complete", map[string]interface{}{"use_redis": true}, phone); rotated(r) {
+		t.Error("phone completing a borrow received the kiosk's new session")
+	}
+	if got := s.stock(id); got != 0 {
+		t.Errorf("in_stock after second kiosk borrow = %d, want 0", got)
+	}
+
+	// 归还
+	phone = newSession("return")
+	s.ok("POST", "/api/v1/return/user", map[string]interface{}{"name": "张三", "phone": "13800000001"}, phone)
+	s.ok("POST", "/api/v1/return/book", map[string]interface{}{"barcode": code}, phone)
+	if r := s.ok("POST", "/api/v1/return/complete", map[string]interface{}{"use_redis": true}, kiosk); !rotated(r) {
+		t.Error("kiosk completing a return did not receive the new session")
+	}
+	if got := s.stock(id); got != 1 {
+		t.Errorf("in_stock after kiosk return = %d, want 1", got)
+	}
+	var open int64
+	s.db.Model(&db.BorrowRecord{}).Where("borrower_phone = ? AND status = 1", "13800000001").Count(&open)
+	if open != 0 {
+		t.Errorf("%d open loans left after return, want 0", open)
+	}
+}
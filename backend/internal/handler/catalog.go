@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/catalog"
+)
+
+type CatalogHandler struct {
+	provider catalog.Provider
+	local    *catalog.LocalProvider
+	dir      string
+}
+
+// NewCatalogHandler provider 用于查询（可组合多个来源），local 为本地目录，上传的目录文件保存在 dir 中
+func NewCatalogHandler(provider catalog.Provider, local *catalog.LocalProvider, dir string) *CatalogHandler {
+	return &CatalogHandler{provider: provider, local: local, dir: dir}
+}
+
+// Lookup 根据一维码/ISBN查询书目元数据，用于录入时预填表单
+func (h *CatalogHandler) Lookup(ctx context.Context, c *app.RequestContext) {
+	code := c.Param("barcode")
+	entry, err := h.provider.Lookup(ctx, code)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	if entry == nil {
+		Fail(c, apperr.New(apperr.CatalogEntryNotFound))
+		return
+	}
+	Success(c, entry)
+}
+
+// Status 查询本地目录加载情况
+func (h *CatalogHandler) Status(ctx context.Context, c *app.RequestContext) {
+	Success(c, map[string]interface{}{
+		"provider": h.provider.Name(),
+		"entries":  h.local.Len(),
+		"files":    h.local.Files(),
+	})
+}
+
+// Import 上传 CSV/MARC 目录文件，保存到目录文件夹并立即加载，重启后自动重新加载。
+// 上传内容先写入临时文件并解析，成功后才替换同名的已有文件及其条目，解析失败时已有文件不受影响
+func (h *CatalogHandler) Import(ctx context.Context, c *app.RequestContext) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		Fail(c, apperr.Invalid("file", apperr.Required))
+		return
+	}
+	name := filepath.Base(fileHeader.Filename)
+	if !catalog.Supported(name) {
+		Fail(c, apperr.New(apperr.UnsupportedImportFile))
+		return
+	}
+
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		Fail(c, err)
+		return
+	}
+	// 临时文件的扩展名不受支持，启动时 LoadDir 不会加载中途遗留的临时文件
+	tmp, err := os.CreateTemp(h.dir, name+".*.tmp")
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+	if err := c.SaveUploadedFile(fileHeader, tmpPath); err != nil {
+		Fail(c, err)
+		return
+	}
+
+	entries, err := catalog.ReadFile(tmpPath, name)
+	if err != nil {
+		Fail(c, apperr.Wrap(apperr.InvalidFile, err, "reason", err.Error()))
+		return
+	}
+	if err := os.Rename(tmpPath, filepath.Join(h.dir, name)); err != nil {
+		Fail(c, err)
+		return
+	}
+	h.local.Load(entries, name)
+
+	Success(c, map[string]interface{}{
+		"file":    name,
+		"loaded":  len(entries),
+		"entries": h.local.Len(),
+	})
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/db"
+	"booksystem/internal/i18n"
+	"booksystem/internal/service"
+)
+
+// exportBatchSize 每批从数据库读取的行数
+const exportBatchSize = 500
+
+type ExportHandler struct {
+	db *gorm.DB
+}
+
+func NewExportHandler(db *gorm.DB) *ExportHandler {
+	return &ExportHandler{db: db}
+}
+
+// 各导出表格的列，值为列名在 i18n 提示目录中的键
+var (
+	bookColumns = []string{
+		"COLUMN_ID", "COLUMN_BARCODE", "COLUMN_TITLE", "COLUMN_AUTHORS", "COLUMN_PUBLISHER", "COLUMN_PUBLISH_YEAR",
+		"COLUMN_EDITION", "COLUMN_LANGUAGE", "COLUMN_CLASS_NUMBER", "COLUMN_TAGS", "COLUMN_QUANTITY", "COLUMN_IN_STOCK",
+		"COLUMN_LOCATION", "COLUMN_PRICE", "COLUMN_REMARK", "COLUMN_CREATED_AT",
+	}
+	borrowRecordColumns = []string{
+		"COLUMN_RECORD_ID", "COLUMN_BORROWER_NAME", "COLUMN_BORROWER_PHONE", "COLUMN_BORROW_TIME", "COLUMN_STATUS",
+		"COLUMN_BARCODE", "COLUMN_TITLE",
+	}
+	borrowerColumns = []string{"COLUMN_ID", "COLUMN_NAME", "COLUMN_PHONE", "COLUMN_CREATED_AT"}
+)
+
+// Books 导出图书（筛选条件与图书列表相同），表头和分隔符使用请求的语言
+func (h *ExportHandler) Books(ctx context.Context, c *app.RequestContext) {
+	query := applyBookFilters(h.db.Model(&db.Book{}), c)
+	lang := requestLang(c)
+	h.stream(c, "books", query, func(w service.TableWriter) error {
+		return writeBooks(w, query, lang)
+	})
+}
+
+// ExportBooks 将全部图书按 format（csv 或 xlsx）写入 out，列与图书导出接口相同，表头使用 lang 语言
+func ExportBooks(database *gorm.DB, out io.Writer, format string, lang i18n.Lang) error {
+	w, err := service.NewTableWriter(out, format)
+	if err != nil {
+		return err
+	}
+	err = writeBooks(w, database.Model(&db.Book{}), lang)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeBooks 逐批查询 query 中的图书并写出表头和数据行
+func writeBooks(w service.TableWriter, query *gorm.DB, lang i18n.Lang) error {
+	query = preloadBookMetadata(query.Preload("ShelfLayer.Bookshelf.Area"))
+	if err := w.WriteRow(columnNames(lang, bookColumns)); err != nil {
+		return err
+	}
+	sep := i18n.Text(lang, i18n.MsgListSeparator, nil)
+
+	var books []db.Book
+	return query.FindInBatches(&books, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, book := range books {
+			if err := w.WriteRow([]string{
+				strconv.FormatInt(book.ID, 10),
+				book.Barcode,
+				book.Name,
+				joinNames(book.Authors, sep, func(a db.Author) string { return a.Name }),
+				stringValue(publisherName(book)),
+				intValue(book.PublishYear),
+				stringValue(book.Edition),
+				stringValue(book.Language),
+				stringValue(book.ClassNumber),
+				joinNames(book.Tags, sep, func(t db.Tag) string { return t.Name }),
+				strconv.Itoa(book.Quantity),
+				strconv.Itoa(book.InStock),
+				stringValue(shelfLayerName(book)),
+				floatValue(book.Price),
+				stringValue(book.Remark),
+				formatTime(book.CreatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// BorrowRecords 导出借阅记录（筛选条件与借阅记录列表相同，每本图书一行）
+func (h *ExportHandler) BorrowRecords(ctx context.Context, c *app.RequestContext) {
+	records := applyBorrowRecordFilters(h.db.Model(&db.BorrowRecord{}), c)
+	query := records.Session(&gorm.Session{}).Preload("Details.Book")
+	lang := requestLang(c)
+
+	// 每条明细一行，没有明细的记录一行，与左连接的行数相同
+	rows := h.db.Table("(?) AS r", records.Select("id")).
+		Joins("LEFT JOIN borrow_details d ON d.borrow_record_id = r.id")
+
+	h.stream(c, "borrow-records", rows, func(w service.TableWriter) error {
+		if err := w.WriteRow(columnNames(lang, borrowRecordColumns)); err != nil {
+			return err
+		}
+
+		var records []db.BorrowRecord
+		return query.FindInBatches(&records, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, record := range records {
+				base := []string{
+					strconv.FormatInt(record.ID, 10),
+					record.BorrowerName,
+					record.BorrowerPhone,
+					formatTime(record.BorrowTime),
+					borrowStatusText(lang, record.Status),
+				}
+				// 已全部归还的记录没有明细，仍输出一行
+				if len(record.Details) == 0 {
+					if err := w.WriteRow(append(base, "", "")); err != nil {
+						return err
+					}
+					continue
+				}
+				for _, detail := range record.Details {
+					var name string
+					if detail.Book != nil {
+						name = detail.Book.Name
+					}
+					row := append(append([]string{}, base...), detail.Barcode, name)
+					if err := w.WriteRow(row); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// Borrowers 导出借阅用户
+func (h *ExportHandler) Borrowers(ctx context.Context, c *app.RequestContext) {
+	query := h.db.Model(&db.Borrower{})
+	if name := c.Query("name"); name != "" {
+		query = query.Where(likeLower("name"), "%"+name+"%")
+	}
+	if phone := c.Query("phone"); phone != "" {
+		query = query.Where("phone = ?", phone)
+	}
+
+	lang := requestLang(c)
+
+	h.stream(c, "borrowers", query, func(w service.TableWriter) error {
+		if err := w.WriteRow(columnNames(lang, borrowerColumns)); err != nil {
+			return err
+		}
+
+		var borrowers []db.Borrower
+		return query.FindInBatches(&borrowers, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, borrower := range borrowers {
+				if err := w.WriteRow([]string{
+					strconv.FormatInt(borrower.ID, 10),
+					borrower.Name,
+					borrower.Phone,
+					formatTime(borrower.CreatedAt),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// stream 以流式响应输出表格，write 在后台协程中逐批写入数据。
+// XLSX 不能流式输出，先检查行数上限，rows 的记录数须与写出的数据行数相同
+func (h *ExportHandler) stream(c *app.RequestContext, name string, rows *gorm.DB, write func(w service.TableWriter) error) {
+	format := c.DefaultQuery("format", service.ExportFormatCSV)
+	if format != service.ExportFormatCSV && format != service.ExportFormatXLSX {
+		Fail(c, apperr.Invalid("format", apperr.InvalidValue))
+		return
+	}
+	if format == service.ExportFormatXLSX {
+		var n int64
+		if err := rows.Session(&gorm.Session{}).Count(&n).Error; err != nil {
+			Fail(c, err)
+			return
+		}
+		if n > service.MaxXLSXRows {
+			Fail(c, apperr.New(apperr.ExportTooLarge, "max", service.MaxXLSXRows))
+			return
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw, err := service.NewTableWriter(pw, format)
+		if err == nil {
+			err = write(tw)
+			if closeErr := tw.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		// 出错时中断响应流，客户端会收到不完整的文件而不是看似完整的数据
+		pw.CloseWithError(err)
+	}()
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.SetContentType(service.ExportContentType(format))
+	c.SetBodyStream(pr, -1)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func intValue(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
+
+// columnNames lang 语言的表头
+func columnNames(lang i18n.Lang, keys []string) []string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = i18n.Text(lang, key, nil)
+	}
+	return names
+}
+
+// joinNames 将作者、标签等名称以 sep 连接
+func joinNames[T any](items []T, sep string, name func(T) string) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = name(item)
+	}
+	return strings.Join(names, sep)
+}
+
+func floatValue(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', 2, 64)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func borrowStatusText(lang i18n.Lang, status int8) string {
+	switch status {
+	case 1:
+		return i18n.Text(lang, i18n.MsgStatusBorrowed, nil)
+	case 2:
+		return i18n.Text(lang, i18n.MsgStatusReturned, nil)
+	default:
+		return strconv.Itoa(int(status))
+	}
+}
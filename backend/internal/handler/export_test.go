@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/ut"
+
+	"booksystem/internal/db"
+	"booksystem/internal/service"
+)
+
+// createBorrowRecord 直接写入一条借阅记录，每个一维码一条明细
+func (s *testServer) createBorrowRecord(status int8, barcodes ...string) {
+	s.t.Helper()
+	record := db.BorrowRecord{BorrowerName: "x", BorrowerPhone: "13800000000", BorrowTime: time.Now(), Status: status}
+	if err := s.db.Create(&record).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	if len(barcodes) == 0 {
+		return
+	}
+	details := make([]db.BorrowDetail, len(barcodes))
+	for i, code := range barcodes {
+		details[i] = db.BorrowDetail{BorrowRecordID: record.ID, Barcode: code}
+	}
+	if err := s.db.CreateInBatches(details, 500).Error; err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func TestExportBorrowRecordRows(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.createBorrowRecord(1)
+	s.createBorrowRecord(1, "A")
+	s.createBorrowRecord(1, "B", "C", "D")
+	s.createBorrowRecord(2, "E", "F") // 已归还，默认不导出
+
+	w := ut.PerformRequest(s.engine, "GET", "/api/v1/export/borrow-records", nil)
+	if status := w.Result().StatusCode(); status != 200 {
+		t.Fatalf("CSV export: status %d", status)
+	}
+	lines := strings.Split(strings.TrimSpace(string(w.Result().Body())), "\n")
+	if len(lines) != 6 {
+		t.Errorf("CSV export has %d lines, want a header and 5 rows:\n%s", len(lines), w.Result().Body())
+	}
+
+	// 记录数未超出上限，但按明细展开后超出
+	barcodes := make([]string, service.MaxXLSXRows)
+	for i := range barcodes {
+		barcodes[i] = "X"
+	}
+	s.createBorrowRecord(1, barcodes...)
+	r := s.do("GET", "/api/v1/export/borrow-records?format=xlsx", nil)
+	if r.Status != 400 || r.ErrorCode != "EXPORT_TOO_LARGE" {
+		t.Errorf("XLSX export of %d rows: got %d %s, want 400 EXPORT_TOO_LARGE", service.MaxXLSXRows+5, r.Status, r.ErrorCode)
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"gorm.io/gorm"
+
+	"booksystem/internal/db/migrations"
+	"booksystem/internal/service"
+)
+
+// healthCheckTimeout 就绪检查中每项检查的超时时间
+const healthCheckTimeout = 2 * time.Second
+
+// 检查结果
+const (
+	healthOK          = "ok"
+	healthUnavailable = "unavailable"
+	healthDisabled    = "disabled"
+	healthDraining    = "draining"
+)
+
+type HealthHandler struct {
+	db       *gorm.DB
+	redis    *service.RedisService
+	draining atomic.Bool
+}
+
+// NewHealthHandler redis 为空表示启动时未能连接 Redis，借还书会话功能已停用
+func NewHealthHandler(db *gorm.DB, redis *service.RedisService) *HealthHandler {
+	return &HealthHandler{db: db, redis: redis}
+}
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Version int64  `json:"version,omitempty"` // 数据库已执行到的迁移版本
+}
+
+// HealthStatus 健康检查响应，与业务接口不同，不可用时返回 HTTP 503
+type HealthStatus struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// Live 存活检查：进程能处理请求即返回 200，不检查依赖，依赖故障时不应重启服务
+func (h *HealthHandler) Live(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, HealthStatus{Status: healthOK})
+}
+
+// Ready 就绪检查：数据库可读且迁移已全部执行、Redis 可用时返回 200，否则返回 503。
+// 开始停止服务后始终返回 503，负载均衡不再转发新请求
+func (h *HealthHandler) Ready(ctx context.Context, c *app.RequestContext) {
+	checks := map[string]HealthCheck{
+		"database": h.checkDatabase(ctx),
+		"redis":    h.checkRedis(ctx),
+	}
+	status := healthOK
+	for _, check := range checks {
+		if check.Status == healthUnavailable {
+			status = healthUnavailable
+		}
+	}
+	if h.draining.Load() {
+		status = healthDraining
+	}
+
+	code := consts.StatusOK
+	if status != healthOK {
+		code = consts.StatusServiceUnavailable
+	}
+	c.JSON(code, HealthStatus{Status: status, Checks: checks})
+}
+
+// Drain 标记服务正在停止，作为 Hertz 的 OnShutdown 钩子调用
+func (h *HealthHandler) Drain(ctx context.Context) {
+	h.draining.Store(true)
+}
+
+// checkDatabase 只读地查询迁移版本表，不执行任何 DDL：数据库被锁或连接断开时读取失败；
+// 版本表不存在（尚未迁移）、有未执行的迁移，或数据库的版本比当前程序新时也视为不可用
+func (h *HealthHandler) checkDatabase(ctx context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	migrator, err := migrations.New(h.db.WithContext(ctx))
+	if err != nil {
+		return HealthCheck{Status: healthUnavailable, Error: err.Error()}
+	}
+	list, err := migrator.Inspect()
+	if err != nil {
+		return HealthCheck{Status: healthUnavailable, Error: err.Error()}
+	}
+
+	check := HealthCheck{Status: healthOK}
+	pending, unknown := 0, 0
+	for _, s := range list {
+		switch {
+		case s.Unknown:
+			unknown++
+		case !s.Applied:
+			pending++
+		}
+		if s.Applied {
+			check.Version = s.Version
+		}
+	}
+	if pending > 0 {
+		check.Status = healthUnavailable
+		check.Error = fmt.Sprintf("%d pending migration(s)", pending)
+	} else if unknown > 0 {
+		check.Status = healthUnavailable
+		check.Error = fmt.Sprintf("database has %d migration(s) unknown to this build", unknown)
+	}
+	return check
+}
+
+func (h *HealthHandler) checkRedis(ctx context.Context) HealthCheck {
+	if h.redis == nil {
+		return HealthCheck{Status: healthDisabled}
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := h.redis.Ping(ctx); err != nil {
+		return HealthCheck{Status: healthUnavailable, Error: err.Error()}
+	}
+	return HealthCheck{Status: healthOK}
+}
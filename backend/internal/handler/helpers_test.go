@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"booksystem/internal/barcode"
+	"booksystem/internal/db/migrations"
+	"booksystem/internal/service"
+	"booksystem/internal/storage"
+)
+
+func init() {
+	// 不输出注册路由等调试日志
+	hlog.SetLevel(hlog.LevelWarn)
+}
+
+// migrateTestDB 对 openTestDB 打开的数据库执行全部迁移，测试结束时关闭连接
+func migrateTestDB(t *testing.T, database *gorm.DB) *gorm.DB {
+	t.Helper()
+	database.Logger = logger.Discard
+	migrator, err := migrations.New(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	return closeTestDB(t, database)
+}
+
+// closeTestDB 测试结束时关闭连接
+func closeTestDB(t *testing.T, database *gorm.DB) *gorm.DB {
+	t.Helper()
+	database.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return database
+}
+
+// newTestRedis 启动内存中的 Redis，测试结束时关闭
+func newTestRedis(t *testing.T) *service.RedisService {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redis, err := service.NewRedisService(mr.Addr(), "", 0, time.Hour, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return redis
+}
+
+// testServer 注册了图书、借还书、位置删除合并、上架、标签和扫码会话接口的路由，路由与 main.go 中相同
+type testServer struct {
+	t      *testing.T
+	db     *gorm.DB
+	engine *route.Engine
+}
+
+// newTestServer redis 为空时不注册扫码会话接口，借还书终端接口不可用
+func newTestServer(t *testing.T, redis *service.RedisService) *testServer {
+	t.Helper()
+	database := openTestDB(t)
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	barcodes := barcode.NewValidator([]string{"LIB"})
+
+	engine := route.NewEngine(config.NewOptions([]config.Option{server.WithCustomValidator(Validator{})}))
+	books := NewBookHandler(database, barcodes, store)
+	borrows := NewBorrowHandler(database, redis, barcodes, 0)
+	api := engine.Group("/api/v1")
+	api.POST("/books", books.Create)
+	api.GET("/books", books.List)
+	api.PUT("/books/:id", books.Update)
+	api.DELETE("/books/:id", books.Delete)
+	api.POST("/borrow", borrows.Create)
+	api.POST("/borrow/return", borrows.Return)
+	areas, bookshelves, layers := NewAreaHandler(database), NewBookshelfHandler(database), NewShelfLayerHandler(database)
+	api.DELETE("/areas/:id", areas.Delete)
+	api.DELETE("/bookshelves/:id", bookshelves.Delete)
+	api.POST("/shelf-layers/:id/merge", layers.Merge)
+	api.DELETE("/shelf-layers/:id", layers.Delete)
+	api.POST("/labels/sheet", NewLabelHandler(database, barcodes, "").Sheet)
+	shelving := NewShelvingHandler(database)
+	api.POST("/shelving", shelving.Shelve)
+	api.GET("/shelving/logs", shelving.Logs)
+	api.GET("/export/borrow-records", NewExportHandler(database).BorrowRecords)
+	if redis != nil {
+		kiosk := NewKioskHandler(redis, "http://kiosk.test")
+		api.POST("/kiosk/sessions", kiosk.CreateSession)
+		api.POST("/borrow/user", borrows.SetBorrowUser)
+		api.POST("/borrow/book", borrows.AddBorrowBook)
+		api.POST("/borrow/complete", borrows.CompleteBorrow)
+		api.POST("/return/user", borrows.SetReturnUser)
+		api.POST("/return/book", borrows.AddReturnBook)
+		api.POST("/return/complete", borrows.CompleteReturn)
+	}
+	return &testServer{t: t, db: database, engine: engine}
+}
+
+// sub 返回在子测试 t 中报告失败的 testServer，数据库和路由共用
+func (s *testServer) sub(t *testing.T) *testServer {
+	return &testServer{t: t, db: s.db, engine: s.engine}
+}
+
+// testResponse 统一响应格式，Data 留待按接口解析
+type testResponse struct {
+	Status    int
+	ErrorCode string          `json:"error_code"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// decode 将 Data 解析到 v
+func (r testResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatalf("decode %s: %v", r.Data, err)
+	}
+}
+
+// do 发送请求，body 不为空时以 JSON 发送
+func (s *testServer) do(method, path string, body interface{}, headers ...ut.Header) testResponse {
+	s.t.Helper()
+	var reqBody *ut.Body
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reqBody = &ut.Body{Body: bytes.NewReader(raw), Len: len(raw)}
+		headers = append(headers, ut.Header{Key: "Content-Type", Value: "application/json"})
+	}
+	w := ut.PerformRequest(s.engine, method, path, reqBody, headers...)
+	resp := w.Result()
+
+	var r testResponse
+	if err := json.Unmarshal(resp.Body(), &r); err != nil {
+		s.t.Fatalf("%s %s: decode response %q: %v", method, path, resp.Body(), err)
+	}
+	r.Status = resp.StatusCode()
+	return r
+}
+
+// ok 发送请求并要求成功
+func (s *testServer) ok(method, path string, body interface{}, headers ...ut.Header) testResponse {
+	s.t.Helper()
+	r := s.do(method, path, body, headers...)
+	if r.Status != 200 {
+		s.t.Fatalf("%s %s: status %d, %s: %s", method, path, r.Status, r.ErrorCode, r.Message)
+	}
+	return r
+}
+
+// createBook 通过接口创建图书，返回图书ID和一维码
+func (s *testServer) createBook(req map[string]interface{}) (int64, string) {
+	s.t.Helper()
+	var book struct {
+		ID      int64  `json:"id"`
+		Barcode string `json:"barcode"`
+	}
+	s.ok("POST", "/api/v1/books", req).decode(s.t, &book)
+	return book.ID, book.Barcode
+}
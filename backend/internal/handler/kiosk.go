@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/label"
+	"booksystem/internal/logging"
+	"booksystem/internal/service"
+)
+
+const (
+	// 请求头：终端标识与扫码会话令牌
+	KioskIDHeader      = "X-Kiosk-ID"
+	KioskSessionHeader = "X-Kiosk-Session"
+
+	// kioskPagePath 手机端扫码页面路径，后接会话令牌
+	kioskPagePath = "/kiosk/"
+
+	// kioskHeartbeatInterval 事件流心跳间隔，同时用于检查扫码会话是否失效
+	kioskHeartbeatInterval = 15 * time.Second
+)
+
+var kioskIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+type KioskHandler struct {
+	redis     *service.RedisService
+	baseURL   string
+	closing   chan struct{} // 服务停止时关闭，结束事件流
+	closeOnce sync.Once
+}
+
+// NewKioskHandler baseURL 为前端对外访问地址（如 https://lib.example.com），为空时使用终端页面的地址
+func NewKioskHandler(redis *service.RedisService, baseURL string) *KioskHandler {
+	return &KioskHandler{redis: redis, baseURL: strings.TrimRight(baseURL, "/"), closing: make(chan struct{})}
+}
+
+// Drain 结束全部事件流，作为 Hertz 的 OnShutdown 钩子调用。
+// 事件流是长连接，不结束时服务要等到停止超时才能退出；EventSource 断开后会自动重连到其他实例
+func (h *KioskHandler) Drain(ctx context.Context) {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// CreateKioskSessionRequest 创建扫码会话请求
+type CreateKioskSessionRequest struct {
+	Kiosk   string `json:"kiosk"`
+	Mode    string `json:"mode"`     // borrow（默认）或 return
+	BaseURL string `json:"base_url"` // 终端页面的地址（window.location.origin），未配置 PUBLIC_URL 时使用
+}
+
+// CreateSession 为终端创建新的扫码会话，之前的二维码随即失效
+func (h *KioskHandler) CreateSession(ctx context.Context, c *app.RequestContext) {
+	var req CreateKioskSessionRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = service.KioskModeBorrow
+	}
+	if req.Mode != service.KioskModeBorrow && req.Mode != service.KioskModeReturn {
+		Fail(c, apperr.Invalid("mode", apperr.InvalidValue))
+		return
+	}
+	kiosk := req.Kiosk
+	if kiosk == "" {
+		kiosk = string(c.GetHeader(KioskIDHeader))
+	}
+	if kiosk == "" {
+		kiosk = service.DefaultKiosk
+	}
+	if !kioskIDPattern.MatchString(kiosk) {
+		Fail(c, apperr.Invalid("kiosk", apperr.InvalidValue))
+		return
+	}
+
+	baseURL := h.baseURL
+	if baseURL == "" {
+		baseURL = strings.TrimRight(req.BaseURL, "/")
+	}
+	if baseURL == "" {
+		baseURL = "http://" + string(c.Host())
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		Fail(c, apperr.Invalid("base_url", apperr.InvalidValue))
+		return
+	}
+
+	session, err := h.redis.CreateKioskSession(ctx, kiosk, req.Mode, baseURL+kioskPagePath)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, session)
+}
+
+// GetSession 查询扫码会话，手机端打开页面时用于确认二维码是否有效
+func (h *KioskHandler) GetSession(ctx context.Context, c *app.RequestContext) {
+	session, ok := h.session(ctx, c)
+	if !ok {
+		return
+	}
+	Success(c, session)
+}
+
+// QRCode 生成扫码会话的二维码图片，format=png|svg，module 为模块边长（像素）
+func (h *KioskHandler) QRCode(ctx context.Context, c *app.RequestContext) {
+	session, ok := h.session(ctx, c)
+	if !ok {
+		return
+	}
+
+	module, err := strconv.Atoi(c.DefaultQuery("module", "8"))
+	if err != nil || module < 1 || module > 40 {
+		Fail(c, apperr.Invalid("module", apperr.OutOfRange, "min", 1, "max", 40))
+		return
+	}
+	code, err := label.EncodeQR(session.URL)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format := c.DefaultQuery("format", "png"); format {
+	case "png":
+		err = label.WriteQRPNG(&buf, code, module)
+		contentType = "image/png"
+	case "svg":
+		err = label.WriteQRSVG(&buf, code, module)
+		contentType = "image/svg+xml"
+	default:
+		Fail(c, apperr.Invalid("format", apperr.InvalidValue))
+		return
+	}
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	// 二维码随会话轮换，不能缓存
+	c.Header("Cache-Control", "no-store")
+	c.Data(200, contentType, buf.Bytes())
+}
+
+// Events 以 SSE 推送终端的借阅/归还列表变化。
+// 终端通过 kiosk 参数订阅，读者手机通过 session 参数订阅（EventSource 不能设置请求头），
+// 手机端订阅的会话失效时推送 expired 事件并结束。
+func (h *KioskHandler) Events(ctx context.Context, c *app.RequestContext) {
+	mode := c.DefaultQuery("mode", service.KioskModeBorrow)
+	if mode != service.KioskModeBorrow && mode != service.KioskModeReturn {
+		Fail(c, apperr.Invalid("mode", apperr.InvalidValue))
+		return
+	}
+	kiosk, ok := resolveKiosk(ctx, c, h.redis, mode)
+	if !ok {
+		return
+	}
+	token := sessionToken(c)
+
+	sub, err := h.redis.SubscribeKioskEvents(ctx, kiosk, mode)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.SetStatusCode(200)
+	c.Response.Header.SetContentType("text/event-stream")
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("X-Accel-Buffering", "no")
+	c.Response.HijackWriter(resp.NewChunkedBodyWriter(&c.Response, c.GetWriter()))
+
+	// 先推送当前状态，客户端据此初始化
+	basket, err := loadBasket(ctx, h.redis, kiosk, mode)
+	if err != nil {
+		return
+	}
+	if err := writeEvent(c, &service.KioskEvent{Type: service.KioskEventState, Data: basket, Time: time.Now()}); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(kioskHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.closing:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err := writeEvent(c, event); err != nil {
+				return
+			}
+			// 完成后终端换用了新会话，手机端的会话随之失效
+			if token != "" && event.Type == service.KioskEventCompleted {
+				writeEvent(c, &service.KioskEvent{Type: service.KioskEventExpired, Time: time.Now()})
+				return
+			}
+		case <-ticker.C:
+			if token != "" {
+				exists, err := h.redis.KioskSessionExists(ctx, token)
+				if err == nil && !exists {
+					writeEvent(c, &service.KioskEvent{Type: service.KioskEventExpired, Time: time.Now()})
+					return
+				}
+			}
+			// SSE 注释行作为心跳，连接断开时写入失败
+			if _, err := c.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			if err := c.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent 写入一条 SSE 事件并立即发送
+func writeEvent(c *app.RequestContext, event *service.KioskEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+// loadBasket 读取终端当前的借阅/归还用户和图书列表
+func loadBasket(ctx context.Context, redis *service.RedisService, kiosk, mode string) (map[string]interface{}, error) {
+	getUser, getBooks := redis.GetBorrowUser, redis.GetBorrowBooks
+	if mode == service.KioskModeReturn {
+		getUser, getBooks = redis.GetReturnUser, redis.GetReturnBooks
+	}
+
+	user, err := getUser(ctx, kiosk)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return map[string]interface{}{
+			"user":  nil,
+			"books": []interface{}{},
+		}, nil
+	}
+	books, err := getBooks(ctx, kiosk)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"user":  user,
+		"books": books,
+	}, nil
+}
+
+func (h *KioskHandler) session(ctx context.Context, c *app.RequestContext) (*service.KioskSession, bool) {
+	session, err := h.redis.GetKioskSession(ctx, c.Param("token"))
+	if err != nil {
+		Fail(c, err)
+		return nil, false
+	}
+	if session == nil {
+		Fail(c, apperr.New(apperr.SessionExpired))
+		return nil, false
+	}
+	return session, true
+}
+
+// resolveKiosk 确定借阅/归还接口操作的终端：
+// 手机端通过会话令牌（X-Kiosk-Session 请求头或 session 参数）绑定到扫码的终端，
+// 终端本身通过 X-Kiosk-ID 请求头或 kiosk 参数传入标识，都未传入时使用默认终端。
+func resolveKiosk(ctx context.Context, c *app.RequestContext, redis *service.RedisService, mode string) (string, bool) {
+	if token := sessionToken(c); token != "" {
+		session, err := redis.GetKioskSession(ctx, token)
+		if err != nil {
+			Fail(c, err)
+			return "", false
+		}
+		if session == nil || session.Mode != mode {
+			Fail(c, apperr.New(apperr.SessionExpired))
+			return "", false
+		}
+		c.Set(logging.ActorKey, "kiosk-session:"+session.Kiosk)
+		return session.Kiosk, true
+	}
+
+	kiosk := string(c.GetHeader(KioskIDHeader))
+	if kiosk == "" {
+		kiosk = c.Query("kiosk")
+	}
+	if kiosk == "" {
+		kiosk = service.DefaultKiosk
+	} else if !kioskIDPattern.MatchString(kiosk) {
+		Fail(c, apperr.Invalid("kiosk", apperr.InvalidValue))
+		return "", false
+	}
+	c.Set(logging.ActorKey, "kiosk:"+kiosk)
+	return kiosk, true
+}
+
+// sessionToken 读取请求中的扫码会话令牌
+func sessionToken(c *app.RequestContext) string {
+	if token := string(c.GetHeader(KioskSessionHeader)); token != "" {
+		return token
+	}
+	return c.Query("session")
+}
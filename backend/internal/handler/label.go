@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/barcode"
+	"booksystem/internal/db"
+	"booksystem/internal/label"
+)
+
+// maxLabelsPerSheet 单次生成的标签数上限
+const maxLabelsPerSheet = 2000
+
+type LabelHandler struct {
+	db       *gorm.DB
+	barcodes *barcode.Validator
+	fontPath string
+}
+
+// NewLabelHandler fontPath 为标签使用的中文 TTF 字体，为空时使用 PDF 内置字体
+func NewLabelHandler(db *gorm.DB, barcodes *barcode.Validator, fontPath string) *LabelHandler {
+	return &LabelHandler{db: db, barcodes: barcodes, fontPath: fontPath}
+}
+
+// LabelSheetRequest 生成标签页请求
+type LabelSheetRequest struct {
+	BookIDs []int64       `json:"book_ids" binding:"required"`
+	Layout  string        `json:"layout"`        // 预置版式名称，默认 avery-l7160
+	Custom  *label.Layout `json:"custom_layout"` // 自定义版式，传入时忽略 layout
+	Style   label.Style   `json:"style"`         // barcode（默认）或 spine
+	Copies  int           `json:"copies"`        // 每本书打印的份数，默认1
+	Skip    int           `json:"skip"`          // 跳过开头已用掉的标签位
+}
+
+// Barcode 生成一维码图片，format=svg|png，module 为模块宽度（像素），height 为条高（像素）
+func (h *LabelHandler) Barcode(ctx context.Context, c *app.RequestContext) {
+	bars, err := label.Encode(c.Param("code"))
+	if err != nil {
+		Fail(c, apperr.Wrap(apperr.BarcodeNotEncodable, err, "reason", err.Error()))
+		return
+	}
+
+	module, err := strconv.Atoi(c.DefaultQuery("module", "2"))
+	if err != nil || module < 1 || module > 10 {
+		Fail(c, apperr.Invalid("module", apperr.OutOfRange, "min", 1, "max", 10))
+		return
+	}
+	height, err := strconv.Atoi(c.DefaultQuery("height", "60"))
+	if err != nil || height < 10 || height > 1000 {
+		Fail(c, apperr.Invalid("height", apperr.OutOfRange, "min", 10, "max", 1000))
+		return
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format := c.DefaultQuery("format", "svg"); format {
+	case "svg":
+		err = label.WriteSVG(&buf, bars, module, height, c.DefaultQuery("text", "true") == "true")
+		contentType = "image/svg+xml"
+	case "png":
+		err = label.WritePNG(&buf, bars, module, height)
+		contentType = "image/png"
+	default:
+		Fail(c, apperr.Invalid("format", apperr.InvalidValue))
+		return
+	}
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	c.Data(200, contentType, buf.Bytes())
+}
+
+// Layouts 查询预置标签纸版式
+func (h *LabelHandler) Layouts(ctx context.Context, c *app.RequestContext) {
+	Success(c, map[string]interface{}{
+		"default": label.DefaultLayout,
+		"layouts": label.Presets(),
+	})
+}
+
+// Sheet 为选中的图书生成 PDF 标签页，没有一维码的图书先分配馆内条码
+func (h *LabelHandler) Sheet(ctx context.Context, c *app.RequestContext) {
+	var req LabelSheetRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+	if len(req.BookIDs) == 0 {
+		Fail(c, apperr.Invalid("book_ids", apperr.Required))
+		return
+	}
+
+	layout, ok := resolveLayout(c, req.Layout, req.Custom)
+	if !ok {
+		return
+	}
+	if req.Style == "" {
+		req.Style = label.StyleBarcode
+	}
+	if req.Style != label.StyleBarcode && req.Style != label.StyleSpine {
+		Fail(c, apperr.Invalid("style", apperr.InvalidValue))
+		return
+	}
+	if req.Copies == 0 {
+		req.Copies = 1
+	}
+	if req.Copies < 0 {
+		Fail(c, apperr.Invalid("copies", apperr.TooSmall, "min", 1))
+		return
+	}
+	// 先单独检查份数，避免份数很大时乘积溢出
+	if req.Copies > maxLabelsPerSheet || req.Copies*len(req.BookIDs) > maxLabelsPerSheet {
+		Fail(c, apperr.New(apperr.TooManyLabels, "max", maxLabelsPerSheet))
+		return
+	}
+	if !validSkip(c, layout, req.Skip) {
+		return
+	}
+
+	// 全部图书都存在时才分配馆内条码，有不存在的图书时整个事务回滚
+	var books []db.Book
+	byID := make(map[int64]db.Book, len(req.BookIDs))
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("ShelfLayer.Bookshelf.Area").Where("id IN ?", req.BookIDs).Find(&books).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			byID[book.ID] = book
+		}
+		for _, id := range req.BookIDs {
+			if _, ok := byID[id]; !ok {
+				return apperr.New(apperr.BookNotFound).WithData(map[string]int64{"book_id": id})
+			}
+		}
+		for i := range books {
+			if books[i].Barcode != "" {
+				continue
+			}
+			code, err := nextInternalBarcode(tx, h.barcodes)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&books[i]).Update("barcode", code).Error; err != nil {
+				return err
+			}
+			byID[books[i].ID] = books[i]
+		}
+		return nil
+	}); err != nil {
+		Fail(c, err)
+		return
+	}
+
+	// 按请求中的顺序排列标签
+	var labels []label.Label
+	for _, id := range req.BookIDs {
+		book := byID[id]
+		for i := 0; i < req.Copies; i++ {
+			labels = append(labels, label.Label{
+				Barcode:    book.Barcode,
+				Title:      book.Name,
+				Location:   stringValue(shelfLayerName(book)),
+				CallNumber: stringValue(book.ClassNumber),
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := label.Render(&buf, layout, labels, label.Options{Style: req.Style, Skip: req.Skip, FontPath: h.fontPath}); err != nil {
+		Fail(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("labels-%s.pdf", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(200, "application/pdf", buf.Bytes())
+}
+
+// ShelfLayerLabelRequest 生成层位置标签请求，按层ID或所属书架/区域选择
+type ShelfLayerLabelRequest struct {
+	ShelfLayerIDs []int64       `json:"shelf_layer_ids"`
+	BookshelfID   int64         `json:"bookshelf_id"`
+	AreaID        int64         `json:"area_id"`
+	Layout        string        `json:"layout"`
+	Custom        *label.Layout `json:"custom_layout"`
+	Style         label.Style   `json:"style"` // barcode 或 qr（默认）
+	Skip          int           `json:"skip"`
+}
+
+// ShelfLayers 生成层位置标签，贴在书架上供扫码上架使用
+func (h *LabelHandler) ShelfLayers(ctx context.Context, c *app.RequestContext) {
+	var req ShelfLayerLabelRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+	if len(req.ShelfLayerIDs) == 0 && req.BookshelfID == 0 && req.AreaID == 0 {
+		Fail(c, apperr.Validation(
+			apperr.Field("shelf_layer_ids", apperr.Required),
+			apperr.Field("bookshelf_id", apperr.Required),
+			apperr.Field("area_id", apperr.Required),
+		))
+		return
+	}
+
+	layout, ok := resolveLayout(c, req.Layout, req.Custom)
+	if !ok {
+		return
+	}
+	if req.Style == "" {
+		req.Style = label.StyleQR
+	}
+	if req.Style != label.StyleBarcode && req.Style != label.StyleQR {
+		Fail(c, apperr.Invalid("style", apperr.InvalidValue))
+		return
+	}
+	if !validSkip(c, layout, req.Skip) {
+		return
+	}
+
+	query := h.db.Preload("Bookshelf.Area").Joins("JOIN bookshelves ON bookshelves.id = shelf_layers.bookshelf_id")
+	switch {
+	case len(req.ShelfLayerIDs) > 0:
+		query = query.Where("shelf_layers.id IN ?", req.ShelfLayerIDs)
+	case req.BookshelfID != 0:
+		query = query.Where("shelf_layers.bookshelf_id = ?", req.BookshelfID)
+	default:
+		query = query.Where("bookshelves.area_id = ?", req.AreaID)
+	}
+	var layers []db.ShelfLayer
+	if err := query.Find(&layers).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+	sortShelfLayersByLocation(layers)
+	if len(layers) == 0 {
+		Fail(c, apperr.New(apperr.NoMatchingLayers))
+		return
+	}
+	if len(layers) > maxLabelsPerSheet {
+		Fail(c, apperr.New(apperr.TooManyLabels, "max", maxLabelsPerSheet))
+		return
+	}
+
+	labels := make([]label.Label, len(layers))
+	for i, layer := range layers {
+		labels[i] = label.Label{
+			Barcode:  stringValue(layer.LabelCode),
+			Title:    layerLocation(layer),
+			Location: layerLocationCode(layer),
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := label.Render(&buf, layout, labels, label.Options{Style: req.Style, Skip: req.Skip, FontPath: h.fontPath}); err != nil {
+		Fail(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("location-labels-%s.pdf", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(200, "application/pdf", buf.Bytes())
+}
+
+// resolveLayout 取自定义版式或预置版式（默认 avery-l7160），无效时写入错误响应
+func resolveLayout(c *app.RequestContext, name string, custom *label.Layout) (label.Layout, bool) {
+	layout, ok := label.Preset(label.DefaultLayout)
+	if custom != nil {
+		layout = *custom
+		if layout.Name == "" {
+			layout.Name = "custom"
+		}
+	} else if name != "" {
+		if layout, ok = label.Preset(name); !ok {
+			Fail(c, apperr.Invalid("layout", apperr.InvalidValue))
+			return layout, false
+		}
+	}
+	if err := layout.Validate(); err != nil {
+		Fail(c, apperr.Wrap(apperr.InvalidLabelLayout, err, "reason", err.Error()))
+		return layout, false
+	}
+	return layout, true
+}
+
+// validSkip 校验跳过的标签位数，无效时写入错误响应
+func validSkip(c *app.RequestContext, layout label.Layout, skip int) bool {
+	if skip < 0 || skip >= layout.PerPage() {
+		Fail(c, apperr.Invalid("skip", apperr.OutOfRange, "min", 0, "max", layout.PerPage()-1))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/ut"
+
+	"booksystem/internal/db"
+)
+
+func TestLabelSheetCopies(t *testing.T) {
+	s := newTestServer(t, nil)
+	id, _ := s.createBook(map[string]interface{}{"name": "x", "quantity": 1})
+
+	tests := []struct {
+		name string
+		req  map[string]interface{}
+	}{
+		// 1<<62 × 4 溢出为 0
+		{"copies overflow", map[string]interface{}{"book_ids": []int64{id, id, id, id}, "copies": int64(1) << 62}},
+		{"too many copies", map[string]interface{}{"book_ids": []int64{id}, "copies": maxLabelsPerSheet + 1}},
+	}
+	for _, tt := range tests {
+		if r := s.do("POST", "/api/v1/labels/sheet", tt.req); r.Status != 400 || r.ErrorCode != "TOO_MANY_LABELS" {
+			t.Errorf("%s: got %d %s, want 400 TOO_MANY_LABELS", tt.name, r.Status, r.ErrorCode)
+		}
+	}
+}
+
+func TestLabelSheetUnknownBook(t *testing.T) {
+	s := newTestServer(t, nil)
+	id, _ := s.createBook(map[string]interface{}{"name": "x", "quantity": 1})
+	// 没有一维码的图书在生成标签时分配馆内条码
+	if err := s.db.Model(&db.Book{}).Where("id = ?", id).Update("barcode", "").Error; err != nil {
+		t.Fatal(err)
+	}
+	sequence := func() int64 {
+		var seq db.BarcodeSequence
+		s.db.Where("prefix = ?", "LIB").First(&seq)
+		return seq.NextValue
+	}
+	before := sequence()
+
+	r := s.do("POST", "/api/v1/labels/sheet", map[string]interface{}{"book_ids": []int64{id, id + 100}})
+	if r.Status != 404 || r.ErrorCode != "BOOK_NOT_FOUND" {
+		t.Errorf("got %d %s, want 404 BOOK_NOT_FOUND", r.Status, r.ErrorCode)
+	}
+	var book db.Book
+	s.db.First(&book, id)
+	if book.Barcode != "" || sequence() != before {
+		t.Errorf("barcode %q, sequence %d -> %d after a rejected request; want nothing assigned", book.Barcode, before, sequence())
+	}
+
+	// 全部存在时分配条码并生成 PDF
+	body := []byte(`{"book_ids":[` + strconv.FormatInt(id, 10) + `]}`)
+	w := ut.PerformRequest(s.engine, "POST", "/api/v1/labels/sheet", &ut.Body{Body: bytes.NewReader(body), Len: len(body)},
+		ut.Header{Key: "Content-Type", Value: "application/json"})
+	if resp := w.Result(); resp.StatusCode() != 200 || string(resp.Header.ContentType()) != "application/pdf" {
+		t.Fatalf("valid request: got %d %s", resp.StatusCode(), resp.Body())
+	}
+	s.db.First(&book, id)
+	if book.Barcode == "" || sequence() == before {
+		t.Error("no internal barcode assigned for a valid request")
+	}
+}
@@ -2,6 +2,12 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"gorm.io/gorm"
@@ -9,72 +15,245 @@ import (
 	"booksystem/internal/db"
 )
 
+// locationTreeTTL 位置树缓存的最长有效期。变更时会立即失效，这里只是兜底。
+const locationTreeTTL = time.Minute
+
+// locationTreeTables 这些表变更后位置树（含图书数量）需要重建
+var locationTreeTables = map[string]bool{
+	"areas":        true,
+	"bookshelves":  true,
+	"shelf_layers": true,
+	"books":        true,
+}
+
 type LocationHandler struct {
 	db *gorm.DB
+
+	// version 相关表的变更提交后加一，构建期间发生变更的结果不写入缓存
+	version atomic.Int64
+	mu      sync.RWMutex
+	cache   *locationTreeCache
+}
+
+type locationTreeCache struct {
+	version int64
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// NewLocationHandler 创建处理器，并注册 GORM 回调在区域/书架/层/图书变更时使缓存失效。
+// 失效推迟到事务提交之后：提交前其他请求仍读到旧数据，若此时就失效，旧数据会以新版本号写入缓存。
+func NewLocationHandler(conn *gorm.DB) *LocationHandler {
+	h := &LocationHandler{db: conn}
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error == nil && locationTreeTables[tx.Statement.Table] {
+			db.AfterCommit(tx, func() { h.version.Add(1) })
+		}
+	}
+	cb := conn.Callback()
+	cb.Create().After("gorm:create").Register("location_tree:invalidate", invalidate)
+	cb.Update().After("gorm:update").Register("location_tree:invalidate", invalidate)
+	cb.Delete().After("gorm:delete").Register("location_tree:invalidate", invalidate)
+	return h
+}
+
+// ShelfLayerNode 位置树中的层
+type ShelfLayerNode struct {
+	ID           int64   `json:"id"`
+	Name         string  `json:"name"`
+	Code         *string `json:"code"`
+	LocationCode string  `json:"location_code,omitempty"` // 位置编号，如 A-03-2，各级都设置了简码时才有
+	LabelCode    *string `json:"label_code"`
+	Capacity     *int    `json:"capacity"`
+	BookCount    int64   `json:"book_count"`     // 图书种数
+	VolumeCount  int64   `json:"volume_count"`   // 馆藏册数
+	InStockCount int64   `json:"in_stock_count"` // 在库册数
+	OverCapacity bool    `json:"over_capacity"`  // 馆藏册数超出容量
+}
+
+// BookshelfNode 位置树中的书架，数量为下属各层之和
+type BookshelfNode struct {
+	ID                 int64            `json:"id"`
+	Name               string           `json:"name"`
+	Code               *string          `json:"code"`
+	BookCount          int64            `json:"book_count"`
+	VolumeCount        int64            `json:"volume_count"`
+	InStockCount       int64            `json:"in_stock_count"`
+	OverCapacityLayers int              `json:"over_capacity_layers"` // 超出容量的层数
+	ShelfLayers        []ShelfLayerNode `json:"shelf_layers"`
 }
 
-func NewLocationHandler(db *gorm.DB) *LocationHandler {
-	return &LocationHandler{db: db}
+// AreaNode 位置树中的区域，数量为下属各书架之和
+type AreaNode struct {
+	ID                 int64           `json:"id"`
+	Name               string          `json:"name"`
+	Code               *string         `json:"code"`
+	BookCount          int64           `json:"book_count"`
+	VolumeCount        int64           `json:"volume_count"`
+	InStockCount       int64           `json:"in_stock_count"`
+	OverCapacityLayers int             `json:"over_capacity_layers"`
+	Bookshelves        []BookshelfNode `json:"bookshelves"`
 }
 
-// GetTree 获取位置三级联动树
+// GetTree 获取位置三级联动树，附带各节点的图书数量。
+// 结果缓存到位置或图书变更为止，客户端可通过 If-None-Match 跳过未变化的树。
 func (h *LocationHandler) GetTree(ctx context.Context, c *app.RequestContext) {
-	var areas []db.Area
-	if err := h.db.Find(&areas).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+	cache, err := h.tree()
+	if err != nil {
+		Fail(c, err)
 		return
 	}
 
-	type ShelfLayerNode struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
+	c.Header("ETag", cache.etag)
+	c.Header("Cache-Control", "no-cache")
+	if string(c.GetHeader("If-None-Match")) == cache.etag {
+		c.Status(304)
+		return
 	}
+	c.Data(200, "application/json; charset=utf-8", cache.body)
+}
 
-	type BookshelfNode struct {
-		ID          int64           `json:"id"`
-		Name        string          `json:"name"`
-		ShelfLayers []ShelfLayerNode `json:"shelf_layers"`
+// tree 返回缓存的位置树，缓存失效时重建
+func (h *LocationHandler) tree() (*locationTreeCache, error) {
+	version := h.version.Load()
+	h.mu.RLock()
+	cache := h.cache
+	h.mu.RUnlock()
+	if cache != nil && cache.version == version && time.Now().Before(cache.expires) {
+		return cache, nil
 	}
 
-	type AreaNode struct {
-		ID          int64            `json:"id"`
-		Name        string           `json:"name"`
-		Bookshelves []BookshelfNode `json:"bookshelves"`
+	result, err := h.buildTree()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(Response{Code: 200, Message: "success", Data: result})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	cache = &locationTreeCache{
+		version: version,
+		body:    body,
+		etag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		expires: time.Now().Add(locationTreeTTL),
 	}
 
-	result := make([]AreaNode, 0, len(areas))
+	// 构建期间有变更时不缓存，下次请求重新构建
+	if h.version.Load() == version {
+		h.mu.Lock()
+		h.cache = cache
+		h.mu.Unlock()
+	}
+	return cache, nil
+}
 
+// buildTree 用三次查询取出区域、书架、层，再用一次聚合查询统计各层图书数量。
+// 同级节点按排序值和名称自然排序。
+func (h *LocationHandler) buildTree() ([]AreaNode, error) {
+	var areas []db.Area
+	if err := h.db.Find(&areas).Error; err != nil {
+		return nil, err
+	}
+	var bookshelves []db.Bookshelf
+	if err := h.db.Find(&bookshelves).Error; err != nil {
+		return nil, err
+	}
+	var layers []db.ShelfLayer
+	if err := h.db.Find(&layers).Error; err != nil {
+		return nil, err
+	}
+	sortAreas(areas)
+	sortBookshelves(bookshelves)
+	sortShelfLayers(layers)
+
+	type layerCount struct {
+		ShelfLayerID int64
+		BookCount    int64
+		VolumeCount  int64
+		InStockCount int64
+	}
+	var counts []layerCount
+	if err := h.db.Model(&db.Book{}).
+		Select("shelf_layer_id, COUNT(*) AS book_count, COALESCE(SUM(quantity), 0) AS volume_count, COALESCE(SUM(in_stock), 0) AS in_stock_count").
+		Where("shelf_layer_id IS NOT NULL").
+		Group("shelf_layer_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	countByLayer := make(map[int64]layerCount, len(counts))
+	for _, count := range counts {
+		countByLayer[count.ShelfLayerID] = count
+	}
+
+	areaCodes := make(map[int64]*string, len(areas))
 	for _, area := range areas {
-		var bookshelves []db.Bookshelf
-		h.db.Where("area_id = ?", area.ID).Find(&bookshelves)
-
-		bookshelfNodes := make([]BookshelfNode, 0, len(bookshelves))
-		for _, bookshelf := range bookshelves {
-			var shelfLayers []db.ShelfLayer
-			h.db.Where("bookshelf_id = ?", bookshelf.ID).Find(&shelfLayers)
-
-			shelfLayerNodes := make([]ShelfLayerNode, 0, len(shelfLayers))
-			for _, layer := range shelfLayers {
-				shelfLayerNodes = append(shelfLayerNodes, ShelfLayerNode{
-					ID:   layer.ID,
-					Name: layer.Name,
-				})
-			}
+		areaCodes[area.ID] = area.Code
+	}
+	shelfCodes := make(map[int64][2]*string, len(bookshelves))
+	for _, bookshelf := range bookshelves {
+		shelfCodes[bookshelf.ID] = [2]*string{areaCodes[bookshelf.AreaID], bookshelf.Code}
+	}
+
+	layersByShelf := make(map[int64][]ShelfLayerNode)
+	for _, layer := range layers {
+		count := countByLayer[layer.ID]
+		codes := shelfCodes[layer.BookshelfID]
+		layersByShelf[layer.BookshelfID] = append(layersByShelf[layer.BookshelfID], ShelfLayerNode{
+			ID:           layer.ID,
+			Name:         layer.Name,
+			Code:         layer.Code,
+			LocationCode: locationCode(codes[0], codes[1], layer.Code),
+			LabelCode:    layer.LabelCode,
+			Capacity:     layer.Capacity,
+			BookCount:    count.BookCount,
+			VolumeCount:  count.VolumeCount,
+			InStockCount: count.InStockCount,
+			OverCapacity: layer.Capacity != nil && count.VolumeCount > int64(*layer.Capacity),
+		})
+	}
 
-			bookshelfNodes = append(bookshelfNodes, BookshelfNode{
-				ID:          bookshelf.ID,
-				Name:        bookshelf.Name,
-				ShelfLayers: shelfLayerNodes,
-			})
+	shelvesByArea := make(map[int64][]BookshelfNode)
+	for _, bookshelf := range bookshelves {
+		node := BookshelfNode{
+			ID:          bookshelf.ID,
+			Name:        bookshelf.Name,
+			Code:        bookshelf.Code,
+			ShelfLayers: layersByShelf[bookshelf.ID],
 		}
+		if node.ShelfLayers == nil {
+			node.ShelfLayers = []ShelfLayerNode{}
+		}
+		for _, layer := range node.ShelfLayers {
+			node.BookCount += layer.BookCount
+			node.VolumeCount += layer.VolumeCount
+			node.InStockCount += layer.InStockCount
+			if layer.OverCapacity {
+				node.OverCapacityLayers++
+			}
+		}
+		shelvesByArea[bookshelf.AreaID] = append(shelvesByArea[bookshelf.AreaID], node)
+	}
 
-		result = append(result, AreaNode{
+	result := make([]AreaNode, 0, len(areas))
+	for _, area := range areas {
+		node := AreaNode{
 			ID:          area.ID,
 			Name:        area.Name,
-			Bookshelves: bookshelfNodes,
-		})
+			Code:        area.Code,
+			Bookshelves: shelvesByArea[area.ID],
+		}
+		if node.Bookshelves == nil {
+			node.Bookshelves = []BookshelfNode{}
+		}
+		for _, bookshelf := range node.Bookshelves {
+			node.BookCount += bookshelf.BookCount
+			node.VolumeCount += bookshelf.VolumeCount
+			node.InStockCount += bookshelf.InStockCount
+			node.OverCapacityLayers += bookshelf.OverCapacityLayers
+		}
+		result = append(result, node)
 	}
-
-	Success(c, result)
+	return result, nil
 }
-
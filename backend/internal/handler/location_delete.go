@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/db"
+)
+
+// 删除区域/书架/层的方式
+const (
+	DeleteRestrict = "restrict" // 默认：节点下还有书架、层或图书时拒绝删除
+	DeleteRelocate = "relocate" // 先把图书转移到 target_layer_id 指定的层，再删除节点及其下属节点
+	DeleteCascade  = "cascade"  // 删除节点及其下属节点，图书保留但清空位置
+)
+
+// locationSubtree 待删除的节点及其下属的书架和层
+type locationSubtree struct {
+	areaIDs      []int64
+	bookshelfIDs []int64
+	layerIDs     []int64
+}
+
+// AffectedBook 删除位置时受影响的图书
+type AffectedBook struct {
+	ID      int64  `json:"id"`
+	Barcode string `json:"barcode"`
+	Name    string `json:"name"`
+}
+
+// LocationDeleteResult 删除位置的结果汇总
+type LocationDeleteResult struct {
+	Mode           string         `json:"mode"`
+	DeletedAreas   int            `json:"deleted_areas"`
+	DeletedShelves int            `json:"deleted_bookshelves"`
+	DeletedLayers  int            `json:"deleted_shelf_layers"`
+	TargetLayerID  *int64         `json:"target_layer_id,omitempty"`
+	TargetLocation *string        `json:"target_location,omitempty"`
+	AffectedBooks  []AffectedBook `json:"affected_books"`
+	AffectedCount  int            `json:"affected_book_count"`
+}
+
+// deleteLocation 按查询参数 mode 删除位置节点，collect 在事务内查出待删除的节点（不存在时返回 gorm.ErrRecordNotFound）。
+// notFoundCode 为节点不存在时的错误码。
+func deleteLocation(c *app.RequestContext, database *gorm.DB, notFoundCode apperr.Code, collect func(tx *gorm.DB) (*locationSubtree, error)) {
+	mode := c.DefaultQuery("mode", DeleteRestrict)
+	var targetID int64
+	switch mode {
+	case DeleteRestrict, DeleteCascade:
+	case DeleteRelocate:
+		var err error
+		if targetID, err = strconv.ParseInt(c.Query("target_layer_id"), 10, 64); err != nil {
+			Fail(c, apperr.Invalid("target_layer_id", apperr.Required))
+			return
+		}
+	default:
+		Fail(c, apperr.Invalid("mode", apperr.InvalidValue))
+		return
+	}
+
+	result := LocationDeleteResult{Mode: mode, AffectedBooks: []AffectedBook{}}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		subtree, err := collect(tx)
+		if err != nil {
+			return err
+		}
+
+		var books []db.Book
+		if len(subtree.layerIDs) > 0 {
+			if err := tx.Select("id", "barcode", "name", "shelf_layer_id").
+				Where("shelf_layer_id IN ?", subtree.layerIDs).Order("id").Find(&books).Error; err != nil {
+				return err
+			}
+		}
+		for _, book := range books {
+			result.AffectedBooks = append(result.AffectedBooks, AffectedBook{ID: book.ID, Barcode: book.Barcode, Name: book.Name})
+		}
+		result.AffectedCount = len(books)
+
+		switch mode {
+		case DeleteRestrict:
+			// 子树中除要删除的节点本身外的书架和层
+			children := len(subtree.areaIDs) + len(subtree.bookshelfIDs) + len(subtree.layerIDs) - 1
+			if children > 0 || len(books) > 0 {
+				return apperr.New(apperr.LocationNotEmpty, "children", children, "books", len(books))
+			}
+		case DeleteRelocate:
+			for _, id := range subtree.layerIDs {
+				if id == targetID {
+					return apperr.New(apperr.TargetInSubtree)
+				}
+			}
+			var target db.ShelfLayer
+			if err := tx.Preload("Bookshelf.Area").First(&target, targetID).Error; err != nil {
+				return notFound(err, apperr.ShelfLayerNotFound)
+			}
+			location := layerLocation(target)
+			result.TargetLayerID, result.TargetLocation = &target.ID, &location
+
+			if err := relocateBooks(tx, books, target.ID); err != nil {
+				return err
+			}
+		case DeleteCascade:
+			if len(books) > 0 {
+				if err := tx.Model(&db.Book{}).Where("shelf_layer_id IN ?", subtree.layerIDs).
+					Update("shelf_layer_id", nil).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(subtree.layerIDs) > 0 {
+			if err := tx.Where("id IN ?", subtree.layerIDs).Delete(&db.ShelfLayer{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(subtree.bookshelfIDs) > 0 {
+			if err := tx.Where("id IN ?", subtree.bookshelfIDs).Delete(&db.Bookshelf{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(subtree.areaIDs) > 0 {
+			if err := tx.Where("id IN ?", subtree.areaIDs).Delete(&db.Area{}).Error; err != nil {
+				return err
+			}
+		}
+		result.DeletedAreas = len(subtree.areaIDs)
+		result.DeletedShelves = len(subtree.bookshelfIDs)
+		result.DeletedLayers = len(subtree.layerIDs)
+		return nil
+	})
+
+	if err != nil {
+		Fail(c, notFound(err, notFoundCode))
+		return
+	}
+	Success(c, result)
+}
+
+// collectLayers 查出 subtree 中各书架下的层
+func collectLayers(tx *gorm.DB, subtree *locationSubtree) error {
+	if len(subtree.bookshelfIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&db.ShelfLayer{}).Where("bookshelf_id IN ?", subtree.bookshelfIDs).Pluck("id", &subtree.layerIDs).Error
+}
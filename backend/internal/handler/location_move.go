@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/db"
+)
+
+// MoveBookshelfRequest 移动书架请求，name 不为空时同时改名（用于避开目标区域的同名书架）
+type MoveBookshelfRequest struct {
+	AreaID int64  `json:"area_id" binding:"required"`
+	Name   string `json:"name"`
+}
+
+// MoveShelfLayerRequest 移动层请求，name 不为空时同时改名（用于避开目标书架的同名层）
+type MoveShelfLayerRequest struct {
+	BookshelfID int64  `json:"bookshelf_id" binding:"required"`
+	Name        string `json:"name"`
+}
+
+// MergeShelfLayerRequest 合并层请求
+type MergeShelfLayerRequest struct {
+	TargetLayerID int64 `json:"target_layer_id" binding:"required"`
+}
+
+// LocationConflict 目标位置下已存在的同名节点
+type LocationConflict struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// checkNameConflict 检查同一 parentColumn 下是否已有同名节点（排除 excludeID），有时返回 apperr.LocationConflict，附带冲突节点
+func checkNameConflict(tx *gorm.DB, model interface{}, parentColumn string, parentID int64, name string, excludeID int64) error {
+	var conflict LocationConflict
+	err := tx.Model(model).Select("id", "name").
+		Where(parentColumn+" = ? AND name = ? AND id <> ?", parentID, name, excludeID).
+		Take(&conflict).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return apperr.New(apperr.LocationConflict, "name", conflict.Name).
+		WithData(map[string]interface{}{"conflict": conflict})
+}
+
+// Move 将书架移动到其他区域，图书随书架移动，位置码不变。
+// 书架名称和简码在同一区域内唯一，目标区域已有同名书架时返回冲突详情。
+func (h *BookshelfHandler) Move(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+	var req MoveBookshelfRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+
+	var bookshelf db.Bookshelf
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&bookshelf, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Select("id").First(&db.Area{}, req.AreaID).Error; err != nil {
+			return notFound(err, apperr.AreaNotFound)
+		}
+		name := bookshelf.Name
+		if req.Name != "" {
+			name = req.Name
+		}
+		if err := checkNameConflict(tx, &db.Bookshelf{}, "area_id", req.AreaID, name, id); err != nil {
+			return err
+		}
+		if taken, err := locationCodeTaken(tx, &db.Bookshelf{}, "area_id", req.AreaID, bookshelf.Code, id); err != nil {
+			return err
+		} else if taken {
+			return apperr.New(apperr.DuplicateCode, "code", *bookshelf.Code)
+		}
+		updates := map[string]interface{}{"area_id": req.AreaID, "name": name}
+		if req.AreaID != bookshelf.AreaID {
+			sortOrder, err := nextSortOrder(tx, &db.Bookshelf{}, "area_id", req.AreaID)
+			if err != nil {
+				return err
+			}
+			updates["sort_order"] = sortOrder
+		}
+		return tx.Model(&bookshelf).Updates(updates).Error
+	})
+	if err != nil {
+		Fail(c, notFound(err, apperr.BookshelfNotFound))
+		return
+	}
+
+	h.db.Preload("Area").First(&bookshelf, id)
+	Success(c, bookshelf)
+}
+
+// Move 将层移动到其他书架，图书随层移动，位置码不变。
+// 层名称和简码在同一书架下唯一，目标书架已有同名层时返回冲突详情。
+func (h *ShelfLayerHandler) Move(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+	var req MoveShelfLayerRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+
+	var layer db.ShelfLayer
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&layer, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Select("id").First(&db.Bookshelf{}, req.BookshelfID).Error; err != nil {
+			return notFound(err, apperr.BookshelfNotFound)
+		}
+		name := layer.Name
+		if req.Name != "" {
+			name = req.Name
+		}
+		if err := checkNameConflict(tx, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID, name, id); err != nil {
+			return err
+		}
+		if taken, err := locationCodeTaken(tx, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID, layer.Code, id); err != nil {
+			return err
+		} else if taken {
+			return apperr.New(apperr.DuplicateCode, "code", *layer.Code)
+		}
+		updates := map[string]interface{}{"bookshelf_id": req.BookshelfID, "name": name}
+		if req.BookshelfID != layer.BookshelfID {
+			sortOrder, err := nextSortOrder(tx, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID)
+			if err != nil {
+				return err
+			}
+			updates["sort_order"] = sortOrder
+		}
+		return tx.Model(&layer).Updates(updates).Error
+	})
+	if err != nil {
+		Fail(c, notFound(err, apperr.ShelfLayerNotFound))
+		return
+	}
+
+	h.db.Preload("Bookshelf.Area").First(&layer, id)
+	Success(c, layer)
+}
+
+// Merge 将本层合并到目标层：本层的图书全部转移到目标层并写入上架记录，然后删除本层。
+// 上架记录中指向本层的原位置/新位置由外键规则置空（迁移 0004）。
+func (h *ShelfLayerHandler) Merge(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+	var req MergeShelfLayerRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+	if req.TargetLayerID == id {
+		Fail(c, apperr.Invalid("target_layer_id", apperr.SameLayer))
+		return
+	}
+
+	var source, target db.ShelfLayer
+	var books []db.Book
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Bookshelf.Area").First(&source, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Bookshelf.Area").First(&target, req.TargetLayerID).Error; err != nil {
+			return notFound(err, apperr.ShelfLayerNotFound)
+		}
+		if err := tx.Select("id", "barcode", "name", "shelf_layer_id").
+			Where("shelf_layer_id = ?", id).Order("id").Find(&books).Error; err != nil {
+			return err
+		}
+		if err := relocateBooks(tx, books, target.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&source).Error
+	})
+	if err != nil {
+		Fail(c, notFound(err, apperr.ShelfLayerNotFound))
+		return
+	}
+
+	affected := make([]AffectedBook, len(books))
+	for i, book := range books {
+		affected[i] = AffectedBook{ID: book.ID, Barcode: book.Barcode, Name: book.Name}
+	}
+	Success(c, map[string]interface{}{
+		"source_layer_id":     source.ID,
+		"source_location":     layerLocation(source),
+		"target_layer_id":     target.ID,
+		"target_location":     layerLocation(target),
+		"affected_books":      affected,
+		"affected_book_count": len(affected),
+	})
+}
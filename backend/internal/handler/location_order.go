@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/db"
+	"booksystem/internal/natsort"
+)
+
+// locationCodePattern 区域/书架/层简码：字母和数字，保存为大写
+var locationCodePattern = regexp.MustCompile(`^[A-Z0-9]{1,16}$`)
+
+// ReorderRequest 调整排序请求，ids 为同一上级下全部节点的新顺序
+type ReorderRequest struct {
+	IDs []int64 `json:"ids" binding:"required"`
+}
+
+// compareLocation 位置节点的排序规则：先按排序值，再按名称自然排序，最后按ID
+func compareLocation(sortA int, nameA string, idA int64, sortB int, nameB string, idB int64) int {
+	if c := cmp.Compare(sortA, sortB); c != 0 {
+		return c
+	}
+	if c := natsort.Compare(nameA, nameB); c != 0 {
+		return c
+	}
+	return cmp.Compare(idA, idB)
+}
+
+func sortAreas(areas []db.Area) {
+	slices.SortStableFunc(areas, func(a, b db.Area) int {
+		return compareLocation(a.SortOrder, a.Name, a.ID, b.SortOrder, b.Name, b.ID)
+	})
+}
+
+func sortBookshelves(bookshelves []db.Bookshelf) {
+	slices.SortStableFunc(bookshelves, func(a, b db.Bookshelf) int {
+		return compareLocation(a.SortOrder, a.Name, a.ID, b.SortOrder, b.Name, b.ID)
+	})
+}
+
+func sortShelfLayers(layers []db.ShelfLayer) {
+	slices.SortStableFunc(layers, func(a, b db.ShelfLayer) int {
+		return compareLocation(a.SortOrder, a.Name, a.ID, b.SortOrder, b.Name, b.ID)
+	})
+}
+
+// sortShelfLayersByLocation 按区域、书架、层的顺序排列，层需预加载 Bookshelf.Area
+func sortShelfLayersByLocation(layers []db.ShelfLayer) {
+	slices.SortStableFunc(layers, func(a, b db.ShelfLayer) int {
+		areaA, areaB := a.Bookshelf.Area, b.Bookshelf.Area
+		if c := compareLocation(areaA.SortOrder, areaA.Name, areaA.ID, areaB.SortOrder, areaB.Name, areaB.ID); c != 0 {
+			return c
+		}
+		shelfA, shelfB := a.Bookshelf, b.Bookshelf
+		if c := compareLocation(shelfA.SortOrder, shelfA.Name, shelfA.ID, shelfB.SortOrder, shelfB.Name, shelfB.ID); c != 0 {
+			return c
+		}
+		return compareLocation(a.SortOrder, a.Name, a.ID, b.SortOrder, b.Name, b.ID)
+	})
+}
+
+// locationCode 组成位置编号，如 A-03-2；任一级未设置简码时返回空串
+func locationCode(areaCode, bookshelfCode, layerCode *string) string {
+	if areaCode == nil || bookshelfCode == nil || layerCode == nil {
+		return ""
+	}
+	return *areaCode + "-" + *bookshelfCode + "-" + *layerCode
+}
+
+// layerLocationCode 层的位置编号，层需预加载 Bookshelf.Area
+func layerLocationCode(layer db.ShelfLayer) string {
+	return locationCode(layer.Bookshelf.Area.Code, layer.Bookshelf.Code, layer.Code)
+}
+
+// normalizeLocationCode 规范化简码：去除首尾空格并转为大写，空串表示不设置
+func normalizeLocationCode(code string) (*string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, nil
+	}
+	if !locationCodePattern.MatchString(code) {
+		return nil, apperr.Invalid("code", apperr.InvalidLocationCode)
+	}
+	return &code, nil
+}
+
+// locationCodeTaken 检查同一上级下是否已有相同简码的节点。parentColumn 为空时在全部节点中检查（区域）。
+func locationCodeTaken(tx *gorm.DB, model interface{}, parentColumn string, parentID int64, code *string, excludeID int64) (bool, error) {
+	if code == nil {
+		return false, nil
+	}
+	query := tx.Model(model).Where("code = ? AND id <> ?", *code, excludeID)
+	if parentColumn != "" {
+		query = query.Where(parentColumn+" = ?", parentID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// nextSortOrder 新节点的排序值：上级下的节点手动排过序时排在最后，否则为 0（按名称自然排序）
+func nextSortOrder(tx *gorm.DB, model interface{}, parentColumn string, parentID int64) (int, error) {
+	query := tx.Model(model)
+	if parentColumn != "" {
+		query = query.Where(parentColumn+" = ?", parentID)
+	}
+	var last int
+	if err := query.Select("COALESCE(MAX(sort_order), 0)").Scan(&last).Error; err != nil {
+		return 0, err
+	}
+	if last == 0 {
+		return 0, nil
+	}
+	return last + 1, nil
+}
+
+// reorderLocations 按 ids 的顺序写入排序值（从 1 开始）。
+// ids 必须恰好是同一上级下的全部节点；parentColumn 为空时为全部区域。notFoundCode 为节点不存在时的错误码。
+func reorderLocations(c *app.RequestContext, database *gorm.DB, model interface{}, parentColumn string, notFoundCode apperr.Code) {
+	var req ReorderRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(model)
+		if parentColumn != "" {
+			var parentIDs []int64
+			if err := tx.Model(model).Where("id = ?", req.IDs[0]).Pluck(parentColumn, &parentIDs).Error; err != nil {
+				return err
+			}
+			if len(parentIDs) == 0 {
+				return apperr.New(notFoundCode)
+			}
+			query = query.Where(parentColumn+" = ?", parentIDs[0])
+		}
+		var siblings []int64
+		if err := query.Pluck("id", &siblings).Error; err != nil {
+			return err
+		}
+
+		seen := make(map[int64]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if seen[id] {
+				return apperr.Invalid("ids", apperr.DuplicateID, "id", id)
+			}
+			seen[id] = true
+		}
+		for _, id := range siblings {
+			if !seen[id] {
+				return apperr.Invalid("ids", apperr.MissingID, "id", id)
+			}
+		}
+		if len(req.IDs) != len(siblings) {
+			return apperr.Invalid("ids", apperr.ForeignID)
+		}
+
+		for i, id := range req.IDs {
+			if err := tx.Model(model).Where("id = ?", id).Update("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+// Reorder 调整区域顺序
+func (h *AreaHandler) Reorder(ctx context.Context, c *app.RequestContext) {
+	reorderLocations(c, h.db, &db.Area{}, "", apperr.AreaNotFound)
+}
+
+// Reorder 调整同一区域下书架的顺序
+func (h *BookshelfHandler) Reorder(ctx context.Context, c *app.RequestContext) {
+	reorderLocations(c, h.db, &db.Bookshelf{}, "area_id", apperr.BookshelfNotFound)
+}
+
+// Reorder 调整同一书架下层的顺序
+func (h *ShelfLayerHandler) Reorder(ctx context.Context, c *app.RequestContext) {
+	reorderLocations(c, h.db, &db.ShelfLayer{}, "bookshelf_id", apperr.ShelfLayerNotFound)
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"booksystem/internal/db"
+)
+
+// createLayers 创建一个区域和书架，书架下按 names 创建层
+func (s *testServer) createLayers(area string, names ...string) (db.Area, []db.ShelfLayer) {
+	s.t.Helper()
+	a := db.Area{Name: area}
+	if err := s.db.Create(&a).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	shelf := db.Bookshelf{AreaID: a.ID, Name: "1"}
+	if err := s.db.Create(&shelf).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	layers := make([]db.ShelfLayer, len(names))
+	for i, name := range names {
+		layers[i] = db.ShelfLayer{BookshelfID: shelf.ID, Name: name}
+		if err := s.db.Create(&layers[i]).Error; err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	return a, layers
+}
+
+// shelve 扫码把图书上架到层，写入上架记录
+func (s *testServer) shelve(layer db.ShelfLayer, barcodes ...string) {
+	s.t.Helper()
+	s.ok("POST", "/api/v1/shelving", map[string]interface{}{"codes": append([]string{*layer.LabelCode}, barcodes...)})
+}
+
+// layerOf 返回图书所在的层
+func (s *testServer) layerOf(id int64) *int64 {
+	s.t.Helper()
+	var book db.Book
+	if err := s.db.First(&book, id).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	return book.ShelfLayerID
+}
+
+// shelvingLogs 图书的上架记录，按时间先后排列
+func (s *testServer) shelvingLogs(bookID int64) []db.ShelvingLog {
+	s.t.Helper()
+	var logs []db.ShelvingLog
+	if err := s.db.Where("book_id = ?", bookID).Order("id").Find(&logs).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	return logs
+}
+
+func TestMergeShelfLayer(t *testing.T) {
+	s := newTestServer(t, nil)
+	_, layers := s.createLayers("A", "1", "2")
+	source, target := layers[0], layers[1]
+	id, code := s.createBook(map[string]interface{}{"name": "x", "quantity": 1})
+	s.shelve(source, code)
+
+	var result struct {
+		AffectedBookCount int `json:"affected_book_count"`
+	}
+	s.ok("POST", fmt.Sprintf("/api/v1/shelf-layers/%d/merge", source.ID), map[string]interface{}{"target_layer_id": target.ID}).decode(t, &result)
+	if result.AffectedBookCount != 1 {
+		t.Errorf("affected_book_count = %d, want 1", result.AffectedBookCount)
+	}
+	if got := s.layerOf(id); got == nil || *got != target.ID {
+		t.Errorf("book layer after merge = %v, want %d", got, target.ID)
+	}
+	var left int64
+	s.db.Model(&db.ShelfLayer{}).Where("id = ?", source.ID).Count(&left)
+	if left != 0 {
+		t.Error("source layer left after merge")
+	}
+
+	// 指向已删除层的位置置空，记录保留
+	logs := s.shelvingLogs(id)
+	if len(logs) != 2 {
+		t.Fatalf("%d shelving logs, want 2", len(logs))
+	}
+	if logs[0].ToLayerID != nil || logs[1].FromLayerID != nil || logs[1].ToLayerID == nil || *logs[1].ToLayerID != target.ID {
+		t.Errorf("logs after merge = %+v, want references to the source layer cleared", logs)
+	}
+
+	// 有上架记录的图书可以删除，记录随之删除
+	s.ok("DELETE", fmt.Sprintf("/api/v1/books/%d", id), nil)
+	if logs := s.shelvingLogs(id); len(logs) != 0 {
+		t.Errorf("%d shelving logs left after deleting the book", len(logs))
+	}
+}
+
+func TestDeleteLocationWithBooks(t *testing.T) {
+	s := newTestServer(t, nil)
+	_, targets := s.createLayers("Target", "1")
+	target := targets[0]
+
+	tests := []struct {
+		mode string
+		want *int64
+	}{
+		{"relocate", &target.ID},
+		{"cascade", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			s := s.sub(t)
+			area, layers := s.createLayers(tt.mode, "1", "2")
+			first, firstCode := s.createBook(map[string]interface{}{"name": "x", "quantity": 1})
+			second, secondCode := s.createBook(map[string]interface{}{"name": "y", "quantity": 1})
+			// 第一本书经过两层，上架记录引用两层
+			s.shelve(layers[0], firstCode)
+			s.shelve(layers[1], firstCode, secondCode)
+
+			path := fmt.Sprintf("/api/v1/areas/%d?mode=%s", area.ID, tt.mode)
+			if tt.want != nil {
+				path += fmt.Sprintf("&target_layer_id=%d", *tt.want)
+			}
+			var result LocationDeleteResult
+			s.ok("DELETE", path, nil).decode(t, &result)
+			if result.DeletedLayers != 2 || result.AffectedCount != 2 {
+				t.Errorf("deleted %d layers, %d books affected; want 2 and 2", result.DeletedLayers, result.AffectedCount)
+			}
+			for _, id := range []int64{first, second} {
+				got := s.layerOf(id)
+				if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+					t.Errorf("book %d layer = %v, want %v", id, got, tt.want)
+				}
+			}
+			if logs := s.shelvingLogs(first); len(logs) < 2 {
+				t.Errorf("%d shelving logs left for book %d, want the history kept", len(logs), first)
+			}
+		})
+	}
+}
+
+func TestLocationTreeCacheAfterCommit(t *testing.T) {
+	database := openTestDB(t)
+	h := NewLocationHandler(database)
+	areaNames := func() []string {
+		t.Helper()
+		cache, err := h.tree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp struct{ Data []AreaNode }
+		if err := json.Unmarshal(cache.body, &resp); err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(resp.Data))
+		for i, area := range resp.Data {
+			names[i] = area.Name
+		}
+		return names
+	}
+
+	// 事务提交前构建的树不含新区域，提交后不能继续使用
+	tx := database.Begin()
+	if err := tx.Create(&db.Area{Name: "A"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := areaNames(); len(got) != 0 {
+		t.Fatalf("tree before commit = %q, want empty", got)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := areaNames(); len(got) != 1 || got[0] != "A" {
+		t.Errorf("tree after commit = %q, want [A]", got)
+	}
+
+	// 回滚的变更不使缓存失效
+	version := h.version.Load()
+	tx = database.Begin()
+	if err := tx.Create(&db.Area{Name: "B"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if h.version.Load() != version {
+		t.Error("rolled back write invalidated the tree")
+	}
+}
@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/barcode"
+	"booksystem/internal/db"
+	"booksystem/internal/i18n"
+	"booksystem/internal/marc"
+)
+
+const (
+	// MARC 格式
+	marcFormatISO2709 = "iso2709"
+	marcFormatXML     = "marcxml"
+)
+
+type MarcHandler struct {
+	db       *gorm.DB
+	barcodes *barcode.Validator
+}
+
+func NewMarcHandler(db *gorm.DB, barcodes *barcode.Validator) *MarcHandler {
+	return &MarcHandler{db: db, barcodes: barcodes}
+}
+
+// MarcImportError 导入失败的记录
+type MarcImportError struct {
+	Index   int         `json:"index"` // 记录序号，从1开始
+	Barcode string      `json:"barcode,omitempty"`
+	Code    apperr.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+// MarcImportResult 导入结果汇总
+type MarcImportResult struct {
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Errors  []MarcImportError `json:"errors"`
+}
+
+// Import 导入 ISO 2709 或 MARCXML 文件，按 ISBN/馆藏条码匹配已有图书
+func (h *MarcHandler) Import(ctx context.Context, c *app.RequestContext) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		Fail(c, apperr.Invalid("file", apperr.Required))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	defer file.Close()
+
+	quantity := 1
+	if q := c.PostForm("quantity"); q != "" {
+		if quantity, err = strconv.Atoi(q); err != nil || quantity < 0 {
+			Fail(c, apperr.Invalid("quantity", apperr.InvalidValue))
+			return
+		}
+	}
+
+	result, err := h.ImportFile(file, c.PostForm("format"), quantity, requestLang(c))
+	if err != nil {
+		Fail(c, apperr.Wrap(apperr.InvalidFile, err, "reason", err.Error()))
+		return
+	}
+	Success(c, result)
+}
+
+// ImportFile 导入 MARC 文件，format 为空时按内容识别，新建的图书数量为 quantity。
+// 只有文件无法解析时返回错误，单条记录的错误以 lang 语言记在结果中。
+func (h *MarcHandler) ImportFile(r io.Reader, format string, quantity int, lang i18n.Lang) (MarcImportResult, error) {
+	records, err := readMarc(r, format)
+	if err != nil {
+		return MarcImportResult{}, err
+	}
+
+	result := MarcImportResult{Total: len(records), Errors: []MarcImportError{}}
+	for i, rec := range records {
+		created, code, err := h.importRecord(rec, quantity)
+		switch {
+		case err != nil:
+			result.Skipped++
+			e := apperr.As(err)
+			if e == nil {
+				slog.Error("Failed to import MARC record", "index", i+1, "barcode", code, "error", err)
+				e = apperr.Internal(err)
+			}
+			result.Errors = append(result.Errors, MarcImportError{Index: i + 1, Barcode: code, Code: e.Code, Message: e.Message(lang)})
+		case created:
+			result.Created++
+		default:
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+// importRecord 导入单条记录，返回是否新建图书以及匹配使用的一维码
+func (h *MarcHandler) importRecord(rec marc.Record, quantity int) (bool, string, error) {
+	bib := marc.ToBib(rec)
+	code := bib.Barcode
+	if code == "" {
+		code = bib.ISBN
+	}
+	if code == "" {
+		return false, "", apperr.New(apperr.MarcMissingBarcode)
+	}
+	parsed, err := h.barcodes.Parse(code)
+	if err != nil {
+		return false, code, barcodeError("barcode", err)
+	}
+	if bib.Title == "" {
+		return false, parsed.Normalized, apperr.New(apperr.MarcMissingTitle)
+	}
+
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return false, parsed.Normalized, err
+	}
+	rawStr := string(raw)
+
+	created := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		book, err := findBookByBarcode(tx, parsed.Normalized)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == gorm.ErrRecordNotFound {
+			created = true
+			book = db.Book{Barcode: parsed.Normalized, Quantity: quantity, InStock: quantity}
+		}
+
+		book.Name = bib.Title
+		book.MarcRaw = &rawStr
+		if bib.Price != nil {
+			book.Price = bib.Price
+		}
+		if bib.PublishYear != nil {
+			book.PublishYear = bib.PublishYear
+		}
+		setIfNotEmpty(&book.Edition, bib.Edition)
+		setIfNotEmpty(&book.Language, bib.Language)
+		setIfNotEmpty(&book.ClassNumber, bib.ClassNumber)
+		if bib.Publisher != "" {
+			publisherID, err := findOrCreatePublisher(tx, bib.Publisher)
+			if err != nil {
+				return err
+			}
+			book.PublisherID = &publisherID
+		}
+
+		// 只保存图书本身的字段，关联单独替换
+		if err := tx.Omit("Authors", "Tags", "Categories", "Publisher", "ShelfLayer").Save(&book).Error; err != nil {
+			return err
+		}
+
+		authorIDs, err := findOrCreateAuthors(tx, bib.Authors)
+		if err != nil {
+			return err
+		}
+		tagIDs, err := findOrCreateTags(tx, bib.Tags)
+		if err != nil {
+			return err
+		}
+		return replaceBookAssociations(tx, &book, &authorIDs, &tagIDs, nil)
+	})
+	return created, parsed.Normalized, err
+}
+
+// Export 将选中的图书导出为 MARC 记录（ids 为逗号分隔的图书ID，不传时使用图书列表的筛选条件）
+func (h *MarcHandler) Export(ctx context.Context, c *app.RequestContext) {
+	format := c.DefaultQuery("format", marcFormatISO2709)
+	if format != marcFormatISO2709 && format != marcFormatXML {
+		Fail(c, apperr.Invalid("format", apperr.InvalidValue))
+		return
+	}
+
+	query := preloadBookMetadata(h.db.Model(&db.Book{}))
+	if ids := c.Query("ids"); ids != "" {
+		var idList []int64
+		for _, s := range strings.Split(ids, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				Fail(c, invalidID("ids"))
+				return
+			}
+			idList = append(idList, id)
+		}
+		query = query.Where("id IN ?", idList)
+	} else {
+		query = applyBookFilters(query, c)
+	}
+
+	var books []db.Book
+	if err := query.Find(&books).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeMarc(&buf, books, format); errors.Is(err, marc.ErrTooLong) {
+		Fail(c, apperr.Wrap(apperr.MarcRecordTooLong, err, "reason", err.Error()))
+		return
+	} else if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	contentType, ext := "application/marc", "mrc"
+	if format == marcFormatXML {
+		contentType, ext = "application/marcxml+xml", "xml"
+	}
+	filename := fmt.Sprintf("books-%s.%s", time.Now().Format("20060102150405"), ext)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(200, contentType, buf.Bytes())
+}
+
+// ExportMarc 将全部图书导出为 MARC 记录，format 为 iso2709 或 marcxml
+func ExportMarc(database *gorm.DB, out io.Writer, format string) error {
+	if format != marcFormatISO2709 && format != marcFormatXML {
+		return fmt.Errorf("unsupported MARC format: %s", format)
+	}
+	var books []db.Book
+	if err := preloadBookMetadata(database.Model(&db.Book{})).Find(&books).Error; err != nil {
+		return err
+	}
+	return writeMarc(out, books, format)
+}
+
+// writeMarc 将图书转换为 MARC 记录并按 format 写出
+func writeMarc(w io.Writer, books []db.Book, format string) error {
+	records := make([]marc.Record, 0, len(books))
+	for _, book := range books {
+		rec, err := bookToMarc(book)
+		if err != nil {
+			return err
+		}
+		records = append(records, rec)
+	}
+	if format == marcFormatXML {
+		return marc.WriteXML(w, records)
+	}
+	// 逐条写出，出错时能指出是哪本书
+	for i, rec := range records {
+		if err := marc.WriteISO2709(w, []marc.Record{rec}); err != nil {
+			return fmt.Errorf("book %d: %w", books[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// readMarc 根据格式参数或文件内容识别 MARCXML / ISO 2709 并解析
+func readMarc(r io.Reader, format string) ([]marc.Record, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		head, _ := br.Peek(64)
+		format = marcFormatISO2709
+		if marc.IsXML(head) {
+			format = marcFormatXML
+		}
+	}
+	switch format {
+	case marcFormatXML:
+		return marc.ReadXML(br)
+	case marcFormatISO2709:
+		return marc.ReadISO2709(br)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// bookToMarc 以导入时保存的原始记录为基础生成 MARC 记录，保留未映射字段。
+// 保存的原始记录无法解析时返回错误，而不是只导出映射字段。
+func bookToMarc(book db.Book) (marc.Record, error) {
+	var rec marc.Record
+	if book.MarcRaw != nil {
+		if err := json.Unmarshal([]byte(*book.MarcRaw), &rec); err != nil {
+			return rec, fmt.Errorf("book %d: stored MARC record: %w", book.ID, err)
+		}
+	}
+	if _, ok := rec.First("001"); !ok {
+		rec.Fields = append(rec.Fields, marc.Field{Tag: "001", Value: strconv.FormatInt(book.ID, 10)})
+	}
+
+	bib := marc.Bib{
+		Title:       book.Name,
+		Price:       book.Price,
+		PublishYear: book.PublishYear,
+		Edition:     stringValue(book.Edition),
+		Language:    stringValue(book.Language),
+		ClassNumber: stringValue(book.ClassNumber),
+		Publisher:   stringValue(publisherName(book)),
+		Barcode:     book.Barcode,
+	}
+	if _, ok := barcode.ISBN13To10(book.Barcode); ok || strings.HasPrefix(book.Barcode, "979") {
+		bib.ISBN = book.Barcode
+	}
+	for _, a := range book.Authors {
+		bib.Authors = append(bib.Authors, a.Name)
+	}
+	for _, t := range book.Tags {
+		bib.Tags = append(bib.Tags, t.Name)
+	}
+	return marc.Apply(rec, bib), nil
+}
+
+func setIfNotEmpty(dst **string, value string) {
+	if value != "" {
+		*dst = &value
+	}
+}
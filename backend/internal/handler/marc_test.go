@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"testing"
+
+	"booksystem/internal/db"
+)
+
+func TestBookToMarc(t *testing.T) {
+	raw := `{"leader":"00000nam a2200000 a 4500","fields":[{"tag":"001","value":"ISBN-7"},{"tag":"500","ind1":" ","ind2":" ","subfields":[{"code":"a","value":"note"}]}]}`
+	rec, err := bookToMarc(db.Book{ID: 7, Name: "Go", Barcode: "9780134190440", MarcRaw: &raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 保留原始记录中未映射的字段和控制号
+	if f, ok := rec.First("500"); !ok || f.Sub("a") != "note" {
+		t.Errorf("500 = %+v, want the stored note kept", f)
+	}
+	if f, _ := rec.First("001"); f.Value != "ISBN-7" {
+		t.Errorf("001 = %q, want the stored control number", f.Value)
+	}
+	if f, _ := rec.First("245"); f.Sub("a") != "Go" {
+		t.Errorf("245$a = %q, want Go", f.Sub("a"))
+	}
+
+	broken := `{"fields":`
+	if _, err := bookToMarc(db.Book{ID: 8, Name: "x", MarcRaw: &broken}); err == nil {
+		t.Error("bookToMarc with a corrupt stored record succeeded")
+	}
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/db"
+)
+
+// namedModel 只有名称的字典表：作者、出版社、标签、分类
+type namedModel interface {
+	db.Author | db.Publisher | db.Tag | db.Category
+}
+
+// NamedHandler 字典表的增删改查，各表只在模型、不存在时的错误码和删除时解除图书关联的方式上不同
+type NamedHandler[T namedModel] struct {
+	db       *gorm.DB
+	notFound apperr.Code
+	build    func(name string) T
+	unlink   func(tx *gorm.DB, id int64) error // 删除前解除与图书的关联
+}
+
+func NewAuthorHandler(conn *gorm.DB) *NamedHandler[db.Author] {
+	return &NamedHandler[db.Author]{
+		db:       conn,
+		notFound: apperr.AuthorNotFound,
+		build:    func(name string) db.Author { return db.Author{Name: name} },
+		unlink: func(tx *gorm.DB, id int64) error {
+			return tx.Exec("DELETE FROM book_authors WHERE author_id = ?", id).Error
+		},
+	}
+}
+
+func NewPublisherHandler(conn *gorm.DB) *NamedHandler[db.Publisher] {
+	return &NamedHandler[db.Publisher]{
+		db:       conn,
+		notFound: apperr.PublisherNotFound,
+		build:    func(name string) db.Publisher { return db.Publisher{Name: name} },
+		unlink: func(tx *gorm.DB, id int64) error {
+			return tx.Model(&db.Book{}).Where("publisher_id = ?", id).Update("publisher_id", nil).Error
+		},
+	}
+}
+
+func NewTagHandler(conn *gorm.DB) *NamedHandler[db.Tag] {
+	return &NamedHandler[db.Tag]{
+		db:       conn,
+		notFound: apperr.TagNotFound,
+		build:    func(name string) db.Tag { return db.Tag{Name: name} },
+		unlink: func(tx *gorm.DB, id int64) error {
+			return tx.Exec("DELETE FROM book_tags WHERE tag_id = ?", id).Error
+		},
+	}
+}
+
+func NewCategoryHandler(conn *gorm.DB) *NamedHandler[db.Category] {
+	return &NamedHandler[db.Category]{
+		db:       conn,
+		notFound: apperr.CategoryNotFound,
+		build:    func(name string) db.Category { return db.Category{Name: name} },
+		unlink: func(tx *gorm.DB, id int64) error {
+			return tx.Exec("DELETE FROM book_categories WHERE category_id = ?", id).Error
+		},
+	}
+}
+
+// NameRequest 创建或更新字典项请求
+type NameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// Create 创建字典项
+func (h *NamedHandler[T]) Create(ctx context.Context, c *app.RequestContext) {
+	var req NameRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+
+	item := h.build(req.Name)
+	if err := h.db.Create(&item).Error; err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
+		return
+	}
+
+	Success(c, item)
+}
+
+// List 查询字典项列表，可按名称模糊查询
+func (h *NamedHandler[T]) List(ctx context.Context, c *app.RequestContext) {
+	var items []T
+	query := h.db.Model(new(T))
+
+	if name := c.Query("name"); name != "" {
+		query = query.Where(likeLower("name"), "%"+name+"%")
+	}
+
+	if err := query.Order("name").Find(&items).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+	Success(c, items)
+}
+
+// Update 修改字典项名称
+func (h *NamedHandler[T]) Update(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+
+	var req NameRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+
+	var item T
+	if err := h.db.First(&item, id).Error; err != nil {
+		Fail(c, notFound(err, h.notFound))
+		return
+	}
+
+	if err := h.db.Model(&item).Update("name", req.Name).Error; err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
+		return
+	}
+
+	h.db.First(&item, id)
+	Success(c, item)
+}
+
+// Delete 删除字典项（同时解除与图书的关联）
+func (h *NamedHandler[T]) Delete(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		Fail(c, invalidID("id"))
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := h.unlink(tx, id); err != nil {
+			return err
+		}
+		return tx.Delete(new(T), id).Error
+	})
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	Success(c, nil)
+}
+
+// findNamed 按ID查询字典项，有不存在的ID时返回 code 错误，附带不存在的ID
+func findNamed[T namedModel](tx *gorm.DB, ids []int64, code apperr.Code) ([]T, error) {
+	var items []T
+	if len(ids) == 0 {
+		return items, nil
+	}
+	if err := tx.Find(&items, ids).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[int64]bool, len(items))
+	for i := range items {
+		found[namedID(&items[i])] = true
+	}
+	var missing []int64
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+			found[id] = true // 重复的ID只报告一次
+		}
+	}
+	if len(missing) > 0 {
+		return nil, apperr.New(code).WithData(map[string]interface{}{"ids": missing})
+	}
+	return items, nil
+}
+
+// namedID 字典项的ID
+func namedID[T namedModel](item *T) int64 {
+	switch v := interface{}(item).(type) {
+	case *db.Author:
+		return v.ID
+	case *db.Publisher:
+		return v.ID
+	case *db.Tag:
+		return v.ID
+	case *db.Category:
+		return v.ID
+	}
+	return 0
+}
@@ -1,15 +1,35 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/i18n"
+	"booksystem/internal/logging"
 )
 
-// Response 统一响应结构
+// Response 统一响应结构。code 成功时为 200，失败时与 HTTP 状态码相同
 type Response struct {
-	Code    int         `json:"code"`
+	Code int `json:"code"`
+	// ErrorCode 错误码，见 apperr 包中的错误码目录
+	ErrorCode apperr.Code `json:"error_code,omitempty"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	// Details 字段校验明细
+	Details []FieldDetail `json:"details,omitempty"`
+	// RequestID 错误响应带上请求 ID，便于按 ID 查找服务端日志
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldDetail 单个字段的校验错误
+type FieldDetail struct {
+	Field   string      `json:"field"`
+	Code    apperr.Code `json:"code"`
 	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
 }
 
 // Success 成功响应
@@ -21,11 +41,78 @@ func Success(c *app.RequestContext, data interface{}) {
 	})
 }
 
-// Error 错误响应
-func Error(c *app.RequestContext, code int, message string) {
-	c.JSON(consts.StatusOK, Response{
-		Code:    code,
-		Message: message,
-		Data:    nil,
-	})
+// Fail 错误响应：按错误码返回 HTTP 状态码、错误码和请求语言的提示。
+// err 不是 *apperr.Error 时按内部错误处理，原因只写入日志
+func Fail(c *app.RequestContext, err error) {
+	e := apperr.As(err)
+	if e == nil {
+		e = apperr.Internal(err)
+	}
+	// 记录错误码和完整的错误（含内部原因），由请求日志中间件输出
+	c.Set(logging.ErrorCodeKey, string(e.Code))
+	c.Set(logging.ErrorMessageKey, e.Error())
+
+	lang := requestLang(c)
+	status := e.Status()
+	resp := Response{
+		Code:      status,
+		ErrorCode: e.Code,
+		Message:   e.Message(lang),
+		Data:      e.Data,
+		RequestID: c.GetString(logging.RequestIDKey),
+	}
+	for _, f := range e.Fields {
+		resp.Details = append(resp.Details, FieldDetail{Field: f.Field, Code: f.Code, Message: f.Message(lang)})
+	}
+	c.JSON(status, resp)
+}
+
+// requestLang 请求的语言，由 middleware.Language 确定，未经过该中间件时为默认语言
+func requestLang(c *app.RequestContext) i18n.Lang {
+	v, _ := c.Get(i18n.LangKey)
+	if lang, ok := v.(i18n.Lang); ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+// message 请求语言的提示，key 为 i18n 包中的提示键
+func message(c *app.RequestContext, key string) string {
+	return i18n.Text(requestLang(c), key, nil)
+}
+
+// bindError 请求绑定失败：字段校验错误原样返回，其他为请求格式错误
+func bindError(err error) error {
+	if apperr.As(err) != nil {
+		return err
+	}
+	return apperr.Wrap(apperr.BadRequest, err)
+}
+
+// invalidID 路径或查询参数中的 ID 无效
+func invalidID(field string) error {
+	return apperr.Invalid(field, apperr.InvalidValue)
+}
+
+// saveError 保存失败：违反唯一索引时为 conflict，关联的记录不存在时为 ReferenceNotFound，已是 *apperr.Error 时原样返回，其他为内部错误
+func saveError(err error, conflict *apperr.Error) error {
+	switch {
+	case apperr.As(err) != nil:
+		return err
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		conflict.Err = err
+		return conflict
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return apperr.Wrap(apperr.ReferenceNotFound, err)
+	default:
+		return err
+	}
+}
+
+// notFound 查询失败：记录不存在时为 code，已是 *apperr.Error 时原样返回，其他为内部错误
+func notFound(err error, code apperr.Code) error {
+	if apperr.As(err) == nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.Wrap(code, err)
+	}
+	return err
 }
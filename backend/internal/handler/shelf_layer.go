@@ -3,11 +3,14 @@ package handler
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"gorm.io/gorm"
 
+	"booksystem/internal/apperr"
 	"booksystem/internal/db"
+	"booksystem/internal/i18n"
 )
 
 type ShelfLayerHandler struct {
@@ -22,36 +25,72 @@ func NewShelfLayerHandler(db *gorm.DB) *ShelfLayerHandler {
 type CreateShelfLayerRequest struct {
 	BookshelfID int64  `json:"bookshelf_id" binding:"required"`
 	Name        string `json:"name" binding:"required"`
+	Code        string `json:"code"`                     // 简码，如 2
+	Capacity    *int   `json:"capacity" binding:"min=0"` // 容量（册），为空或 0 表示不限
 }
 
 // UpdateShelfLayerRequest 更新层数请求
 type UpdateShelfLayerRequest struct {
-	BookshelfID int64  `json:"bookshelf_id" binding:"required"`
-	Name        string `json:"name" binding:"required"`
+	BookshelfID int64   `json:"bookshelf_id" binding:"required"`
+	Name        string  `json:"name" binding:"required"`
+	Code        *string `json:"code"`                     // 不传时保持不变，传空串表示清除
+	Capacity    *int    `json:"capacity" binding:"min=0"` // 不传时保持不变，传 0 表示不限
+}
+
+// CapacityWarning 层的馆藏册数超出容量
+type CapacityWarning struct {
+	ShelfLayerID int64  `json:"shelf_layer_id"`
+	Location     string `json:"location"`
+	Capacity     int    `json:"capacity"`
+	VolumeCount  int64  `json:"volume_count"`
+	Message      string `json:"message"`
 }
 
 // Create 创建层数
 func (h *ShelfLayerHandler) Create(ctx context.Context, c *app.RequestContext) {
 	var req CreateShelfLayerRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
+		return
+	}
+
+	code, err := normalizeLocationCode(req.Code)
+	if err != nil {
+		Fail(c, err)
 		return
 	}
 
 	shelfLayer := db.ShelfLayer{
 		BookshelfID: req.BookshelfID,
 		Name:        req.Name,
+		Code:        code,
+		Capacity:    capacityValue(req.Capacity),
+	}
+	if err := checkNameConflict(h.db, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID, req.Name, 0); err != nil {
+		Fail(c, err)
+		return
+	}
+	if taken, err := locationCodeTaken(h.db, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID, code, 0); err != nil {
+		Fail(c, err)
+		return
+	} else if taken {
+		Fail(c, apperr.New(apperr.DuplicateCode, "code", *code))
+		return
+	}
+	if shelfLayer.SortOrder, err = nextSortOrder(h.db, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID); err != nil {
+		Fail(c, err)
+		return
 	}
 
 	if err := h.db.Create(&shelfLayer).Error; err != nil {
-		Error(c, 400, "创建失败: "+err.Error())
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
 		return
 	}
 
 	Success(c, shelfLayer)
 }
 
-// List 查询层数列表
+// List 查询层数列表，按排序值和名称排列
 func (h *ShelfLayerHandler) List(ctx context.Context, c *app.RequestContext) {
 	var shelfLayers []db.ShelfLayer
 	query := h.db.Model(&db.ShelfLayer{})
@@ -61,42 +100,85 @@ func (h *ShelfLayerHandler) List(ctx context.Context, c *app.RequestContext) {
 	}
 
 	if err := query.Find(&shelfLayers).Error; err != nil {
-		Error(c, 500, "查询失败: "+err.Error())
+		Fail(c, err)
 		return
 	}
+	sortShelfLayers(shelfLayers)
 
 	Success(c, shelfLayers)
 }
 
+// GetByCode 根据位置码查询层，扫码上架时识别扫到的层
+func (h *ShelfLayerHandler) GetByCode(ctx context.Context, c *app.RequestContext) {
+	layer, err := findShelfLayerByCode(h.db.Preload("Bookshelf.Area"), c.Param("code"))
+	if err != nil {
+		Fail(c, notFound(err, apperr.ShelfLayerNotFound))
+		return
+	}
+	Success(c, layer)
+}
+
+// isShelfLayerCode 判断扫到的是层位置码还是图书一维码
+func isShelfLayerCode(code string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(code)), db.ShelfLayerCodePrefix)
+}
+
+// findShelfLayerByCode 按位置码查询层，大小写不敏感
+func findShelfLayerByCode(tx *gorm.DB, code string) (db.ShelfLayer, error) {
+	var layer db.ShelfLayer
+	err := tx.Where("label_code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&layer).Error
+	return layer, err
+}
+
 // Update 更新层数
 func (h *ShelfLayerHandler) Update(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
 	var req UpdateShelfLayerRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		Error(c, 400, err.Error())
+		Fail(c, bindError(err))
 		return
 	}
 
 	var shelfLayer db.ShelfLayer
 	if err := h.db.First(&shelfLayer, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			Error(c, 404, "层数不存在")
-		} else {
-			Error(c, 500, "查询失败: "+err.Error())
-		}
+		Fail(c, notFound(err, apperr.ShelfLayerNotFound))
 		return
 	}
 
-	if err := h.db.Model(&shelfLayer).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"bookshelf_id": req.BookshelfID,
 		"name":         req.Name,
-	}).Error; err != nil {
-		Error(c, 400, "更新失败: "+err.Error())
+	}
+	code := shelfLayer.Code
+	if req.Code != nil {
+		if code, err = normalizeLocationCode(*req.Code); err != nil {
+			Fail(c, err)
+			return
+		}
+		updates["code"] = code
+	}
+	if err := checkNameConflict(h.db, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID, req.Name, id); err != nil {
+		Fail(c, err)
+		return
+	}
+	if taken, err := locationCodeTaken(h.db, &db.ShelfLayer{}, "bookshelf_id", req.BookshelfID, code, id); err != nil {
+		Fail(c, err)
+		return
+	} else if taken {
+		Fail(c, apperr.New(apperr.DuplicateCode, "code", *code))
+		return
+	}
+	if req.Capacity != nil {
+		updates["capacity"] = capacityValue(req.Capacity)
+	}
+
+	if err := h.db.Model(&shelfLayer).Updates(updates).Error; err != nil {
+		Fail(c, saveError(err, apperr.New(apperr.DuplicateName, "name", req.Name)))
 		return
 	}
 
@@ -108,14 +190,54 @@ func (h *ShelfLayerHandler) Update(ctx context.Context, c *app.RequestContext) {
 func (h *ShelfLayerHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		Error(c, 400, "无效的ID")
+		Fail(c, invalidID("id"))
 		return
 	}
 
-	if err := h.db.Delete(&db.ShelfLayer{}, id).Error; err != nil {
-		Error(c, 500, "删除失败: "+err.Error())
-		return
+	deleteLocation(c, h.db, apperr.ShelfLayerNotFound, func(tx *gorm.DB) (*locationSubtree, error) {
+		var layer db.ShelfLayer
+		if err := tx.Select("id").First(&layer, id).Error; err != nil {
+			return nil, err
+		}
+		return &locationSubtree{layerIDs: []int64{id}}, nil
+	})
+}
+
+// capacityValue 容量为 0 时视为不限
+func capacityValue(capacity *int) *int {
+	if capacity == nil || *capacity == 0 {
+		return nil
 	}
+	return capacity
+}
 
-	Success(c, nil)
+// layerCapacityWarning 统计层的馆藏册数，超出容量时返回 lang 语言的提醒，未设置容量或未超出时返回 nil
+func layerCapacityWarning(tx *gorm.DB, layerID int64, lang i18n.Lang) (*CapacityWarning, error) {
+	var layer db.ShelfLayer
+	if err := tx.Preload("Bookshelf.Area").First(&layer, layerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if layer.Capacity == nil {
+		return nil, nil
+	}
+	var volumes int64
+	if err := tx.Model(&db.Book{}).Where("shelf_layer_id = ?", layerID).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&volumes).Error; err != nil {
+		return nil, err
+	}
+	if volumes <= int64(*layer.Capacity) {
+		return nil, nil
+	}
+	location := layerLocation(layer)
+	params := map[string]interface{}{"location": location, "volumes": volumes, "capacity": *layer.Capacity}
+	return &CapacityWarning{
+		ShelfLayerID: layer.ID,
+		Location:     location,
+		Capacity:     *layer.Capacity,
+		VolumeCount:  volumes,
+		Message:      i18n.Text(lang, i18n.MsgCapacityExceeded, params),
+	}, nil
 }
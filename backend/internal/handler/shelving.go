@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+
+	"booksystem/internal/apperr"
+	"booksystem/internal/db"
+)
+
+// maxShelvingCodes 单次上架提交的扫码数上限
+const maxShelvingCodes = 1000
+
+// 上架结果中每次扫码的处理状态
+const (
+	ShelvingLayer         = "layer"           // 扫到层位置码，之后的图书上架到该层
+	ShelvingLayerNotFound = "layer_not_found" // 位置码不存在，之后的图书在扫到有效位置码前不处理
+	ShelvingMoved         = "moved"           // 图书位置已变更
+	ShelvingUnchanged     = "unchanged"       // 图书已在该层
+	ShelvingBookNotFound  = "not_found"       // 一维码没有对应的图书
+	ShelvingNoLayer       = "no_layer"        // 扫书前没有扫到有效的位置码
+)
+
+type ShelvingHandler struct {
+	db *gorm.DB
+}
+
+func NewShelvingHandler(db *gorm.DB) *ShelvingHandler {
+	return &ShelvingHandler{db: db}
+}
+
+// ShelvingRequest 扫码上架请求，codes 为按扫码顺序排列的层位置码和图书一维码
+type ShelvingRequest struct {
+	Codes []string `json:"codes" binding:"required"`
+}
+
+// ShelvingItem 单次扫码的处理结果
+type ShelvingItem struct {
+	Code         string  `json:"code"`
+	Status       string  `json:"status"`
+	BookID       int64   `json:"book_id,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	FromLayerID  *int64  `json:"from_layer_id,omitempty"`
+	FromLocation *string `json:"from_location,omitempty"`
+	ToLayerID    int64   `json:"to_layer_id,omitempty"`
+	ToLocation   string  `json:"to_location,omitempty"`
+}
+
+// Shelve 扫码上架：先扫层位置码，再扫该层的图书，可在一次提交中连续扫多个层。
+// 位置变更的图书写入上架记录，同一次提交的记录共用批次号。
+func (h *ShelvingHandler) Shelve(ctx context.Context, c *app.RequestContext) {
+	var req ShelvingRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		Fail(c, bindError(err))
+		return
+	}
+	if len(req.Codes) > maxShelvingCodes {
+		Fail(c, apperr.Invalid("codes", apperr.TooMany, "max", maxShelvingCodes))
+		return
+	}
+
+	batchID, err := newShelvingBatchID()
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	items := make([]ShelvingItem, 0, len(req.Codes))
+	moved, unchanged := 0, 0
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// 已查询过的层，用于显示原位置
+		layers := make(map[int64]*db.ShelfLayer)
+		var current *db.ShelfLayer
+
+		for _, code := range req.Codes {
+			code = strings.TrimSpace(code)
+			if code == "" {
+				continue
+			}
+			item := ShelvingItem{Code: code}
+
+			if isShelfLayerCode(code) {
+				layer, err := findShelfLayerByCode(tx.Preload("Bookshelf.Area"), code)
+				if err == gorm.ErrRecordNotFound {
+					current = nil
+					item.Status = ShelvingLayerNotFound
+					items = append(items, item)
+					continue
+				} else if err != nil {
+					return err
+				}
+				current = &layer
+				layers[layer.ID] = current
+				item.Status = ShelvingLayer
+				item.ToLayerID = layer.ID
+				item.ToLocation = layerLocation(layer)
+				items = append(items, item)
+				continue
+			}
+
+			if current == nil {
+				item.Status = ShelvingNoLayer
+				items = append(items, item)
+				continue
+			}
+
+			book, err := findBookByBarcode(tx, code)
+			if err == gorm.ErrRecordNotFound {
+				item.Status = ShelvingBookNotFound
+				items = append(items, item)
+				continue
+			} else if err != nil {
+				return err
+			}
+			item.BookID = book.ID
+			item.Name = book.Name
+			item.ToLayerID = current.ID
+			item.ToLocation = layerLocation(*current)
+
+			if book.ShelfLayerID != nil {
+				from, ok := layers[*book.ShelfLayerID]
+				if !ok {
+					var layer db.ShelfLayer
+					if err := tx.Preload("Bookshelf.Area").First(&layer, *book.ShelfLayerID).Error; err == nil {
+						from = &layer
+						layers[layer.ID] = from
+					}
+				}
+				item.FromLayerID = book.ShelfLayerID
+				if from != nil {
+					location := layerLocation(*from)
+					item.FromLocation = &location
+				}
+				if *book.ShelfLayerID == current.ID {
+					item.Status = ShelvingUnchanged
+					unchanged++
+					items = append(items, item)
+					continue
+				}
+			}
+
+			if err := tx.Model(&db.Book{}).Where("id = ?", book.ID).Update("shelf_layer_id", current.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&db.ShelvingLog{
+				BatchID:     batchID,
+				BookID:      book.ID,
+				Barcode:     book.Barcode,
+				FromLayerID: book.ShelfLayerID,
+				ToLayerID:   &current.ID,
+			}).Error; err != nil {
+				return err
+			}
+			item.Status = ShelvingMoved
+			moved++
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+
+	result := map[string]interface{}{
+		"moved":     moved,
+		"unchanged": unchanged,
+		"items":     items,
+	}
+	if moved > 0 {
+		result["batch_id"] = batchID
+	}
+	Success(c, result)
+}
+
+// Logs 查询上架记录，可按图书、层（原位置或新位置）、批次筛选
+func (h *ShelvingHandler) Logs(ctx context.Context, c *app.RequestContext) {
+	query := h.db.Model(&db.ShelvingLog{})
+	if bookID := c.Query("book_id"); bookID != "" {
+		query = query.Where("book_id = ?", bookID)
+	}
+	if layerID := c.Query("shelf_layer_id"); layerID != "" {
+		query = query.Where("from_layer_id = ? OR to_layer_id = ?", layerID, layerID)
+	}
+	if batchID := c.Query("batch_id"); batchID != "" {
+		query = query.Where("batch_id = ?", batchID)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	offset := (page - 1) * pageSize
+
+	var total int64
+	query.Count(&total)
+
+	var logs []db.ShelvingLog
+	if err := query.Preload("Book").
+		Preload("FromLayer.Bookshelf.Area").
+		Preload("ToLayer.Bookshelf.Area").
+		Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		Fail(c, err)
+		return
+	}
+
+	type LogResponse struct {
+		ID           int64     `json:"id"`
+		BatchID      string    `json:"batch_id"`
+		BookID       int64     `json:"book_id"`
+		Barcode      string    `json:"barcode"`
+		Name         *string   `json:"name,omitempty"`
+		FromLayerID  *int64    `json:"from_layer_id,omitempty"`
+		FromLocation *string   `json:"from_location,omitempty"`
+		ToLayerID    *int64    `json:"to_layer_id,omitempty"`
+		ToLocation   *string   `json:"to_location,omitempty"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+
+	list := make([]LogResponse, len(logs))
+	for i, log := range logs {
+		list[i] = LogResponse{
+			ID:          log.ID,
+			BatchID:     log.BatchID,
+			BookID:      log.BookID,
+			Barcode:     log.Barcode,
+			FromLayerID: log.FromLayerID,
+			ToLayerID:   log.ToLayerID,
+			CreatedAt:   log.CreatedAt,
+		}
+		if log.Book != nil {
+			list[i].Name = &log.Book.Name
+		}
+		if log.FromLayer != nil {
+			location := layerLocation(*log.FromLayer)
+			list[i].FromLocation = &location
+		}
+		if log.ToLayer != nil {
+			location := layerLocation(*log.ToLayer)
+			list[i].ToLocation = &location
+		}
+	}
+
+	Success(c, map[string]interface{}{
+		"list":      list,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// relocateBooks 把图书整体转移到目标层，并作为一个批次写入上架记录。
+// books 需包含 id、barcode 和原来的 shelf_layer_id。
+func relocateBooks(tx *gorm.DB, books []db.Book, targetID int64) error {
+	if len(books) == 0 {
+		return nil
+	}
+	batchID, err := newShelvingBatchID()
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, len(books))
+	logs := make([]db.ShelvingLog, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		logs[i] = db.ShelvingLog{
+			BatchID:     batchID,
+			BookID:      book.ID,
+			Barcode:     book.Barcode,
+			FromLayerID: book.ShelfLayerID,
+			ToLayerID:   &targetID,
+		}
+	}
+	if err := tx.Model(&db.Book{}).Where("id IN ?", ids).Update("shelf_layer_id", targetID).Error; err != nil {
+		return err
+	}
+	return tx.Create(&logs).Error
+}
+
+// layerLocation 层的完整位置名称，如 "一楼-A架-第1层"
+func layerLocation(layer db.ShelfLayer) string {
+	return layer.Bookshelf.Area.Name + "-" + layer.Bookshelf.Name + "-" + layer.Name
+}
+
+// newShelvingBatchID 生成上架批次号，时间在前便于排序
+func newShelvingBatchID() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,45 @@
+//go:build integration
+
+package handler
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"booksystem/internal/db"
+	"booksystem/internal/db/migrations"
+)
+
+// 带 integration 标签时处理器测试在 TEST_DB_DRIVER、TEST_DB_DSN 指定的 MySQL 或 PostgreSQL 上运行，
+// 数据库容器由 docker-compose.dev.yml 启动，例如：
+//
+//	docker compose -f docker-compose.dev.yml --profile postgres up -d
+//	TEST_DB_DRIVER=postgres TEST_DB_DSN="host=127.0.0.1 user=booksystem password=booksystem dbname=booksystem port=5432 sslmode=disable" \
+//		go test -tags integration ./internal/handler/
+//
+// 每个测试开始前回滚全部迁移后重新执行，库中原有的数据会被清空，不要指向正在使用的数据库。
+
+// openTestDB 连接 TEST_DB_DSN 指定的数据库并重建表结构，未设置时跳过测试
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_DSN")
+	if driver == "" || dsn == "" {
+		t.Skip("TEST_DB_DRIVER and TEST_DB_DSN are not set")
+	}
+	database, err := db.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Logger = logger.Discard
+	migrator, err := migrations.New(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(len(migrations.All())); err != nil {
+		t.Fatal(err)
+	}
+	return migrateTestDB(t, database)
+}
@@ -0,0 +1,30 @@
+//go:build !integration
+
+package handler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"booksystem/internal/db"
+)
+
+// openTestDB 在临时目录中创建 SQLite 数据库并执行全部迁移，MySQL/PostgreSQL 见 testdb_integration_test.go。
+// 迁移重建表时要求关闭外键检查，迁移后重新打开并启用外键检查，删除规则与 MySQL/PostgreSQL 一致。
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	database, err := db.Open(db.DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrateTestDB(t, database)
+
+	database, err = db.Open(db.DriverSQLite, path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return closeTestDB(t, database)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"booksystem/internal/apperr"
+)
+
+// Validator 校验请求结构体字段的 binding 标签，用于 server.WithCustomValidator，BindAndValidate 时调用。
+// 支持的规则（逗号分隔）：
+//   - required：字段不能为零值，字符串不能为空白，切片不能为空
+//   - min=N：数值不能小于 N
+//
+// 校验失败时返回 apperr.ValidationFailed，列出全部不合格的字段，字段名取 json 标签
+type Validator struct{}
+
+func (Validator) ValidateStruct(obj interface{}) error {
+	v, ok := obj.(reflect.Value)
+	if !ok {
+		v = reflect.ValueOf(obj)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if fields := validateFields(v); len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
+
+func (Validator) Engine() interface{} {
+	return nil
+}
+
+func (Validator) ValidateTag() string {
+	return "binding"
+}
+
+func validateFields(v reflect.Value) []apperr.FieldError {
+	var fields []apperr.FieldError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		if sf.Anonymous && fv.Kind() == reflect.Struct {
+			fields = append(fields, validateFields(fv)...)
+			continue
+		}
+		tag := sf.Tag.Get("binding")
+		if tag == "" {
+			continue
+		}
+		name := fieldName(sf)
+		for _, rule := range strings.Split(tag, ",") {
+			if f, ok := checkRule(name, strings.TrimSpace(rule), fv); !ok {
+				fields = append(fields, f)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// checkRule 检查单条规则，不满足时返回字段错误
+func checkRule(name, rule string, fv reflect.Value) (apperr.FieldError, bool) {
+	switch {
+	case rule == "required":
+		if isBlank(fv) {
+			return apperr.Field(name, apperr.Required), false
+		}
+	case strings.HasPrefix(rule, "min="):
+		min, err := strconv.ParseFloat(strings.TrimPrefix(rule, "min="), 64)
+		if err != nil {
+			break
+		}
+		if n, ok := number(fv); ok && n < min {
+			return apperr.Field(name, apperr.TooSmall, "min", strings.TrimPrefix(rule, "min=")), false
+		}
+	}
+	return apperr.FieldError{}, true
+}
+
+// fieldName json 标签中的字段名，没有时为结构体字段名
+func fieldName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return sf.Name
+}
+
+func isBlank(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// number 数值字段（指针为空时视为未设置）的值
+func number(v reflect.Value) (float64, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package i18n
+
+// en 英文提示
+var en = map[string]string{
+	"BAD_REQUEST":       "Malformed request",
+	"VALIDATION_FAILED": "Invalid request parameters",
+	"INTERNAL_ERROR":    "Internal server error, please try again later",
+
+	"REQUIRED":                  "{field} is required",
+	"INVALID_VALUE":             "{field} has an invalid value",
+	"TOO_SMALL":                 "{field} must be at least {min}",
+	"OUT_OF_RANGE":              "{field} must be between {min} and {max}",
+	"TOO_MANY":                  "{field} allows at most {max} items",
+	"DUPLICATE_ID":              "{field} contains a duplicate ID: {id}",
+	"MISSING_ID":                "{field} must include every node under the same parent, missing: {id}",
+	"FOREIGN_ID":                "{field} may only include nodes under the same parent",
+	"SAME_LAYER":                "Cannot merge a layer into itself",
+	"INVALID_LOCATION_CODE":     "Location codes may only contain letters and digits, up to 16 characters",
+	"IN_STOCK_EXCEEDS_QUANTITY": "Copies in stock cannot exceed the total quantity",
+
+	"BOOK_NOT_FOUND":          "Book not found",
+	"DUPLICATE_BARCODE":       "Barcode already exists: {barcode}",
+	"BARCODE_CHECKSUM":        "Barcode check digit is wrong, please check the scan or input",
+	"BARCODE_UNKNOWN_FORMAT":  "Barcode is neither a valid ISBN/EAN-13 nor a library barcode",
+	"BOOK_HAS_LOANS":          "This book has loan records and cannot be deleted",
+	"CATALOG_ENTRY_NOT_FOUND": "No catalogue entry found for this book",
+	"AUTHOR_NOT_FOUND":        "Author not found",
+	"PUBLISHER_NOT_FOUND":     "Publisher not found",
+	"CATEGORY_NOT_FOUND":      "Category not found",
+	"TAG_NOT_FOUND":           "Tag not found",
+	"DUPLICATE_NAME":          "Name already exists: {name}",
+	"REFERENCE_NOT_FOUND":     "A referenced record does not exist",
+	"UNSUPPORTED_IMPORT_FILE": "Only CSV, ISO 2709 (.mrc/.iso) and MARCXML (.xml) files are supported",
+	"MARC_MISSING_BARCODE":    "Record has neither an ISBN (020$a) nor a holdings barcode (852$p)",
+	"MARC_MISSING_TITLE":      "Record has no title (245$a)",
+	"MARC_RECORD_TOO_LONG":    "A record is too long for ISO 2709 ({reason}), export as MARCXML instead",
+
+	"STOCK_EXHAUSTED":     "All copies of \"{name}\" are on loan",
+	"LOAN_LIMIT_EXCEEDED": "Loan limit exceeded: each borrower may have at most {max} books, currently {borrowed}",
+	"LOAN_NOT_FOUND":      "No loan found for this book, or the borrower does not match",
+	"BORROWER_NOT_FOUND":  "No loans found for this phone number",
+	"BORROWER_NOT_SET":    "Please enter the borrower's details first",
+	"NO_BOOKS_SELECTED":   "Please add at least one book",
+	"SESSION_EXPIRED":     "This QR code has expired, please scan the code on the kiosk again",
+
+	"AREA_NOT_FOUND":        "Area not found",
+	"BOOKSHELF_NOT_FOUND":   "Bookshelf not found",
+	"SHELF_LAYER_NOT_FOUND": "Shelf layer not found",
+	"NO_MATCHING_LAYERS":    "No matching shelf layers",
+	"DUPLICATE_CODE":        "Another node under the same parent already uses code {code}",
+	"LOCATION_CONFLICT":     "Another node under the same parent is already named {name}",
+	"LOCATION_NOT_EMPTY":    "This location still holds {children} bookshelves/layers and {books} titles; relocate the books or delete in cascade",
+	"TARGET_IN_SUBTREE":     "The target layer belongs to the location being deleted",
+
+	"FILE_TOO_LARGE":         "File exceeds the size limit ({max} MB)",
+	"UNSUPPORTED_MEDIA_TYPE": "Unsupported file type: {type}",
+	"COVER_NOT_IMAGE":        "The cover must be an image",
+	"INVALID_FILE":           "File could not be parsed: {reason}",
+	"ATTACHMENT_NOT_FOUND":   "Attachment not found",
+	"THUMBNAIL_NOT_FOUND":    "This attachment has no thumbnail",
+	"BACKUP_NOT_FOUND":       "Backup not found",
+	"BACKUP_NOT_SUPPORTED":   "Online backup is only supported for SQLite; use your database's own backup tools",
+	"INVALID_LABEL_LAYOUT":   "Invalid label layout: {reason}",
+	"TOO_MANY_LABELS":        "Too many labels (at most {max})",
+	"BARCODE_NOT_ENCODABLE":  "Cannot generate barcode: {reason}",
+	"EXPORT_TOO_LARGE":       "Too many rows for an XLSX export (at most {max}), narrow the filters or export CSV",
+
+	MsgBorrowed:         "Books borrowed",
+	MsgReturned:         "Book returned",
+	MsgUserSaved:        "Borrower details saved",
+	MsgBookAdded:        "Book added",
+	MsgBookRemoved:      "Book removed",
+	MsgCapacityExceeded: "{location} is over capacity: {volumes}/{capacity} volumes",
+
+	// 导出表格的列名、分隔符和借阅状态
+	"COLUMN_ID":             "ID",
+	"COLUMN_BARCODE":        "Barcode",
+	"COLUMN_TITLE":          "Title",
+	"COLUMN_AUTHORS":        "Authors",
+	"COLUMN_PUBLISHER":      "Publisher",
+	"COLUMN_PUBLISH_YEAR":   "Publish year",
+	"COLUMN_EDITION":        "Edition",
+	"COLUMN_LANGUAGE":       "Language",
+	"COLUMN_CLASS_NUMBER":   "Class number",
+	"COLUMN_TAGS":           "Tags",
+	"COLUMN_QUANTITY":       "Quantity",
+	"COLUMN_IN_STOCK":       "In stock",
+	"COLUMN_LOCATION":       "Location",
+	"COLUMN_PRICE":          "Price",
+	"COLUMN_REMARK":         "Remark",
+	"COLUMN_CREATED_AT":     "Created at",
+	"COLUMN_RECORD_ID":      "Record ID",
+	"COLUMN_BORROWER_NAME":  "Borrower name",
+	"COLUMN_BORROWER_PHONE": "Borrower phone",
+	"COLUMN_BORROW_TIME":    "Borrowed at",
+	"COLUMN_STATUS":         "Status",
+	"COLUMN_NAME":           "Name",
+	"COLUMN_PHONE":          "Phone",
+
+	// 分号与目录文件导入时的作者分隔符一致，导出的文件可以重新导入
+	MsgListSeparator:  "; ",
+	MsgStatusBorrowed: "On loan",
+	MsgStatusReturned: "Returned",
+}
@@ -0,0 +1,102 @@
+// Package i18n 接口提示信息的多语言目录（简体中文和英文）。
+// 提示按键查找，键为错误码（见 apperr 包）或成功提示名；模板中的 {name} 由参数替换。
+// 请求的语言由 lang 参数或 Cookie（用户偏好）决定，没有时按 Accept-Language 协商，默认简体中文。
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lang 语言
+type Lang string
+
+// 支持的语言
+const (
+	ZhCN Lang = "zh-CN"
+	En   Lang = "en"
+)
+
+// Default 默认语言，请求未指定或不支持时使用
+const Default = ZhCN
+
+// LangKey RequestContext 中保存请求语言的键，也是偏好语言的查询参数和 Cookie 名
+const LangKey = "lang"
+
+// 成功提示和提醒的键，错误提示的键为错误码
+const (
+	MsgBorrowed         = "BORROWED"
+	MsgReturned         = "RETURNED"
+	MsgUserSaved        = "USER_SAVED"
+	MsgBookAdded        = "BOOK_ADDED"
+	MsgBookRemoved      = "BOOK_REMOVED"
+	MsgCapacityExceeded = "CAPACITY_EXCEEDED"
+)
+
+// 导出表格中的文字，列名的键为 COLUMN_ 加列名（见 handler 包的导出）
+const (
+	MsgListSeparator  = "LIST_SEPARATOR" // 作者、标签等名称之间的分隔符
+	MsgStatusBorrowed = "STATUS_BORROWED"
+	MsgStatusReturned = "STATUS_RETURNED"
+)
+
+// catalogues 各语言的提示目录，缺少的键使用默认语言
+var catalogues = map[Lang]map[string]string{
+	ZhCN: zhCN,
+	En:   en,
+}
+
+// Parse 解析语言标签，如 zh、zh-CN、zh-Hans、en-US，不支持时返回 false
+func Parse(tag string) (Lang, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	switch primary {
+	case "zh":
+		return ZhCN, true
+	case "en":
+		return En, true
+	}
+	return "", false
+}
+
+// Negotiate 按 Accept-Language 请求头选择权重最高的支持语言，都不支持时为默认语言
+func Negotiate(acceptLanguage string) Lang {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if lang, ok := Parse(tag); ok && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Text 查找 key 在 lang 中的提示并替换参数，两种语言都没有时返回 key
+func Text(lang Lang, key string, params map[string]interface{}) string {
+	tmpl, ok := catalogues[lang][key]
+	if !ok {
+		if tmpl, ok = catalogues[Default][key]; !ok {
+			return key
+		}
+	}
+	return render(tmpl, params)
+}
+
+// render 把模板中的 {name} 替换为参数值，缺少的参数保留原样
+func render(tmpl string, p map[string]interface{}) string {
+	if len(p) == 0 || !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	pairs := make([]string, 0, len(p)*2)
+	for k, v := range p {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
@@ -0,0 +1,103 @@
+package i18n
+
+// zhCN 简体中文提示，也是其他语言缺少某个键时的后备
+var zhCN = map[string]string{
+	"BAD_REQUEST":       "请求格式错误",
+	"VALIDATION_FAILED": "请求参数有误",
+	"INTERNAL_ERROR":    "服务器内部错误，请稍后重试",
+
+	"REQUIRED":                  "{field} 不能为空",
+	"INVALID_VALUE":             "{field} 的值无效",
+	"TOO_SMALL":                 "{field} 不能小于 {min}",
+	"OUT_OF_RANGE":              "{field} 应在 {min} 到 {max} 之间",
+	"TOO_MANY":                  "{field} 最多 {max} 个",
+	"DUPLICATE_ID":              "{field} 中有重复的 ID: {id}",
+	"MISSING_ID":                "{field} 需包含同一上级下的全部节点，缺少: {id}",
+	"FOREIGN_ID":                "{field} 只能包含同一上级下的节点",
+	"SAME_LAYER":                "不能合并到本层",
+	"INVALID_LOCATION_CODE":     "简码只能包含字母和数字，最多16位",
+	"IN_STOCK_EXCEEDS_QUANTITY": "在库数量不能大于总数量",
+
+	"BOOK_NOT_FOUND":          "图书不存在",
+	"DUPLICATE_BARCODE":       "一维码已存在: {barcode}",
+	"BARCODE_CHECKSUM":        "一维码校验位错误，请检查是否扫描或输入有误",
+	"BARCODE_UNKNOWN_FORMAT":  "一维码不是有效的ISBN/EAN-13，也不是馆内条码",
+	"BOOK_HAS_LOANS":          "该图书存在借阅记录，无法删除",
+	"CATALOG_ENTRY_NOT_FOUND": "目录中未找到该书目",
+	"AUTHOR_NOT_FOUND":        "作者不存在",
+	"PUBLISHER_NOT_FOUND":     "出版社不存在",
+	"CATEGORY_NOT_FOUND":      "分类不存在",
+	"TAG_NOT_FOUND":           "标签不存在",
+	"DUPLICATE_NAME":          "名称已存在: {name}",
+	"REFERENCE_NOT_FOUND":     "关联的记录不存在",
+	"UNSUPPORTED_IMPORT_FILE": "仅支持 CSV、ISO 2709(.mrc/.iso) 和 MARCXML(.xml) 文件",
+	"MARC_MISSING_BARCODE":    "记录缺少ISBN(020$a)和馆藏条码(852$p)",
+	"MARC_MISSING_TITLE":      "记录缺少题名(245$a)",
+	"MARC_RECORD_TOO_LONG":    "记录超出 ISO 2709 的长度上限（{reason}），请导出为 MARCXML",
+
+	"STOCK_EXHAUSTED":     "《{name}》已全部借出",
+	"LOAN_LIMIT_EXCEEDED": "超出借阅上限：每位读者最多同时借阅 {max} 册，当前已借 {borrowed} 册",
+	"LOAN_NOT_FOUND":      "未找到该图书的借阅记录或归还人信息不匹配",
+	"BORROWER_NOT_FOUND":  "未找到该电话的借阅记录",
+	"BORROWER_NOT_SET":    "请先设置用户信息",
+	"NO_BOOKS_SELECTED":   "请至少添加一本图书",
+	"SESSION_EXPIRED":     "二维码已失效，请重新扫描终端上的二维码",
+
+	"AREA_NOT_FOUND":        "区域不存在",
+	"BOOKSHELF_NOT_FOUND":   "书架不存在",
+	"SHELF_LAYER_NOT_FOUND": "层不存在",
+	"NO_MATCHING_LAYERS":    "没有符合条件的层",
+	"DUPLICATE_CODE":        "同一上级下已有相同简码: {code}",
+	"LOCATION_CONFLICT":     "同一上级下已有同名节点: {name}",
+	"LOCATION_NOT_EMPTY":    "该位置下还有{children}个书架/层、{books}种图书，请选择转移图书或级联删除",
+	"TARGET_IN_SUBTREE":     "目标层属于要删除的位置",
+
+	"FILE_TOO_LARGE":         "文件大小超出限制（最大{max}MB）",
+	"UNSUPPORTED_MEDIA_TYPE": "不支持的文件类型: {type}",
+	"COVER_NOT_IMAGE":        "封面必须是图片",
+	"INVALID_FILE":           "文件无法解析: {reason}",
+	"ATTACHMENT_NOT_FOUND":   "附件不存在",
+	"THUMBNAIL_NOT_FOUND":    "该附件没有缩略图",
+	"BACKUP_NOT_FOUND":       "备份不存在",
+	"BACKUP_NOT_SUPPORTED":   "仅 SQLite 数据库支持在线备份，其他数据库请使用数据库自带的备份工具",
+	"INVALID_LABEL_LAYOUT":   "标签版式无效: {reason}",
+	"TOO_MANY_LABELS":        "标签数量超出限制（最多{max}个）",
+	"BARCODE_NOT_ENCODABLE":  "无法生成条码: {reason}",
+	"EXPORT_TOO_LARGE":       "导出的行数超出 XLSX 的上限（最多{max}行），请缩小筛选范围或导出 CSV",
+
+	MsgBorrowed:         "借阅成功",
+	MsgReturned:         "归还成功",
+	MsgUserSaved:        "用户信息已保存",
+	MsgBookAdded:        "添加成功",
+	MsgBookRemoved:      "删除成功",
+	MsgCapacityExceeded: "{location}已超出容量：{volumes}/{capacity}册",
+
+	// 导出表格的列名、分隔符和借阅状态
+	"COLUMN_ID":             "ID",
+	"COLUMN_BARCODE":        "一维码",
+	"COLUMN_TITLE":          "书名",
+	"COLUMN_AUTHORS":        "作者",
+	"COLUMN_PUBLISHER":      "出版社",
+	"COLUMN_PUBLISH_YEAR":   "出版年",
+	"COLUMN_EDITION":        "版次",
+	"COLUMN_LANGUAGE":       "语种",
+	"COLUMN_CLASS_NUMBER":   "分类号",
+	"COLUMN_TAGS":           "标签",
+	"COLUMN_QUANTITY":       "数量",
+	"COLUMN_IN_STOCK":       "在库数量",
+	"COLUMN_LOCATION":       "位置",
+	"COLUMN_PRICE":          "价格",
+	"COLUMN_REMARK":         "备注",
+	"COLUMN_CREATED_AT":     "创建时间",
+	"COLUMN_RECORD_ID":      "借阅记录ID",
+	"COLUMN_BORROWER_NAME":  "借阅人姓名",
+	"COLUMN_BORROWER_PHONE": "借阅人电话",
+	"COLUMN_BORROW_TIME":    "借阅时间",
+	"COLUMN_STATUS":         "状态",
+	"COLUMN_NAME":           "姓名",
+	"COLUMN_PHONE":          "电话",
+
+	MsgListSeparator:  "、",
+	MsgStatusBorrowed: "借出",
+	MsgStatusReturned: "已归还",
+}
@@ -0,0 +1,132 @@
+package label
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+)
+
+// quietZone 条码两侧的空白区，单位为模块宽度
+const quietZone = 10
+
+// Symbology 条码码制
+type Symbology string
+
+const (
+	SymbologyEAN13   Symbology = "ean13"
+	SymbologyCode128 Symbology = "code128"
+)
+
+// Bars 编码后的一维码，每个元素代表一个模块，true 为黑条
+type Bars struct {
+	Content   string
+	Symbology Symbology
+	Modules   []bool
+}
+
+// Encode 生成一维码：13位数字（ISBN-13/EAN-13）使用 EAN-13，馆内条码等其他内容使用 Code128
+func Encode(code string) (*Bars, error) {
+	if code == "" {
+		return nil, fmt.Errorf("barcode content is empty")
+	}
+
+	var bc barcode.Barcode
+	var err error
+	symbology := SymbologyCode128
+	if len(code) == 13 && isDigits(code) {
+		symbology = SymbologyEAN13
+		bc, err = ean.Encode(code)
+	} else {
+		bc, err = code128.Encode(code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	width := bc.Bounds().Dx()
+	modules := make([]bool, width)
+	for x := 0; x < width; x++ {
+		r, _, _, _ := bc.At(bc.Bounds().Min.X+x, bc.Bounds().Min.Y).RGBA()
+		modules[x] = r == 0
+	}
+	return &Bars{Content: code, Symbology: symbology, Modules: modules}, nil
+}
+
+// runs 返回连续黑条的起始模块和宽度，用于绘制矢量图形
+func (b *Bars) runs() [][2]int {
+	var out [][2]int
+	for x := 0; x < len(b.Modules); x++ {
+		if !b.Modules[x] {
+			continue
+		}
+		start := x
+		for x < len(b.Modules) && b.Modules[x] {
+			x++
+		}
+		out = append(out, [2]int{start, x - start})
+	}
+	return out
+}
+
+// totalModules 含两侧空白区的总模块数
+func (b *Bars) totalModules() int {
+	return len(b.Modules) + 2*quietZone
+}
+
+// WriteSVG 输出 SVG 条码。module 为模块宽度（像素），height 为条高（像素），showText 为是否在条码下方显示内容
+func WriteSVG(w io.Writer, b *Bars, module, height int, showText bool) error {
+	textHeight := 0
+	if showText {
+		textHeight = module * 8
+	}
+	width := b.totalModules() * module
+	total := height + textHeight
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, total, width, total); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/>`, width, total)
+	fmt.Fprint(w, `<g fill="#000">`)
+	for _, run := range b.runs() {
+		fmt.Fprintf(w, `<rect x="%d" y="0" width="%d" height="%d"/>`, (run[0]+quietZone)*module, run[1]*module, height)
+	}
+	fmt.Fprint(w, `</g>`)
+	if showText {
+		fmt.Fprintf(w, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle">%s</text>`,
+			width/2, height+textHeight-module, module*7, html.EscapeString(b.Content))
+	}
+	_, err := fmt.Fprint(w, `</svg>`)
+	return err
+}
+
+// WritePNG 输出 PNG 条码。module 为模块宽度（像素），height 为条高（像素）
+func WritePNG(w io.Writer, b *Bars, module, height int) error {
+	img := image.NewGray(image.Rect(0, 0, b.totalModules()*module, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for _, run := range b.runs() {
+		for x := (run[0] + quietZone) * module; x < (run[0]+run[1]+quietZone)*module; x++ {
+			for y := 0; y < height; y++ {
+				img.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package label
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Layout 标签纸版式，尺寸单位均为毫米
+type Layout struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	PageWidth   float64 `json:"page_width"`
+	PageHeight  float64 `json:"page_height"`
+	Columns     int     `json:"columns"`
+	Rows        int     `json:"rows"`
+	LabelWidth  float64 `json:"label_width"`
+	LabelHeight float64 `json:"label_height"`
+	MarginTop   float64 `json:"margin_top"`
+	MarginLeft  float64 `json:"margin_left"`
+	GapX        float64 `json:"gap_x"` // 同一行相邻标签的水平间距
+	GapY        float64 `json:"gap_y"` // 相邻两行标签的垂直间距
+}
+
+// DefaultLayout 默认版式
+const DefaultLayout = "avery-l7160"
+
+// presets 常见不干胶标签纸版式
+var presets = map[string]Layout{
+	"avery-l7160": {Description: "A4 3×7，63.5×38.1mm", PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 7, LabelWidth: 63.5, LabelHeight: 38.1, MarginTop: 15.15, MarginLeft: 7.25, GapX: 2.5},
+	"avery-l7159": {Description: "A4 3×8，63.5×33.9mm", PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 8, LabelWidth: 63.5, LabelHeight: 33.9, MarginTop: 12.9, MarginLeft: 7.25, GapX: 2.5},
+	"avery-l7163": {Description: "A4 2×7，99.1×38.1mm", PageWidth: 210, PageHeight: 297, Columns: 2, Rows: 7, LabelWidth: 99.1, LabelHeight: 38.1, MarginTop: 15.15, MarginLeft: 4.65, GapX: 2.5},
+	"avery-l7651": {Description: "A4 5×13，38.1×21.2mm（适合书脊标签）", PageWidth: 210, PageHeight: 297, Columns: 5, Rows: 13, LabelWidth: 38.1, LabelHeight: 21.2, MarginTop: 10.7, MarginLeft: 4.75, GapX: 2.5},
+	"avery-5160":  {Description: "Letter 3×10，66.7×25.4mm", PageWidth: 215.9, PageHeight: 279.4, Columns: 3, Rows: 10, LabelWidth: 66.7, LabelHeight: 25.4, MarginTop: 12.7, MarginLeft: 4.8, GapX: 3.2},
+}
+
+// Preset 按名称获取预置版式
+func Preset(name string) (Layout, bool) {
+	l, ok := presets[name]
+	l.Name = name
+	return l, ok
+}
+
+// Presets 返回全部预置版式，按名称排序
+func Presets() []Layout {
+	out := make([]Layout, 0, len(presets))
+	for name := range presets {
+		l, _ := Preset(name)
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// PerPage 每页标签数
+func (l Layout) PerPage() int {
+	return l.Columns * l.Rows
+}
+
+// Validate 检查版式尺寸是否合理，标签不能超出纸张
+func (l Layout) Validate() error {
+	if l.PageWidth <= 0 || l.PageHeight <= 0 || l.LabelWidth <= 0 || l.LabelHeight <= 0 {
+		return fmt.Errorf("page and label size must be positive")
+	}
+	if l.Columns <= 0 || l.Rows <= 0 {
+		return fmt.Errorf("columns and rows must be positive")
+	}
+	if l.MarginTop < 0 || l.MarginLeft < 0 || l.GapX < 0 || l.GapY < 0 {
+		return fmt.Errorf("margins and gaps must not be negative")
+	}
+	if width := l.MarginLeft + float64(l.Columns)*l.LabelWidth + float64(l.Columns-1)*l.GapX; width > l.PageWidth+0.01 {
+		return fmt.Errorf("labels are %.1fmm wide in total, wider than the %.1fmm page", width, l.PageWidth)
+	}
+	if height := l.MarginTop + float64(l.Rows)*l.LabelHeight + float64(l.Rows-1)*l.GapY; height > l.PageHeight+0.01 {
+		return fmt.Errorf("labels are %.1fmm high in total, higher than the %.1fmm page", height, l.PageHeight)
+	}
+	return nil
+}
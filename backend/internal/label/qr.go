@@ -0,0 +1,82 @@
+package label
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode/qr"
+)
+
+// qrQuietZone 二维码四周的空白区，单位为模块
+const qrQuietZone = 4
+
+// QR 编码后的二维码，Modules[y][x] 为 true 表示黑色模块
+type QR struct {
+	Content string
+	Modules [][]bool
+}
+
+// EncodeQR 生成二维码，使用 M 级纠错
+func EncodeQR(content string) (*QR, error) {
+	bc, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+	bounds := bc.Bounds()
+	modules := make([][]bool, bounds.Dy())
+	for y := range modules {
+		modules[y] = make([]bool, bounds.Dx())
+		for x := range modules[y] {
+			r, _, _, _ := bc.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			modules[y][x] = r == 0
+		}
+	}
+	return &QR{Content: content, Modules: modules}, nil
+}
+
+// size 含空白区的边长（模块数）
+func (q *QR) size() int {
+	return len(q.Modules) + 2*qrQuietZone
+}
+
+// WriteQRSVG 输出 SVG 二维码，module 为模块边长（像素）
+func WriteQRSVG(w io.Writer, q *QR, module int) error {
+	size := q.size() * module
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, q.size(), q.size()); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, q.size(), q.size())
+	for y, row := range q.Modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(w, "M%d %dh1v1h-1z", x+qrQuietZone, y+qrQuietZone)
+			}
+		}
+	}
+	_, err := fmt.Fprint(w, `"/></svg>`)
+	return err
+}
+
+// WriteQRPNG 输出 PNG 二维码，module 为模块边长（像素）
+func WriteQRPNG(w io.Writer, q *QR, module int) error {
+	size := q.size() * module
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y, row := range q.Modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := (y + qrQuietZone) * module; py < (y+qrQuietZone+1)*module; py++ {
+				for px := (x + qrQuietZone) * module; px < (x+qrQuietZone+1)*module; px++ {
+					img.Pix[py*img.Stride+px] = 0
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
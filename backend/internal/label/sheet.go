@@ -0,0 +1,243 @@
+package label
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Style 标签样式
+type Style string
+
+const (
+	StyleBarcode Style = "barcode" // 一维码标签：书名、位置、一维码
+	StyleSpine   Style = "spine"   // 书脊标签：分类号（索书号）、书名、位置
+	StyleQR      Style = "qr"      // 二维码标签：左侧二维码，右侧书名（位置名称）和编码
+)
+
+// Label 单个标签的内容
+type Label struct {
+	Barcode    string
+	Title      string
+	Location   string
+	CallNumber string
+}
+
+// Options 生成标签页的选项
+type Options struct {
+	Style Style
+	// Skip 跳过开头的标签位，用于继续使用已撕掉部分标签的标签纸
+	Skip int
+	// FontPath 含中文字形的 TTF 字体文件；为空时使用 PDF 内置字体，非西文字符会显示为 "?"
+	FontPath string
+}
+
+// padding 标签内边距（毫米）
+const padding = 1.5
+
+// ptToMM 字号（磅）换算为毫米
+const ptToMM = 25.4 / 72
+
+// Render 按版式将标签排版为 PDF
+func Render(w io.Writer, layout Layout, labels []Label, opts Options) error {
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+	if opts.Skip < 0 || opts.Skip >= layout.PerPage() {
+		return fmt.Errorf("skip must be between 0 and %d", layout.PerPage()-1)
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: layout.PageWidth, Ht: layout.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+
+	r := &renderer{pdf: pdf, family: "Helvetica", latinOnly: true}
+	if opts.FontPath != "" {
+		data, err := os.ReadFile(opts.FontPath)
+		if err != nil {
+			return fmt.Errorf("read label font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes("label", "", data)
+		r.family, r.latinOnly = "label", false
+	}
+
+	for i, lbl := range labels {
+		slot := i + opts.Skip
+		pos := slot % layout.PerPage()
+		if i == 0 || pos == 0 {
+			pdf.AddPage()
+		}
+		col, row := pos%layout.Columns, pos/layout.Columns
+		x := layout.MarginLeft + float64(col)*(layout.LabelWidth+layout.GapX)
+		y := layout.MarginTop + float64(row)*(layout.LabelHeight+layout.GapY)
+
+		var err error
+		switch opts.Style {
+		case StyleSpine:
+			r.spine(lbl, x, y, layout.LabelWidth, layout.LabelHeight)
+		case StyleQR:
+			err = r.qr(lbl, x, y, layout.LabelWidth, layout.LabelHeight)
+		default:
+			err = r.barcode(lbl, x, y, layout.LabelWidth, layout.LabelHeight)
+		}
+		if err != nil {
+			return fmt.Errorf("label %d (%s): %w", i+1, lbl.Barcode, err)
+		}
+		if pdf.Err() {
+			return pdf.Error()
+		}
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+	return pdf.Output(w)
+}
+
+type renderer struct {
+	pdf       *fpdf.Fpdf
+	family    string
+	latinOnly bool
+}
+
+// fontSize 按标签高度确定字号，38mm 高的标签使用 9 磅
+func fontSize(height float64) float64 {
+	return math.Max(5, math.Min(10, height/38.1*9))
+}
+
+// barcode 绘制一维码标签：书名、位置、条码、条码文字
+func (r *renderer) barcode(lbl Label, x, y, w, h float64) error {
+	bars, err := Encode(lbl.Barcode)
+	if err != nil {
+		return err
+	}
+
+	size := fontSize(h)
+	line := size * ptToMM * 1.2
+	innerW := w - 2*padding
+	cy := y + padding
+
+	r.text(lbl.Title, x+padding, cy, innerW, size, "L")
+	cy += line
+	if lbl.Location != "" {
+		r.text(lbl.Location, x+padding, cy, innerW, size*0.85, "L")
+		cy += line * 0.85
+	}
+
+	barHeight := y + h - padding - line - cy
+	if barHeight < 3 {
+		return fmt.Errorf("label is too small for a barcode")
+	}
+	module := innerW / float64(bars.totalModules())
+	r.pdf.SetFillColor(0, 0, 0)
+	for _, run := range bars.runs() {
+		r.pdf.Rect(x+padding+float64(run[0]+quietZone)*module, cy, float64(run[1])*module, barHeight, "F")
+	}
+	r.text(lbl.Barcode, x+padding, cy+barHeight, innerW, size, "C")
+	return nil
+}
+
+// spine 绘制书脊标签：分类号按 "/" 分行放大显示，下方为书名和位置
+func (r *renderer) spine(lbl Label, x, y, w, h float64) {
+	size := fontSize(h)
+	innerW := w - 2*padding
+	cy := y + padding
+
+	callNumber := lbl.CallNumber
+	if callNumber == "" {
+		callNumber = lbl.Barcode
+	}
+	for _, part := range strings.Split(callNumber, "/") {
+		r.text(strings.TrimSpace(part), x+padding, cy, innerW, size*1.4, "C")
+		cy += size * 1.4 * ptToMM * 1.2
+	}
+	for _, s := range []string{lbl.Title, lbl.Location} {
+		if s == "" || cy+size*ptToMM*1.2 > y+h-padding {
+			continue
+		}
+		r.text(s, x+padding, cy, innerW, size*0.85, "C")
+		cy += size * 0.85 * ptToMM * 1.2
+	}
+}
+
+// qr 绘制二维码标签：二维码按标签高度居左，右侧依次为书名、位置、编码
+func (r *renderer) qr(lbl Label, x, y, w, h float64) error {
+	q, err := EncodeQR(lbl.Barcode)
+	if err != nil {
+		return err
+	}
+
+	side := math.Min(h-2*padding, w/2)
+	module := side / float64(q.size())
+	if module < 0.25 {
+		return fmt.Errorf("label is too small for a QR code")
+	}
+	qy := y + (h-side)/2
+	r.pdf.SetFillColor(0, 0, 0)
+	for row, modules := range q.Modules {
+		for col, dark := range modules {
+			if dark {
+				r.pdf.Rect(x+padding+float64(col+qrQuietZone)*module, qy+float64(row+qrQuietZone)*module, module, module, "F")
+			}
+		}
+	}
+
+	size := fontSize(h)
+	tx := x + padding + side
+	tw := x + w - padding - tx
+	cy := y + padding
+	for _, s := range []string{lbl.Title, lbl.Location, lbl.Barcode} {
+		if s == "" || cy+size*ptToMM*1.2 > y+h-padding {
+			continue
+		}
+		r.text(s, tx, cy, tw, size, "L")
+		cy += size * ptToMM * 1.2
+	}
+	return nil
+}
+
+// text 输出单行文字，超出宽度时截断并加省略号
+func (r *renderer) text(s string, x, y, w, size float64, align string) {
+	if r.latinOnly {
+		s = toLatin(s)
+	}
+	r.pdf.SetFont(r.family, "", size)
+	s = r.truncate(s, w)
+	r.pdf.SetXY(x, y)
+	r.pdf.CellFormat(w, size*ptToMM*1.2, s, "", 0, align, false, 0, "")
+}
+
+// truncate 按实际字宽截断文字
+func (r *renderer) truncate(s string, w float64) string {
+	if r.pdf.GetStringWidth(s) <= w {
+		return s
+	}
+	ellipsis := "…"
+	if r.latinOnly {
+		ellipsis = "..."
+	}
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		t := strings.TrimSpace(string(runes[:n])) + ellipsis
+		if r.pdf.GetStringWidth(t) <= w {
+			return t
+		}
+	}
+	return ""
+}
+
+// toLatin 内置字体只支持西文字符，其余替换为 "?"
+func toLatin(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
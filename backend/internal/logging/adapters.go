@@ -0,0 +1,194 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// hertzLogger 把 Hertz 的日志转到 slog，级别由 slog 控制
+type hertzLogger struct {
+	l *slog.Logger
+}
+
+// NewHertzLogger 用于 hlog.SetLogger。Trace 记为 debug，Notice 记为 info，Fatal 记为 error 后退出
+func NewHertzLogger(l *slog.Logger) hlog.FullLogger {
+	return hertzLogger{l: l.With("component", "hertz")}
+}
+
+func (h hertzLogger) log(ctx context.Context, level hlog.Level, msg string) {
+	var lv slog.Level
+	switch level {
+	case hlog.LevelTrace, hlog.LevelDebug:
+		lv = slog.LevelDebug
+	case hlog.LevelInfo, hlog.LevelNotice:
+		lv = slog.LevelInfo
+	case hlog.LevelWarn:
+		lv = slog.LevelWarn
+	default:
+		lv = slog.LevelError
+	}
+	h.l.Log(ctx, lv, msg)
+	if level == hlog.LevelFatal {
+		os.Exit(1)
+	}
+}
+
+func (h hertzLogger) Trace(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelTrace, fmt.Sprint(v...))
+}
+func (h hertzLogger) Debug(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelDebug, fmt.Sprint(v...))
+}
+func (h hertzLogger) Info(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelInfo, fmt.Sprint(v...))
+}
+func (h hertzLogger) Notice(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelNotice, fmt.Sprint(v...))
+}
+func (h hertzLogger) Warn(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelWarn, fmt.Sprint(v...))
+}
+func (h hertzLogger) Error(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelError, fmt.Sprint(v...))
+}
+func (h hertzLogger) Fatal(v ...interface{}) {
+	h.log(context.Background(), hlog.LevelFatal, fmt.Sprint(v...))
+}
+
+func (h hertzLogger) Tracef(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelTrace, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) Debugf(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) Infof(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) Noticef(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelNotice, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) Warnf(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelWarn, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) Errorf(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelError, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) Fatalf(format string, v ...interface{}) {
+	h.log(context.Background(), hlog.LevelFatal, fmt.Sprintf(format, v...))
+}
+
+func (h hertzLogger) CtxTracef(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelTrace, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) CtxDebugf(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) CtxInfof(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) CtxNoticef(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelNotice, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) CtxWarnf(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelWarn, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) CtxErrorf(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelError, fmt.Sprintf(format, v...))
+}
+func (h hertzLogger) CtxFatalf(ctx context.Context, format string, v ...interface{}) {
+	h.log(ctx, hlog.LevelFatal, fmt.Sprintf(format, v...))
+}
+
+// SetLevel 和 SetOutput 不起作用，级别和输出由 slog.Logger 决定
+func (h hertzLogger) SetLevel(hlog.Level)   {}
+func (h hertzLogger) SetOutput(w io.Writer) {}
+
+// slowSQLThreshold 超过该耗时的语句记为慢查询，与 GORM 默认日志相同
+const slowSQLThreshold = 200 * time.Millisecond
+
+// gormLogger 把 GORM 的日志转到 slog：失败的语句记为 error（记录不存在除外），慢查询记为 warn，
+// level 为 logger.Info 时每条语句记为 debug
+type gormLogger struct {
+	l     *slog.Logger
+	level logger.LogLevel
+}
+
+// NewGormLogger 创建 GORM 日志，level 为 debug 时输出每条 SQL
+func NewGormLogger(l *slog.Logger, level string) logger.Interface {
+	lv := logger.Warn
+	switch ParseLevel(level) {
+	case slog.LevelDebug:
+		lv = logger.Info
+	case slog.LevelError:
+		lv = logger.Error
+	}
+	return gormLogger{l: l.With("component", "gorm"), level: lv}
+}
+
+func (g gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	g.level = level
+	return g
+}
+
+func (g gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= logger.Info {
+		g.l.InfoContext(ctx, fmt.Sprintf(msg, data...), "source", utils.FileWithLineNum())
+	}
+}
+
+func (g gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= logger.Warn {
+		g.l.WarnContext(ctx, fmt.Sprintf(msg, data...), "source", utils.FileWithLineNum())
+	}
+}
+
+func (g gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= logger.Error {
+		g.l.ErrorContext(ctx, fmt.Sprintf(msg, data...), "source", utils.FileWithLineNum())
+	}
+}
+
+func (g gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && g.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		g.l.ErrorContext(ctx, "SQL failed", "error", err, "sql", sql, "rows", rows,
+			"latency_ms", elapsed.Milliseconds(), "source", utils.FileWithLineNum())
+	case elapsed > slowSQLThreshold && g.level >= logger.Warn:
+		sql, rows := fc()
+		g.l.WarnContext(ctx, "Slow SQL", "sql", sql, "rows", rows,
+			"latency_ms", elapsed.Milliseconds(), "source", utils.FileWithLineNum())
+	case g.level >= logger.Info:
+		sql, rows := fc()
+		g.l.DebugContext(ctx, "SQL", "sql", sql, "rows", rows,
+			"latency_ms", elapsed.Milliseconds(), "source", utils.FileWithLineNum())
+	}
+}
+
+// RedisLogger 把 go-redis 的日志（如连接池拨号失败）转到 slog，记为 warn，用于 redis.SetLogger
+type RedisLogger struct {
+	l *slog.Logger
+}
+
+// NewRedisLogger 创建 go-redis 日志
+func NewRedisLogger(l *slog.Logger) RedisLogger {
+	return RedisLogger{l: l.With("component", "redis")}
+}
+
+func (r RedisLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	r.l.WarnContext(ctx, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,81 @@
+// Package logging 基于 log/slog 的结构化日志。
+// 请求 ID 保存在 context 中，使用 slog.XxxContext 记录的日志会自动带上 request_id；
+// Hertz 和 GORM 的日志也转到同一个 slog.Logger，输出格式一致。
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// RequestContext 中保存请求信息的键，由请求日志中间件和响应函数共用
+const (
+	RequestIDKey    = "request_id"
+	ActorKey        = "actor"         // 操作者，如 kiosk:front-desk
+	ErrorCodeKey    = "error_code"    // 错误响应的错误码
+	ErrorMessageKey = "error_message" // 错误响应的完整错误，含内部原因
+)
+
+// RequestIDHeader 请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// New 创建 Logger，level 为 debug、info、warn 或 error，format 为 json 或 text
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+	var h slog.Handler
+	if format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+// ParseLevel 解析日志级别，无法识别时为 info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID 把请求 ID 放入 context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 取出 context 中的请求 ID，没有时为空
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler 为带请求 ID 的 context 中记录的日志加上 request_id
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,222 @@
+package marc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bib 从 MARC21 记录中提取的书目信息
+type Bib struct {
+	ISBN        string
+	Barcode     string // 馆藏条码（852$p）
+	Title       string
+	Authors     []string
+	Publisher   string
+	PublishYear *int
+	Edition     string
+	Language    string
+	ClassNumber string // 中图法分类号（084$a）
+	Tags        []string
+	Price       *float64
+}
+
+var (
+	yearPattern  = regexp.MustCompile(`\d{4}`)
+	pricePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+// ToBib 按 MARC21 字段映射提取书目信息
+func ToBib(rec Record) Bib {
+	var bib Bib
+
+	if f, ok := rec.First("020"); ok {
+		// 020$a 可能带有限定说明，如 "9787111234567 (pbk.)"
+		if fields := strings.Fields(f.Sub("a")); len(fields) > 0 {
+			bib.ISBN = fields[0]
+		}
+		if m := pricePattern.FindString(f.Sub("c")); m != "" {
+			if p, err := strconv.ParseFloat(m, 64); err == nil {
+				bib.Price = &p
+			}
+		}
+	}
+	if f, ok := rec.First("852"); ok {
+		bib.Barcode = strings.TrimSpace(f.Sub("p"))
+	}
+	if f, ok := rec.First("245"); ok {
+		bib.Title = trimPunct(f.Sub("a"))
+	}
+	if f, ok := rec.First("100"); ok {
+		if name := trimPunct(f.Sub("a")); name != "" {
+			bib.Authors = append(bib.Authors, name)
+		}
+	}
+	for _, f := range rec.Get("700") {
+		if name := trimPunct(f.Sub("a")); name != "" {
+			bib.Authors = append(bib.Authors, name)
+		}
+	}
+	if f, ok := rec.First("250"); ok {
+		bib.Edition = trimPunct(f.Sub("a"))
+	}
+	if f, ok := publicationField(rec); ok {
+		bib.Publisher = trimPunct(f.Sub("b"))
+		if m := yearPattern.FindString(f.Sub("c")); m != "" {
+			year, _ := strconv.Atoi(m)
+			bib.PublishYear = &year
+		}
+	}
+	if f, ok := rec.First("041"); ok {
+		bib.Language = strings.TrimSpace(f.Sub("a"))
+	} else if f, ok := rec.First("008"); ok && len(f.Value) >= 38 {
+		bib.Language = strings.TrimSpace(f.Value[35:38])
+	}
+	if f, ok := rec.First("084"); ok {
+		bib.ClassNumber = strings.TrimSpace(f.Sub("a"))
+	}
+	for _, tag := range []string{"650", "653"} {
+		for _, f := range rec.Get(tag) {
+			if v := trimPunct(f.Sub("a")); v != "" {
+				bib.Tags = append(bib.Tags, v)
+			}
+		}
+	}
+	return bib
+}
+
+// Apply 将书目信息写回记录。原记录中未映射的字段与子字段原样保留，
+// 只改写与原记录提取结果不同的项，保证导入后再导出不丢失数据。
+func Apply(rec Record, bib Bib) Record {
+	out := Record{Leader: rec.Leader, Fields: make([]Field, len(rec.Fields))}
+	for i, f := range rec.Fields {
+		f.Subfields = append([]Subfield(nil), f.Subfields...)
+		out.Fields[i] = f
+	}
+
+	orig := ToBib(rec)
+	set := func(tag, code, value, original string) {
+		if value != original {
+			out.setSub(tag, code, value)
+		}
+	}
+
+	set("020", "a", bib.ISBN, orig.ISBN)
+	if bib.Price != nil && (orig.Price == nil || *orig.Price != *bib.Price) {
+		out.setSub("020", "c", fmt.Sprintf("CNY%.2f", *bib.Price))
+	}
+	if bib.Barcode != bib.ISBN {
+		set("852", "p", bib.Barcode, orig.Barcode)
+	}
+	set("245", "a", bib.Title, orig.Title)
+	if !equalStrings(bib.Authors, orig.Authors) {
+		out.setAuthors(bib.Authors)
+	}
+	set("250", "a", bib.Edition, orig.Edition)
+
+	pubTag := "260"
+	if f, ok := publicationField(out); ok {
+		pubTag = f.Tag
+	}
+	set(pubTag, "b", bib.Publisher, orig.Publisher)
+	if bib.PublishYear != nil && (orig.PublishYear == nil || *orig.PublishYear != *bib.PublishYear) {
+		out.setSub(pubTag, "c", strconv.Itoa(*bib.PublishYear))
+	}
+	set("041", "a", bib.Language, orig.Language)
+	if bib.ClassNumber != "" && bib.ClassNumber != orig.ClassNumber {
+		if _, ok := out.First("084"); !ok {
+			out.Fields = append(out.Fields, Field{Tag: "084", Subfields: []Subfield{{Code: "2", Value: "clc"}}})
+		}
+		out.setSub("084", "a", bib.ClassNumber)
+	}
+	if !equalStrings(bib.Tags, orig.Tags) {
+		out.setKeywords(bib.Tags)
+	}
+
+	out.sortFields()
+	return out
+}
+
+// publicationField 返回出版发行字段，优先 264（RDA），其次 260
+func publicationField(rec Record) (Field, bool) {
+	if f, ok := rec.First("264"); ok {
+		return f, true
+	}
+	return rec.First("260")
+}
+
+// setSub 设置第一个指定标签字段的子字段，字段不存在时新建；value 为空时不改动
+func (r *Record) setSub(tag, code, value string) {
+	if value == "" {
+		return
+	}
+	for i, f := range r.Fields {
+		if f.Tag != tag {
+			continue
+		}
+		for j, sf := range f.Subfields {
+			if sf.Code == code {
+				r.Fields[i].Subfields[j].Value = value
+				return
+			}
+		}
+		r.Fields[i].Subfields = append(r.Fields[i].Subfields, Subfield{Code: code, Value: value})
+		return
+	}
+	r.Fields = append(r.Fields, Field{Tag: tag, Ind1: " ", Ind2: " ", Subfields: []Subfield{{Code: code, Value: value}}})
+}
+
+// setAuthors 第一作者写入 100，其余写入 700
+func (r *Record) setAuthors(authors []string) {
+	if len(authors) == 0 {
+		return
+	}
+	r.setSub("100", "a", authors[0])
+	r.removeTag("700")
+	for _, name := range authors[1:] {
+		r.Fields = append(r.Fields, Field{Tag: "700", Ind1: "1", Ind2: " ", Subfields: []Subfield{{Code: "a", Value: name}}})
+	}
+}
+
+// setKeywords 重建 653 非控关键词字段。
+// 650 主题词属于规范控制数据，保持原样，已在 650 中出现的标签不再重复写入 653。
+func (r *Record) setKeywords(tags []string) {
+	subjects := make(map[string]bool)
+	for _, f := range r.Get("650") {
+		subjects[trimPunct(f.Sub("a"))] = true
+	}
+	r.removeTag("653")
+	for _, tag := range tags {
+		if !subjects[tag] {
+			r.Fields = append(r.Fields, Field{Tag: "653", Ind1: " ", Ind2: " ", Subfields: []Subfield{{Code: "a", Value: tag}}})
+		}
+	}
+}
+
+func (r *Record) removeTag(tag string) {
+	fields := r.Fields[:0]
+	for _, f := range r.Fields {
+		if f.Tag != tag {
+			fields = append(fields, f)
+		}
+	}
+	r.Fields = fields
+}
+
+// trimPunct 去掉 ISBD 规定的结尾标点（如 "书名 /"、"作者,"）
+func trimPunct(s string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(s), " /:;,.="))
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
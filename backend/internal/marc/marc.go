@@ -0,0 +1,245 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ISO 2709 分隔符
+const (
+	recordTerminator = 0x1D
+	fieldTerminator  = 0x1E
+	subfieldDelim    = 0x1F
+	leaderLen        = 24
+)
+
+// ISO 2709 目录项中字段长度占4位、头标区中记录长度占5位
+const (
+	maxFieldLen  = 9999
+	maxRecordLen = 99999
+)
+
+var ErrInvalidRecord = errors.New("invalid MARC record")
+
+// ErrTooLong 字段或记录超出 ISO 2709 能表示的长度，这样的记录只能导出为 MARCXML
+var ErrTooLong = errors.New("MARC record too long for ISO 2709")
+
+// Record MARC记录
+type Record struct {
+	Leader string  `json:"leader"`
+	Fields []Field `json:"fields"`
+}
+
+// Field MARC字段，控制字段（00X）只有 Value，数据字段有指示符和子字段
+type Field struct {
+	Tag       string     `json:"tag"`
+	Value     string     `json:"value,omitempty"`
+	Ind1      string     `json:"ind1,omitempty"`
+	Ind2      string     `json:"ind2,omitempty"`
+	Subfields []Subfield `json:"subfields,omitempty"`
+}
+
+// Subfield MARC子字段
+type Subfield struct {
+	Code  string `json:"code"`
+	Value string `json:"value"`
+}
+
+// IsControl 是否为控制字段
+func (f Field) IsControl() bool {
+	return len(f.Tag) == 3 && f.Tag[0] == '0' && f.Tag[1] == '0'
+}
+
+// Sub 返回第一个指定代码的子字段值
+func (f Field) Sub(code string) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return sf.Value
+		}
+	}
+	return ""
+}
+
+// Get 返回所有指定标签的字段
+func (r *Record) Get(tag string) []Field {
+	var out []Field
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// First 返回第一个指定标签的字段
+func (r *Record) First(tag string) (Field, bool) {
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// sortFields 按标签排序，同一标签保持原有顺序
+func (r *Record) sortFields() {
+	sort.SliceStable(r.Fields, func(i, j int) bool {
+		return r.Fields[i].Tag < r.Fields[j].Tag
+	})
+}
+
+// ReadISO2709 读取 ISO 2709 格式的 MARC21 记录，字符编码按 UTF-8 处理
+func ReadISO2709(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	var records []Record
+	for {
+		raw, err := br.ReadBytes(recordTerminator)
+		if len(bytes.TrimSpace(raw)) > 0 {
+			rec, perr := parseISO2709(bytes.TrimLeft(raw, "\r\n "))
+			if perr != nil {
+				return records, fmt.Errorf("record %d: %w", len(records)+1, perr)
+			}
+			records = append(records, rec)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+	}
+}
+
+func parseISO2709(raw []byte) (Record, error) {
+	if len(raw) < leaderLen+1 {
+		return Record{}, ErrInvalidRecord
+	}
+	rec := Record{Leader: string(raw[:leaderLen])}
+	base, err := strconv.Atoi(string(raw[12:17]))
+	if err != nil || base <= leaderLen || base > len(raw) {
+		return Record{}, fmt.Errorf("%w: bad base address", ErrInvalidRecord)
+	}
+
+	dir := raw[leaderLen : base-1]
+	if len(dir)%12 != 0 {
+		return Record{}, fmt.Errorf("%w: bad directory length", ErrInvalidRecord)
+	}
+	data := raw[base:]
+	for i := 0; i < len(dir); i += 12 {
+		entry := dir[i : i+12]
+		tag := string(entry[:3])
+		length, err1 := strconv.Atoi(string(entry[3:7]))
+		start, err2 := strconv.Atoi(string(entry[7:12]))
+		// Atoi 接受符号，"-001" 之类的负数需单独拒绝，否则切片越界
+		if err1 != nil || err2 != nil || start < 0 || length < 1 || start+length > len(data) {
+			return Record{}, fmt.Errorf("%w: bad directory entry for %s", ErrInvalidRecord, tag)
+		}
+		// 去掉字段结束符
+		content := data[start : start+length-1]
+		rec.Fields = append(rec.Fields, parseField(tag, content))
+	}
+	return rec, nil
+}
+
+func parseField(tag string, content []byte) Field {
+	f := Field{Tag: tag}
+	if f.IsControl() {
+		f.Value = string(content)
+		return f
+	}
+	if len(content) >= 2 {
+		f.Ind1, f.Ind2 = string(content[0]), string(content[1])
+		content = content[2:]
+	}
+	for _, part := range bytes.Split(content, []byte{subfieldDelim}) {
+		if len(part) == 0 {
+			continue
+		}
+		f.Subfields = append(f.Subfields, Subfield{Code: string(part[:1]), Value: string(part[1:])})
+	}
+	return f
+}
+
+// WriteISO2709 以 ISO 2709 格式写出记录，字段按标签排序。
+// 字段超过 9999 字节或记录超过 99999 字节时返回 ErrTooLong，不写出该记录。
+func WriteISO2709(w io.Writer, records []Record) error {
+	for i, rec := range records {
+		raw, err := encodeISO2709(rec)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeISO2709(rec Record) ([]byte, error) {
+	rec.sortFields()
+	var dir, data bytes.Buffer
+	for _, f := range rec.Fields {
+		if len(f.Tag) != 3 {
+			return nil, fmt.Errorf("%w: bad tag %q", ErrInvalidRecord, f.Tag)
+		}
+		var content bytes.Buffer
+		if f.IsControl() {
+			content.WriteString(f.Value)
+		} else {
+			content.WriteString(indicator(f.Ind1))
+			content.WriteString(indicator(f.Ind2))
+			for _, sf := range f.Subfields {
+				content.WriteByte(subfieldDelim)
+				content.WriteString(sf.Code)
+				content.WriteString(sf.Value)
+			}
+		}
+		content.WriteByte(fieldTerminator)
+		if content.Len() > maxFieldLen {
+			return nil, fmt.Errorf("%w: field %s is %d bytes (at most %d)", ErrTooLong, f.Tag, content.Len(), maxFieldLen)
+		}
+		fmt.Fprintf(&dir, "%3s%04d%05d", f.Tag, content.Len(), data.Len())
+		data.Write(content.Bytes())
+	}
+	dir.WriteByte(fieldTerminator)
+
+	base := leaderLen + dir.Len()
+	total := base + data.Len() + 1
+	if total > maxRecordLen {
+		return nil, fmt.Errorf("%w: record is %d bytes (at most %d)", ErrTooLong, total, maxRecordLen)
+	}
+	leader := []byte(normalizeLeader(rec.Leader))
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+
+	out := make([]byte, 0, total)
+	out = append(out, leader...)
+	out = append(out, dir.Bytes()...)
+	out = append(out, data.Bytes()...)
+	return append(out, recordTerminator), nil
+}
+
+// normalizeLeader 补全头标区，并标记字符编码为 UTF-8
+func normalizeLeader(leader string) string {
+	const def = "00000nam a2200000 a 4500"
+	if len(leader) != leaderLen {
+		return def
+	}
+	b := []byte(leader)
+	b[9] = 'a'
+	b[10], b[11] = '2', '2'
+	copy(b[20:24], "4500")
+	return string(b)
+}
+
+func indicator(s string) string {
+	if s == "" {
+		return " "
+	}
+	return s[:1]
+}
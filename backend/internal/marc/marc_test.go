@@ -0,0 +1,185 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleRecord 字段已按标签排序，含中文和重复标签
+func sampleRecord() Record {
+	return Record{
+		Leader: "00000nam a2200000 a 4500",
+		Fields: []Field{
+			{Tag: "001", Value: "42"},
+			{Tag: "008", Value: "260101s2026    cc            000 0 chi d"},
+			{Tag: "020", Ind1: " ", Ind2: " ", Subfields: []Subfield{{Code: "a", Value: "9787111234567"}, {Code: "c", Value: "CNY45.00"}}},
+			{Tag: "245", Ind1: "1", Ind2: "0", Subfields: []Subfield{{Code: "a", Value: "数据结构"}, {Code: "c", Value: "严蔚敏著"}}},
+			{Tag: "650", Ind1: " ", Ind2: "4", Subfields: []Subfield{{Code: "a", Value: "算法"}}},
+			{Tag: "650", Ind1: " ", Ind2: "4", Subfields: []Subfield{{Code: "a", Value: "程序设计"}}},
+			{Tag: "852", Ind1: " ", Ind2: " ", Subfields: []Subfield{{Code: "p", Value: "LIB00000001"}}},
+		},
+	}
+}
+
+func TestISO2709RoundTrip(t *testing.T) {
+	want := []Record{sampleRecord(), {Leader: "00000cam a2200000 a 4500", Fields: []Field{{Tag: "001", Value: "43"}}}}
+
+	var buf bytes.Buffer
+	if err := WriteISO2709(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadISO2709(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		// 头标区中的长度和基地址在写出时重新计算，其余位置不变
+		if got[i].Leader[5:12] != want[i].Leader[5:12] || got[i].Leader[17:] != want[i].Leader[17:] {
+			t.Errorf("record %d leader = %q, want %q apart from lengths", i, got[i].Leader, want[i].Leader)
+		}
+		if !reflect.DeepEqual(got[i].Fields, want[i].Fields) {
+			t.Errorf("record %d fields:\n got %+v\nwant %+v", i, got[i].Fields, want[i].Fields)
+		}
+	}
+
+	// 头标区中的记录长度与实际一致
+	var one bytes.Buffer
+	WriteISO2709(&one, want[:1])
+	raw := one.Bytes()
+	if leader := string(raw[:5]); leader != fmt.Sprintf("%05d", len(raw)) {
+		t.Errorf("record length in leader = %s, want %d", leader, len(raw))
+	}
+}
+
+func TestWriteISO2709Sorts(t *testing.T) {
+	rec := sampleRecord()
+	rec.Fields[0], rec.Fields[6] = rec.Fields[6], rec.Fields[0]
+	var buf bytes.Buffer
+	if err := WriteISO2709(&buf, []Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadISO2709(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got[0].Fields, sampleRecord().Fields) {
+		t.Errorf("fields not sorted by tag: %+v", got[0].Fields)
+	}
+}
+
+func TestWriteISO2709TooLong(t *testing.T) {
+	long := strings.Repeat("x", maxFieldLen)
+	many := make([]Field, 0, 20)
+	for i := 0; i < 20; i++ {
+		many = append(many, Field{Tag: "650", Ind1: " ", Ind2: "4", Subfields: []Subfield{{Code: "a", Value: long[:maxFieldLen-10]}}})
+	}
+	tests := []struct {
+		name string
+		rec  Record
+		err  error
+	}{
+		{"field", Record{Fields: []Field{{Tag: "001", Value: long}}}, ErrTooLong},
+		{"record", Record{Fields: many}, ErrTooLong},
+		{"tag", Record{Fields: []Field{{Tag: "6500", Value: "x"}}}, ErrInvalidRecord},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteISO2709(&buf, []Record{tt.rec}); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: wrote %d bytes of an invalid record", tt.name, buf.Len())
+		}
+	}
+
+	// 刚好不超出上限的字段可以写出
+	var buf bytes.Buffer
+	if err := WriteISO2709(&buf, []Record{{Fields: []Field{{Tag: "001", Value: long[:maxFieldLen-1]}}}}); err != nil {
+		t.Errorf("field of %d bytes: %v", maxFieldLen, err)
+	}
+}
+
+func TestReadISO2709Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	WriteISO2709(&buf, []Record{sampleRecord()})
+	valid := buf.Bytes()
+
+	// 目录从头标区之后开始，每项12字节：标签3位、长度4位、起始位置5位
+	withEntry := func(entry string) []byte {
+		raw := append([]byte(nil), valid...)
+		copy(raw[leaderLen:], entry)
+		return raw
+	}
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"short", []byte("00010nam")},
+		{"bad base address", append([]byte("00000nam a2299999 a 4500"), recordTerminator)},
+		{"negative start", withEntry("0010003-0001")},
+		{"negative length", withEntry("001-00100000")},
+		{"zero length", withEntry("001000000000")},
+		{"past end", withEntry("001999900000")},
+	}
+	for _, tt := range tests {
+		if _, err := ReadISO2709(bytes.NewReader(tt.raw)); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("%s: err = %v, want ErrInvalidRecord", tt.name, err)
+		}
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	want := []Record{sampleRecord()}
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	if !IsXML(buf.Bytes()) {
+		t.Error("IsXML(WriteXML output) = false")
+	}
+	got, err := ReadXML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+	}
+
+	// 单个 <record> 根元素，字段按标签排序
+	single := `<record xmlns="http://www.loc.gov/MARC21/slim"><leader>00000nam a2200000 a 4500</leader>
+<datafield tag="245" ind1="1" ind2="0"><subfield code="a">Go</subfield></datafield>
+<controlfield tag="001">7</controlfield></record>`
+	got, err = ReadXML(strings.NewReader(single))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Fields) != 2 || got[0].Fields[0].Tag != "001" || got[0].Fields[1].Sub("a") != "Go" {
+		t.Errorf("ReadXML(single record) = %+v", got)
+	}
+}
+
+func TestISO2709ToXML(t *testing.T) {
+	var iso, xml bytes.Buffer
+	WriteISO2709(&iso, []Record{sampleRecord()})
+	fromISO, err := ReadISO2709(&iso)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteXML(&xml, fromISO); err != nil {
+		t.Fatal(err)
+	}
+	fromXML, err := ReadXML(&xml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromXML, fromISO) {
+		t.Errorf("ISO 2709 -> MARCXML changed the record:\n got %+v\nwant %+v", fromXML, fromISO)
+	}
+}
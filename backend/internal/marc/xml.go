@@ -0,0 +1,114 @@
+package marc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+const marcXMLNamespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlCollection struct {
+	XMLName xml.Name    `xml:"collection"`
+	Xmlns   string      `xml:"xmlns,attr,omitempty"`
+	Records []xmlRecord `xml:"record"`
+}
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// IsXML 判断数据是否为 MARCXML
+func IsXML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '<'
+}
+
+// ReadXML 读取 MARCXML，支持 <collection> 或单个 <record> 根元素
+func ReadXML(r io.Reader) ([]Record, error) {
+	dec := xml.NewDecoder(r)
+	var records []Record
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		var xr xmlRecord
+		if err := dec.DecodeElement(&xr, &start); err != nil {
+			return records, err
+		}
+		records = append(records, fromXMLRecord(xr))
+	}
+}
+
+func fromXMLRecord(xr xmlRecord) Record {
+	rec := Record{Leader: xr.Leader}
+	for _, cf := range xr.ControlFields {
+		rec.Fields = append(rec.Fields, Field{Tag: cf.Tag, Value: cf.Value})
+	}
+	for _, df := range xr.DataFields {
+		f := Field{Tag: df.Tag, Ind1: df.Ind1, Ind2: df.Ind2}
+		for _, sf := range df.Subfields {
+			f.Subfields = append(f.Subfields, Subfield{Code: sf.Code, Value: sf.Value})
+		}
+		rec.Fields = append(rec.Fields, f)
+	}
+	rec.sortFields()
+	return rec
+}
+
+// WriteXML 以 MARCXML 格式写出记录
+func WriteXML(w io.Writer, records []Record) error {
+	coll := xmlCollection{Xmlns: marcXMLNamespace}
+	for _, rec := range records {
+		rec.sortFields()
+		xr := xmlRecord{Leader: normalizeLeader(rec.Leader)}
+		for _, f := range rec.Fields {
+			if f.IsControl() {
+				xr.ControlFields = append(xr.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+				continue
+			}
+			df := xmlDataField{Tag: f.Tag, Ind1: indicator(f.Ind1), Ind2: indicator(f.Ind2)}
+			for _, sf := range f.Subfields {
+				df.Subfields = append(df.Subfields, xmlSubfield{Code: sf.Code, Value: sf.Value})
+			}
+			xr.DataFields = append(xr.DataFields, df)
+		}
+		coll.Records = append(coll.Records, xr)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(coll)
+}
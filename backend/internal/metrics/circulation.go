@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+
+	"booksystem/internal/service"
+)
+
+// collectTimeout 每次抓取时查询数据库和 Redis 的超时时间
+const collectTimeout = 5 * time.Second
+
+var (
+	quantityDesc = prometheus.NewDesc(namespace+"_books_quantity", "Total quantity of all books.", nil, nil)
+	inStockDesc  = prometheus.NewDesc(namespace+"_books_in_stock", "Total in-stock count of all books.", nil, nil)
+	loansDesc    = prometheus.NewDesc(namespace+"_loans_outstanding", "Books currently lent out.", nil, nil)
+	overdueDesc  = prometheus.NewDesc(namespace+"_loans_overdue", "Books lent out for longer than the loan period.", nil, nil)
+	sessionsDesc = prometheus.NewDesc(namespace+"_sessions_active", "Active Redis sessions: kiosks with a borrow or return user set, and unexpired QR code sessions.", []string{"kind"}, nil)
+)
+
+// circulationCollector 抓取时从数据库和 Redis 读取库存、借阅和会话数量
+type circulationCollector struct {
+	db         *gorm.DB
+	redis      *service.RedisService
+	loanPeriod time.Duration
+}
+
+// RegisterCirculation 注册库存、借阅和会话指标。loanPeriod 为借阅期限，为 0 时不输出逾期数；redis 为空时不输出会话数
+func RegisterCirculation(db *gorm.DB, redis *service.RedisService, loanPeriod time.Duration) error {
+	return Registry.Register(&circulationCollector{db: db, redis: redis, loanPeriod: loanPeriod})
+}
+
+func (c *circulationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- quantityDesc
+	ch <- inStockDesc
+	ch <- loansDesc
+	ch <- overdueDesc
+	ch <- sessionsDesc
+}
+
+// Collect 查询失败的指标输出为错误，其余指标照常输出
+func (c *circulationCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	tx := c.db.WithContext(ctx)
+
+	var stock struct {
+		Quantity int64
+		InStock  int64
+	}
+	if err := tx.Table("books").Select("COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(in_stock), 0) AS in_stock").Scan(&stock).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(quantityDesc, err)
+		ch <- prometheus.NewInvalidMetric(inStockDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(quantityDesc, prometheus.GaugeValue, float64(stock.Quantity))
+		ch <- prometheus.MustNewConstMetric(inStockDesc, prometheus.GaugeValue, float64(stock.InStock))
+	}
+
+	// 归还时借阅明细被删除，借阅中的记录下剩余的明细都是未归还的
+	loans := func() *gorm.DB {
+		return tx.Table("borrow_details").
+			Joins("JOIN borrow_records ON borrow_records.id = borrow_details.borrow_record_id").
+			Where("borrow_records.status = 1")
+	}
+	var outstanding int64
+	if err := loans().Count(&outstanding).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(loansDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(loansDesc, prometheus.GaugeValue, float64(outstanding))
+	}
+	if c.loanPeriod > 0 {
+		var overdue int64
+		if err := loans().Where("borrow_records.borrow_time < ?", time.Now().Add(-c.loanPeriod)).Count(&overdue).Error; err != nil {
+			ch <- prometheus.NewInvalidMetric(overdueDesc, err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(overdueDesc, prometheus.GaugeValue, float64(overdue))
+		}
+	}
+
+	if c.redis != nil {
+		counts, err := c.redis.CountSessions(ctx)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(sessionsDesc, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(counts.Borrow), "borrow")
+		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(counts.Return), "return")
+		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(counts.Kiosk), "kiosk")
+	}
+}
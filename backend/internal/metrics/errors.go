@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// InstrumentDB 在 GORM 的各类操作后统计失败的语句。
+// Scan/Rows 在回调结束后才逐行读取结果，读取过程中的错误（如 SQLite 读到一半被锁）不在统计内
+func InstrumentDB(database *gorm.DB) error {
+	count := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				dbErrors.WithLabelValues(operation).Inc()
+			}
+		}
+	}
+	cb := database.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register("metrics:create", count("create")),
+		cb.Query().After("gorm:query").Register("metrics:query", count("query")),
+		cb.Update().After("gorm:update").Register("metrics:update", count("update")),
+		cb.Delete().After("gorm:delete").Register("metrics:delete", count("delete")),
+		cb.Row().After("gorm:row").Register("metrics:row", count("row")),
+		cb.Raw().After("gorm:raw").Register("metrics:raw", count("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RedisHook 统计失败的 Redis 命令，键不存在（redis.Nil）不算失败，连接失败记为 dial
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			redisErrors.WithLabelValues("dial").Inc()
+		}
+		return conn, err
+	}
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			redisErrors.WithLabelValues(cmd.Name()).Inc()
+		}
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+				redisErrors.WithLabelValues(cmd.Name()).Inc()
+			}
+		}
+		return err
+	}
+}
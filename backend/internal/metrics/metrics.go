@@ -0,0 +1,108 @@
+// Package metrics Prometheus 指标：HTTP 请求数和耗时、借还书数量、库存和借阅状态、Redis/数据库错误。
+// 指标注册在包内的 Registry 中，由 Handler 以 Prometheus 文本格式输出。
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "booksystem"
+
+// Registry 本服务的指标，包括 Go 运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// 桶边界包含 0.5 秒，用于统计扫码接口响应时间 < 500ms 的比例
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "route"})
+
+	// Borrows 借出的册数
+	Borrows = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "borrows_total",
+		Help:      "Books lent out.",
+	})
+
+	// Returns 归还的册数
+	Returns = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "returns_total",
+		Help:      "Books returned.",
+	})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "Failed database statements by operation, not counting record not found.",
+	}, []string{"operation"})
+
+	redisErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_errors_total",
+		Help:      "Failed Redis commands by command, not counting missing keys.",
+	}, []string{"command"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, Borrows, Returns, dbErrors, redisErrors,
+	)
+}
+
+// Middleware 记录每个请求的数量和耗时。route 使用路由模板（如 /api/v1/books/:id），未匹配路由的请求记为 unmatched，避免标签数量随路径无限增长。
+// 流式响应（SSE，如 /api/v1/kiosk/events）的耗时是连接保持的时长而不是响应时间，只计数不记录耗时
+func Middleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		start := time.Now()
+		c.Next(ctx)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := string(c.Method())
+		if !streaming(c) {
+			httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		}
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Response.StatusCode())).Inc()
+	}
+}
+
+// streaming 响应是否为 SSE 事件流
+func streaming(c *app.RequestContext) bool {
+	return bytes.HasPrefix(c.Response.Header.ContentType(), []byte("text/event-stream"))
+}
+
+// Handler 输出 Registry 中的指标，个别指标读取失败时仍输出其余指标
+func Handler() app.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	return func(ctx context.Context, c *app.RequestContext) {
+		req, err := adaptor.GetCompatRequest(&c.Request)
+		if err != nil {
+			c.AbortWithStatus(500)
+			return
+		}
+		h.ServeHTTP(adaptor.GetCompatResponseWriter(&c.Response), req.WithContext(ctx))
+	}
+}
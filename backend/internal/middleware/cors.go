@@ -6,12 +6,30 @@ import (
 	"github.com/cloudwego/hertz/pkg/app"
 )
 
-// CORS 跨域中间件
-func CORS() app.HandlerFunc {
+// CORS 跨域中间件，origins 为允许的来源，包含 * 时允许任意来源
+func CORS(origins []string) app.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
 	return func(ctx context.Context, c *app.RequestContext) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		if allowAll {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			// 只回显允许的来源，响应随 Origin 不同而不同
+			c.Header("Vary", "Origin")
+			if origin := string(c.GetHeader("Origin")); allowed[origin] {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Kiosk-ID, X-Kiosk-Session, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if string(c.Method()) == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"booksystem/internal/i18n"
+)
+
+// Language 确定请求的语言，依次取 lang 查询参数、lang Cookie（用户偏好）和 Accept-Language 请求头，
+// 都没有或不支持时为简体中文。语言保存在 RequestContext 中供响应函数使用，并通过 Content-Language 响应头返回
+func Language() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		lang, ok := i18n.Parse(c.Query(i18n.LangKey))
+		if !ok {
+			lang, ok = i18n.Parse(string(c.Cookie(i18n.LangKey)))
+		}
+		if !ok {
+			lang = i18n.Negotiate(string(c.GetHeader("Accept-Language")))
+		}
+		c.Set(i18n.LangKey, lang)
+		c.Header("Content-Language", string(lang))
+		c.Next(ctx)
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"booksystem/internal/logging"
+)
+
+// maxRequestIDLen 客户端传入的请求 ID 的最大长度，超过或含有其他字符时重新生成
+const maxRequestIDLen = 128
+
+// probeRoutes 健康检查和指标接口，被频繁调用，请求日志只在 debug 级别输出
+var probeRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// RequestLog 请求日志中间件。沿用请求头 X-Request-ID 中的请求 ID，没有时生成一个，
+// 写入响应头并放入 context，处理结束后记录方法、路由、状态、耗时和操作者。
+// HTTP 5xx 记为 error，4xx 记为 warn，错误响应附带错误码和完整的错误信息
+func RequestLog(logger *slog.Logger) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		start := time.Now()
+		id := string(c.GetHeader(logging.RequestIDHeader))
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(logging.RequestIDKey, id)
+		c.Header(logging.RequestIDHeader, id)
+		ctx = logging.WithRequestID(ctx, id)
+
+		c.Next(ctx)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Response.StatusCode()
+		attrs := []slog.Attr{
+			slog.String("method", string(c.Method())),
+			slog.String("route", route),
+			slog.String("path", string(c.Path())),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if actor := requestActor(c); actor != "" {
+			attrs = append(attrs, slog.String("actor", actor))
+		}
+
+		if code := c.GetString(logging.ErrorCodeKey); code != "" {
+			attrs = append(attrs,
+				slog.String("code", code),
+				slog.String("error", c.GetString(logging.ErrorMessageKey)))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case probeRoutes[route]:
+			level = slog.LevelDebug
+		}
+		logger.LogAttrs(ctx, level, "HTTP request", attrs...)
+	}
+}
+
+// requestActor 操作者：处理函数设置的 actor，否则为请求头中的自助借还终端；会话令牌不写入日志
+func requestActor(c *app.RequestContext) string {
+	if actor := c.GetString(logging.ActorKey); actor != "" {
+		return actor
+	}
+	if kiosk := string(c.GetHeader("X-Kiosk-ID")); validRequestID(kiosk) {
+		return "kiosk:" + kiosk
+	}
+	if len(c.GetHeader("X-Kiosk-Session")) > 0 {
+		return "kiosk-session"
+	}
+	return ""
+}
+
+// validRequestID 请求 ID（以及写入日志的终端标识）只允许字母、数字和 - _ . :，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成 16 字节随机数的十六进制字符串
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,220 @@
+// Package migrate 版本化的数据库迁移：按版本号依次执行 up 或回滚 down，已执行的版本记录在 schema_version 表中
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个迁移版本。Up 和 Down 在同一个事务中执行并写入/删除版本记录；
+// MySQL 的 DDL 会隐式提交，迁移中途失败时需按错误信息手工处理后再重试。
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // 为空表示不支持回滚
+}
+
+// SchemaVersion 已执行的迁移版本
+type SchemaVersion struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(100);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 版本表名
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Status 迁移的执行状态。Unknown 表示数据库中有记录但程序中没有该版本（数据库由更新的程序迁移过）。
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// Migrator 执行一组迁移
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New 创建 Migrator，migrations 的版本号必须为正数且不重复
+func New(db *gorm.DB, migrations []Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, mig := range sorted {
+		if mig.Version <= 0 {
+			return nil, fmt.Errorf("migrate: invalid version %d (%s)", mig.Version, mig.Name)
+		}
+		if i > 0 && sorted[i-1].Version == mig.Version {
+			return nil, fmt.Errorf("migrate: duplicate version %d", mig.Version)
+		}
+		if mig.Up == nil {
+			return nil, fmt.Errorf("migrate: %s has no up function", label(mig))
+		}
+	}
+	return &Migrator{db: db, migrations: sorted}, nil
+}
+
+// Up 按版本号顺序执行尚未执行的迁移，直到 target（含）；target 为 0 时执行全部。返回本次执行的迁移。
+func (m *Migrator) Up(target int64) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if latest := m.latest(); latest > 0 {
+		for version := range applied {
+			if version > latest {
+				return nil, fmt.Errorf("migrate: database is at version %d, newer than the latest known version %d", version, latest)
+			}
+		}
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if target > 0 && mig.Version > target {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migrate: up %s: %w", label(mig), err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个迁移。返回本次回滚的迁移。
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("migrate: %s cannot be rolled back", label(mig))
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaVersion{}, mig.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migrate: down %s: %w", label(mig), err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Status 返回全部迁移的执行状态，按版本号排序
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+// Inspect 与 Status 相同，但只读取数据库：版本表不存在时返回错误而不创建，供就绪检查等频繁调用的场景使用
+func (m *Migrator) Inspect() ([]Status, error) {
+	if !m.db.Migrator().HasTable(&SchemaVersion{}) {
+		return nil, fmt.Errorf("migrate: schema_version table does not exist")
+	}
+	applied, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+// status 对照已执行的版本生成执行状态，会从 applied 中删除已知的版本
+func (m *Migrator) status(applied map[int64]SchemaVersion) []Status {
+	list := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if v, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &v.AppliedAt
+			delete(applied, mig.Version)
+		}
+		list = append(list, s)
+	}
+	for _, v := range applied {
+		appliedAt := v.AppliedAt
+		list = append(list, Status{Version: v.Version, Name: v.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Pending 返回尚未执行的迁移数
+func (m *Migrator) Pending() (int, error) {
+	list, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range list {
+		if !s.Applied {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// applied 读取已执行的版本，版本表不存在时先创建
+func (m *Migrator) applied() (map[int64]SchemaVersion, error) {
+	if err := m.db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, fmt.Errorf("migrate: create schema_version table: %w", err)
+	}
+	return m.read()
+}
+
+// read 读取版本表中已执行的版本
+func (m *Migrator) read() (map[int64]SchemaVersion, error) {
+	var versions []SchemaVersion
+	if err := m.db.Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("migrate: read schema_version: %w", err)
+	}
+	applied := make(map[int64]SchemaVersion, len(versions))
+	for _, v := range versions {
+		applied[v.Version] = v
+	}
+	return applied, nil
+}
+
+func (m *Migrator) latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// label 迁移的显示名称，如 0002_foreign_keys
+func label(mig Migration) string {
+	return fmt.Sprintf("%04d_%s", mig.Version, mig.Name)
+}
@@ -0,0 +1,211 @@
+package migrate
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// table 创建/删除一张表的迁移
+func table(version int64, name string) Migration {
+	return Migration{
+		Version: version,
+		Name:    "create_" + name,
+		Up:      func(tx *gorm.DB) error { return tx.Exec("CREATE TABLE " + name + " (id INTEGER PRIMARY KEY)").Error },
+		Down:    func(tx *gorm.DB) error { return tx.Exec("DROP TABLE " + name).Error },
+	}
+}
+
+func versions(migrations []Migration) []int64 {
+	out := []int64{}
+	for _, mig := range migrations {
+		out = append(out, mig.Version)
+	}
+	return out
+}
+
+func TestNew(t *testing.T) {
+	noop := func(tx *gorm.DB) error { return nil }
+	tests := []struct {
+		name       string
+		migrations []Migration
+		err        string
+	}{
+		{"valid", []Migration{table(2, "b"), table(1, "a")}, ""},
+		{"zero version", []Migration{{Version: 0, Name: "x", Up: noop}}, "invalid version"},
+		{"duplicate", []Migration{table(1, "a"), table(1, "b")}, "duplicate version 1"},
+		{"no up", []Migration{{Version: 1, Name: "x"}}, "0001_x has no up function"},
+	}
+	for _, tt := range tests {
+		_, err := New(openDB(t), tt.migrations)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: New() error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	conn := openDB(t)
+	m, err := New(conn, []Migration{table(3, "c"), table(1, "a"), table(2, "b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		run     func() ([]Migration, error)
+		done    []int64
+		tables  []string
+		pending int
+	}{
+		{"up to 2", func() ([]Migration, error) { return m.Up(2) }, []int64{1, 2}, []string{"a", "b"}, 1},
+		{"up again", func() ([]Migration, error) { return m.Up(2) }, []int64{}, []string{"a", "b"}, 1},
+		{"up all", func() ([]Migration, error) { return m.Up(0) }, []int64{3}, []string{"a", "b", "c"}, 0},
+		{"down 2", func() ([]Migration, error) { return m.Down(2) }, []int64{3, 2}, []string{"a"}, 2},
+		{"down more than applied", func() ([]Migration, error) { return m.Down(5) }, []int64{1}, nil, 3},
+		{"down nothing", func() ([]Migration, error) { return m.Down(1) }, []int64{}, nil, 3},
+	}
+	for _, step := range steps {
+		done, err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := versions(done); !reflect.DeepEqual(got, step.done) {
+			t.Errorf("%s: ran %v, want %v", step.name, got, step.done)
+		}
+		for _, name := range []string{"a", "b", "c"} {
+			want := false
+			for _, n := range step.tables {
+				want = want || n == name
+			}
+			if got := conn.Migrator().HasTable(name); got != want {
+				t.Errorf("%s: table %s exists = %v, want %v", step.name, name, got, want)
+			}
+		}
+		if pending, err := m.Pending(); err != nil || pending != step.pending {
+			t.Errorf("%s: Pending() = %d, %v; want %d", step.name, pending, err, step.pending)
+		}
+	}
+}
+
+func TestUpFailure(t *testing.T) {
+	conn := openDB(t)
+	failing := Migration{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE TABLE partial (id INTEGER PRIMARY KEY)").Error; err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}
+	m, err := New(conn, []Migration{table(1, "a"), failing, table(3, "c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := m.Up(0)
+	if err == nil || !strings.Contains(err.Error(), "up 0002_broken: boom") {
+		t.Errorf("Up(0) error = %v, want it to name 0002_broken", err)
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("Up(0) ran %v, want [1]", got)
+	}
+	// 失败的迁移在事务中回滚，后面的迁移不执行
+	if conn.Migrator().HasTable("partial") || conn.Migrator().HasTable("c") {
+		t.Error("failed migration left tables behind or later migrations ran")
+	}
+	if pending, _ := m.Pending(); pending != 2 {
+		t.Errorf("Pending() = %d, want 2", pending)
+	}
+}
+
+func TestDownIrreversible(t *testing.T) {
+	conn := openDB(t)
+	irreversible := table(2, "b")
+	irreversible.Down = nil
+	m, err := New(conn, []Migration{table(1, "a"), irreversible})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Down(2)
+	if err == nil || !strings.Contains(err.Error(), "0002_create_b cannot be rolled back") || len(done) != 0 {
+		t.Errorf("Down(2) = %v, %v; want an error before rolling anything back", versions(done), err)
+	}
+	if !conn.Migrator().HasTable("a") {
+		t.Error("table a dropped after an irreversible migration")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	conn := openDB(t)
+	m, err := New(conn, []Migration{table(1, "a"), table(2, "b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inspect 不创建版本表
+	if _, err := m.Inspect(); err == nil {
+		t.Error("Inspect() on an empty database succeeded")
+	}
+	if conn.Migrator().HasTable(&SchemaVersion{}) {
+		t.Error("Inspect() created the schema_version table")
+	}
+
+	if _, err := m.Up(1); err != nil {
+		t.Fatal(err)
+	}
+	// 数据库由更新的程序迁移过
+	if err := conn.Create(&SchemaVersion{Version: 7, Name: "future"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, read := range []func() ([]Status, error){m.Status, m.Inspect} {
+		list, err := read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		type row struct {
+			Version          int64
+			Applied, Unknown bool
+		}
+		var got []row
+		for _, s := range list {
+			got = append(got, row{s.Version, s.Applied, s.Unknown})
+			if s.Applied != (s.AppliedAt != nil) {
+				t.Errorf("version %d: applied %v but applied at %v", s.Version, s.Applied, s.AppliedAt)
+			}
+		}
+		want := []row{{1, true, false}, {2, false, false}, {7, true, true}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("status = %+v, want %+v", got, want)
+		}
+	}
+
+	if _, err := m.Up(0); err == nil || !strings.Contains(err.Error(), "newer than the latest known version 2") {
+		t.Errorf("Up(0) on a newer database: %v", err)
+	}
+	if conn.Migrator().HasTable("b") {
+		t.Error("Up(0) ran migrations on a newer database")
+	}
+}
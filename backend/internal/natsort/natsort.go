@@ -0,0 +1,78 @@
+// Package natsort 自然排序：名称中的数字按数值比较，"第2层" 排在 "第10层" 之前
+package natsort
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Compare 按自然顺序比较 a 和 b，返回 -1、0 或 1。
+// 连续的 ASCII 数字视为一个整数比较（忽略前导零，数值相同时位数少的在前），其余字符按 Unicode 码点比较。
+func Compare(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			da, db := digitRun(a), digitRun(b)
+			if c := compareNumbers(a[:da], b[:db]); c != 0 {
+				return c
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// Less 报告 a 是否按自然顺序排在 b 之前
+func Less(a, b string) bool {
+	return Compare(a, b) < 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digitRun 返回 s 开头连续数字的长度
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// compareNumbers 比较两个数字串的数值，数值相同时前导零少的在前
+func compareNumbers(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(ta) != len(tb) {
+		if len(ta) < len(tb) {
+			return -1
+		}
+		return 1
+	}
+	if c := strings.Compare(ta, tb); c != 0 {
+		return c
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
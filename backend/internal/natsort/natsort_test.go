@@ -0,0 +1,52 @@
+package natsort
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "a", -1},
+		{"a", "", 1},
+		{"a2", "a10", -1},
+		{"a10", "a2", 1},
+		{"第2层", "第10层", -1},
+		{"A区", "B区", -1},
+		{"层1", "层1", 0},
+		{"2", "02", -1}, // 数值相同时前导零少的在前
+		{"007", "7", 1},
+		{"0", "00", -1},
+		{"x01y", "x1z", 1}, // 前导零在当前数字段就决定顺序
+		{"99999999999999999999", "100000000000000000000", -1}, // 超出 int64 仍按数值比较
+		{"1a", "a", -1},         // 数字的码点小于字母
+		{"file", "file1", -1},   // 前缀在前
+		{"层10-2", "层10-10", -1}, // 多段数字
+		{"B", "a", -1},          // 区分大小写，按码点比较
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+		if got := Less(tt.a, tt.b); got != (tt.want < 0) {
+			t.Errorf("Less(%q, %q) = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	names := []string{"第10层", "第1层", "第2层", "第02层", "顶层", "第1层B", "第1层A"}
+	sort.Slice(names, func(i, j int) bool { return Less(names[i], names[j]) })
+	want := []string{"第1层", "第1层A", "第1层B", "第2层", "第02层", "第10层", "顶层"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sorted = %q, want %q", names, want)
+	}
+}
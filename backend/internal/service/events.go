@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// KioskEventChannel 终端事件的发布订阅频道，kiosk:events:{mode}:{kiosk}
+	KioskEventChannel = "kiosk:events"
+
+	// 事件类型
+	KioskEventState       = "state" // 订阅时推送的当前借阅/归还列表
+	KioskEventUser        = "user"  // 设置了借阅/归还用户
+	KioskEventBookAdded   = "book_added"
+	KioskEventBookRemoved = "book_removed"
+	KioskEventCompleted   = "completed" // 完成借阅/归还
+	KioskEventExpired     = "expired"   // 扫码会话已失效
+)
+
+// KioskEvent 推送给终端和读者手机的事件
+type KioskEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+	Time time.Time   `json:"time"`
+}
+
+// KioskSubscription 终端事件订阅
+type KioskSubscription struct {
+	pubsub *redis.PubSub
+	events chan *KioskEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Events 返回事件通道，订阅关闭后通道关闭
+func (s *KioskSubscription) Events() <-chan *KioskEvent {
+	return s.events
+}
+
+// Close 取消订阅
+func (s *KioskSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
+
+func kioskEventChannel(kiosk, mode string) string {
+	return KioskEventChannel + ":" + mode + ":" + kiosk
+}
+
+// PublishKioskEvent 向终端的订阅者发布事件，没有订阅者时事件被丢弃
+func (r *RedisService) PublishKioskEvent(ctx context.Context, kiosk, mode, eventType string, data interface{}) error {
+	payload, err := json.Marshal(&KioskEvent{Type: eventType, Data: data, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, kioskEventChannel(kiosk, mode), payload).Err()
+}
+
+// SubscribeKioskEvents 订阅终端事件。返回时订阅已生效，之后发布的事件都能收到。
+func (r *RedisService) SubscribeKioskEvents(ctx context.Context, kiosk, mode string) (*KioskSubscription, error) {
+	pubsub := r.client.Subscribe(ctx, kioskEventChannel(kiosk, mode))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	sub := &KioskSubscription{pubsub: pubsub, events: make(chan *KioskEvent), done: make(chan struct{})}
+	go func() {
+		defer close(sub.events)
+		for msg := range pubsub.Channel() {
+			var event KioskEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			select {
+			case sub.events <- &event:
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
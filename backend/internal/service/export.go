@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// 导出格式
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// MaxXLSXRows XLSX 导出的数据行数上限（不含表头）
+const MaxXLSXRows = 100000
+
+// ErrTooManyRows XLSX 导出的行数超出 MaxXLSXRows
+var ErrTooManyRows = errors.New("too many rows for xlsx export")
+
+// TableWriter 逐行写出表格数据。CSV 每行写出后立即流向客户端；
+// XLSX 是 zip 包，行先由 StreamWriter 缓存（超出内存阈值后写入临时文件），Close 时才整体写出，
+// 因此 XLSX 导出不是流式的，行数限制为 MaxXLSXRows，更大的数据量请导出 CSV
+type TableWriter interface {
+	WriteRow(row []string) error
+	Close() error
+}
+
+// NewTableWriter 根据导出格式创建表格写入器
+func NewTableWriter(w io.Writer, format string) (TableWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVWriter(w)
+	case ExportFormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ExportContentType 返回导出格式对应的Content-Type
+func ExportContentType(format string) string {
+	if format == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	// 写入UTF-8 BOM，避免Excel打开中文乱码
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteRow(row []string) error {
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	// 每行刷新，保证数据持续流向客户端
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type xlsxWriter struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	// StreamWriter 超出内存阈值后会写入临时文件，不会将全部行保存在内存中
+	sw, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &xlsxWriter{out: w, file: file, sw: sw}, nil
+}
+
+func (x *xlsxWriter) WriteRow(row []string) error {
+	// 第一行为表头
+	if x.row > MaxXLSXRows {
+		return ErrTooManyRows
+	}
+	x.row++
+	cells := make([]interface{}, len(row))
+	for i, v := range row {
+		cells[i] = v
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sw.SetRow(cell, cells)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.out)
+	return err
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Redis键前缀
+	KioskSessionKey = "kiosk:session" // 扫码会话，kiosk:session:{token}
+	KioskCurrentKey = "kiosk:current" // 终端当前的会话，kiosk:current:{mode}:{kiosk}
+
+	// 会话类型
+	KioskModeBorrow = "borrow"
+	KioskModeReturn = "return"
+)
+
+// KioskSession 借阅终端的扫码会话。终端展示包含会话地址的二维码，
+// 读者用手机扫码后，通过会话令牌操作该终端的借阅/归还列表。
+type KioskSession struct {
+	Token     string    `json:"token"`
+	Kiosk     string    `json:"kiosk"`
+	Mode      string    `json:"mode"`
+	URL       string    `json:"url"` // 二维码中编码的手机端页面地址
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateKioskSession 为终端创建新会话，终端之前的会话立即失效。
+// urlPrefix 为手机端页面地址前缀，会话地址为 urlPrefix + token。
+func (r *RedisService) CreateKioskSession(ctx context.Context, kiosk, mode, urlPrefix string) (*KioskSession, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &KioskSession{
+		Token:     token,
+		Kiosk:     kiosk,
+		Mode:      mode,
+		URL:       urlPrefix + token,
+		CreatedAt: now,
+		ExpiresAt: now.Add(r.kioskTTL),
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	currentKey := KioskCurrentKey + ":" + mode + ":" + kiosk
+	old, err := r.client.Get(ctx, currentKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	pipe := r.client.TxPipeline()
+	if old != "" {
+		pipe.Del(ctx, KioskSessionKey+":"+old)
+	}
+	pipe.Set(ctx, KioskSessionKey+":"+token, data, r.kioskTTL)
+	pipe.Set(ctx, currentKey, token, r.kioskTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetKioskSession 查询会话并顺延有效期，会话不存在或已过期时返回 nil
+func (r *RedisService) GetKioskSession(ctx context.Context, token string) (*KioskSession, error) {
+	key := KioskSessionKey + ":" + token
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session KioskSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+
+	session.ExpiresAt = time.Now().Add(r.kioskTTL)
+	if data, err = json.Marshal(&session); err != nil {
+		return nil, err
+	}
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, key, data, r.kioskTTL)
+	pipe.Expire(ctx, KioskCurrentKey+":"+session.Mode+":"+session.Kiosk, r.kioskTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// KioskSessionExists 检查会话是否仍然有效，不顺延有效期
+func (r *RedisService) KioskSessionExists(ctx context.Context, token string) (bool, error) {
+	n, err := r.client.Exists(ctx, KioskSessionKey+":"+token).Result()
+	return n > 0, err
+}
+
+// RotateKioskSession 终端完成一次借阅/归还后换用新会话，使旧二维码失效。
+// 终端没有使用扫码会话时返回 nil。
+func (r *RedisService) RotateKioskSession(ctx context.Context, kiosk, mode string) (*KioskSession, error) {
+	token, err := r.client.Get(ctx, KioskCurrentKey+":"+mode+":"+kiosk).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.GetKioskSession(ctx, token)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	return r.CreateKioskSession(ctx, kiosk, mode, strings.TrimSuffix(current.URL, current.Token))
+}
+
+// newToken 生成随机会话令牌
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
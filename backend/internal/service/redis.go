@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,14 +16,19 @@ const (
 	BorrowBooksKey = "borrow:books" // 当前借阅的图书列表
 	ReturnUserKey  = "return:user"  // 当前归还用户信息
 	ReturnBooksKey = "return:books" // 当前归还的图书列表
-	RedisTTL       = 2 * time.Hour  // Redis数据过期时间（2小时）
+
+	// DefaultKiosk 默认借阅终端，使用不带终端标识的原有键，兼容旧客户端
+	DefaultKiosk = "default"
 )
 
 type RedisService struct {
-	client *redis.Client
+	client   *redis.Client
+	ttl      time.Duration // 借阅/归还用户信息的有效期
+	kioskTTL time.Duration // 扫码会话的有效期，每次使用后顺延
 }
 
-func NewRedisService(addr, password string, db int) (*RedisService, error) {
+// NewRedisService ttl 为借阅/归还用户信息的有效期，kioskTTL 为终端扫码会话的有效期
+func NewRedisService(addr, password string, db int, ttl, kioskTTL time.Duration) (*RedisService, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: password,
@@ -35,7 +41,22 @@ func NewRedisService(addr, password string, db int) (*RedisService, error) {
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
-	return &RedisService{client: client}, nil
+	return &RedisService{client: client, ttl: ttl, kioskTTL: kioskTTL}, nil
+}
+
+// Ping 检查 Redis 是否可用
+func (r *RedisService) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// AddHook 添加 go-redis 钩子，如统计失败的命令
+func (r *RedisService) AddHook(hook redis.Hook) {
+	r.client.AddHook(hook)
+}
+
+// Close 关闭连接
+func (r *RedisService) Close() error {
+	return r.client.Close()
 }
 
 // BorrowUser 借阅用户信息
@@ -51,17 +72,17 @@ type BorrowBook struct {
 }
 
 // SetBorrowUser 设置当前借阅用户
-func (r *RedisService) SetBorrowUser(ctx context.Context, user *BorrowUser) error {
+func (r *RedisService) SetBorrowUser(ctx context.Context, kiosk string, user *BorrowUser) error {
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, BorrowUserKey, data, RedisTTL).Err()
+	return r.client.Set(ctx, kioskKey(BorrowUserKey, kiosk), data, r.ttl).Err()
 }
 
 // GetBorrowUser 获取当前借阅用户
-func (r *RedisService) GetBorrowUser(ctx context.Context) (*BorrowUser, error) {
-	data, err := r.client.Get(ctx, BorrowUserKey).Bytes()
+func (r *RedisService) GetBorrowUser(ctx context.Context, kiosk string) (*BorrowUser, error) {
+	data, err := r.client.Get(ctx, kioskKey(BorrowUserKey, kiosk)).Bytes()
 	if err == redis.Nil {
 		return nil, nil
 	}
@@ -76,17 +97,17 @@ func (r *RedisService) GetBorrowUser(ctx context.Context) (*BorrowUser, error) {
 }
 
 // AddBorrowBook 添加借阅图书
-func (r *RedisService) AddBorrowBook(ctx context.Context, book *BorrowBook) error {
+func (r *RedisService) AddBorrowBook(ctx context.Context, kiosk string, book *BorrowBook) error {
 	data, err := json.Marshal(book)
 	if err != nil {
 		return err
 	}
-	return r.client.LPush(ctx, BorrowBooksKey, data).Err()
+	return r.client.LPush(ctx, kioskKey(BorrowBooksKey, kiosk), data).Err()
 }
 
 // GetBorrowBooks 获取当前借阅图书列表
-func (r *RedisService) GetBorrowBooks(ctx context.Context) ([]*BorrowBook, error) {
-	dataList, err := r.client.LRange(ctx, BorrowBooksKey, 0, -1).Result()
+func (r *RedisService) GetBorrowBooks(ctx context.Context, kiosk string) ([]*BorrowBook, error) {
+	dataList, err := r.client.LRange(ctx, kioskKey(BorrowBooksKey, kiosk), 0, -1).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -102,9 +123,9 @@ func (r *RedisService) GetBorrowBooks(ctx context.Context) ([]*BorrowBook, error
 }
 
 // RemoveBorrowBook 删除借阅图书（通过索引）
-func (r *RedisService) RemoveBorrowBook(ctx context.Context, index int) error {
+func (r *RedisService) RemoveBorrowBook(ctx context.Context, kiosk string, index int) error {
 	// 先获取所有图书
-	books, err := r.GetBorrowBooks(ctx)
+	books, err := r.GetBorrowBooks(ctx, kiosk)
 	if err != nil {
 		return err
 	}
@@ -113,7 +134,7 @@ func (r *RedisService) RemoveBorrowBook(ctx context.Context, index int) error {
 	}
 
 	// 删除列表并重新添加（除了要删除的那本）
-	if err := r.client.Del(ctx, BorrowBooksKey).Err(); err != nil {
+	if err := r.client.Del(ctx, kioskKey(BorrowBooksKey, kiosk)).Err(); err != nil {
 		return err
 	}
 
@@ -121,7 +142,7 @@ func (r *RedisService) RemoveBorrowBook(ctx context.Context, index int) error {
 	for i := len(books) - 1; i >= 0; i-- {
 		if i != index {
 			data, _ := json.Marshal(books[i])
-			if err := r.client.LPush(ctx, BorrowBooksKey, data).Err(); err != nil {
+			if err := r.client.LPush(ctx, kioskKey(BorrowBooksKey, kiosk), data).Err(); err != nil {
 				return err
 			}
 		}
@@ -130,22 +151,22 @@ func (r *RedisService) RemoveBorrowBook(ctx context.Context, index int) error {
 }
 
 // ClearBorrowData 清除借阅数据
-func (r *RedisService) ClearBorrowData(ctx context.Context) error {
-	return r.client.Del(ctx, BorrowUserKey, BorrowBooksKey).Err()
+func (r *RedisService) ClearBorrowData(ctx context.Context, kiosk string) error {
+	return r.client.Del(ctx, kioskKey(BorrowUserKey, kiosk), kioskKey(BorrowBooksKey, kiosk)).Err()
 }
 
 // SetReturnUser 设置当前归还用户
-func (r *RedisService) SetReturnUser(ctx context.Context, user *BorrowUser) error {
+func (r *RedisService) SetReturnUser(ctx context.Context, kiosk string, user *BorrowUser) error {
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, ReturnUserKey, data, RedisTTL).Err()
+	return r.client.Set(ctx, kioskKey(ReturnUserKey, kiosk), data, r.ttl).Err()
 }
 
 // GetReturnUser 获取当前归还用户
-func (r *RedisService) GetReturnUser(ctx context.Context) (*BorrowUser, error) {
-	data, err := r.client.Get(ctx, ReturnUserKey).Bytes()
+func (r *RedisService) GetReturnUser(ctx context.Context, kiosk string) (*BorrowUser, error) {
+	data, err := r.client.Get(ctx, kioskKey(ReturnUserKey, kiosk)).Bytes()
 	if err == redis.Nil {
 		return nil, nil
 	}
@@ -160,17 +181,17 @@ func (r *RedisService) GetReturnUser(ctx context.Context) (*BorrowUser, error) {
 }
 
 // AddReturnBook 添加归还图书
-func (r *RedisService) AddReturnBook(ctx context.Context, book *BorrowBook) error {
+func (r *RedisService) AddReturnBook(ctx context.Context, kiosk string, book *BorrowBook) error {
 	data, err := json.Marshal(book)
 	if err != nil {
 		return err
 	}
-	return r.client.LPush(ctx, ReturnBooksKey, data).Err()
+	return r.client.LPush(ctx, kioskKey(ReturnBooksKey, kiosk), data).Err()
 }
 
 // GetReturnBooks 获取当前归还图书列表
-func (r *RedisService) GetReturnBooks(ctx context.Context) ([]*BorrowBook, error) {
-	dataList, err := r.client.LRange(ctx, ReturnBooksKey, 0, -1).Result()
+func (r *RedisService) GetReturnBooks(ctx context.Context, kiosk string) ([]*BorrowBook, error) {
+	dataList, err := r.client.LRange(ctx, kioskKey(ReturnBooksKey, kiosk), 0, -1).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -186,9 +207,9 @@ func (r *RedisService) GetReturnBooks(ctx context.Context) ([]*BorrowBook, error
 }
 
 // RemoveReturnBook 删除归还图书（通过索引）
-func (r *RedisService) RemoveReturnBook(ctx context.Context, index int) error {
+func (r *RedisService) RemoveReturnBook(ctx context.Context, kiosk string, index int) error {
 	// 先获取所有图书
-	books, err := r.GetReturnBooks(ctx)
+	books, err := r.GetReturnBooks(ctx, kiosk)
 	if err != nil {
 		return err
 	}
@@ -197,7 +218,7 @@ func (r *RedisService) RemoveReturnBook(ctx context.Context, index int) error {
 	}
 
 	// 删除列表并重新添加（除了要删除的那本）
-	if err := r.client.Del(ctx, ReturnBooksKey).Err(); err != nil {
+	if err := r.client.Del(ctx, kioskKey(ReturnBooksKey, kiosk)).Err(); err != nil {
 		return err
 	}
 
@@ -205,7 +226,7 @@ func (r *RedisService) RemoveReturnBook(ctx context.Context, index int) error {
 	for i := len(books) - 1; i >= 0; i-- {
 		if i != index {
 			data, _ := json.Marshal(books[i])
-			if err := r.client.LPush(ctx, ReturnBooksKey, data).Err(); err != nil {
+			if err := r.client.LPush(ctx, kioskKey(ReturnBooksKey, kiosk), data).Err(); err != nil {
 				return err
 			}
 		}
@@ -214,6 +235,102 @@ func (r *RedisService) RemoveReturnBook(ctx context.Context, index int) error {
 }
 
 // ClearReturnData 清除归还数据
-func (r *RedisService) ClearReturnData(ctx context.Context) error {
-	return r.client.Del(ctx, ReturnUserKey, ReturnBooksKey).Err()
+func (r *RedisService) ClearReturnData(ctx context.Context, kiosk string) error {
+	return r.client.Del(ctx, kioskKey(ReturnUserKey, kiosk), kioskKey(ReturnBooksKey, kiosk)).Err()
+}
+
+// kioskKey 返回指定借阅终端的键，每个终端有独立的借阅/归还列表
+func kioskKey(base, kiosk string) string {
+	if kiosk == "" || kiosk == DefaultKiosk {
+		return base
+	}
+	return base + ":" + kiosk
+}
+
+// PurgeExpiredSessions 清理过期会话留下的键，返回删除的键数。
+// 借阅/归还的用户信息有一定的有效期，图书列表没有有效期，用户信息过期后列表会一直留在 Redis 中；
+// 终端当前会话的记录指向的会话已不存在时也一并删除。
+func (r *RedisService) PurgeExpiredSessions(ctx context.Context) (int, error) {
+	deleted := 0
+	for _, pair := range [][2]string{{BorrowBooksKey, BorrowUserKey}, {ReturnBooksKey, ReturnUserKey}} {
+		booksKey, userKey := pair[0], pair[1]
+		keys, err := r.scanKeys(ctx, booksKey+":*")
+		if err != nil {
+			return deleted, err
+		}
+		keys = append(keys, booksKey)
+		for _, key := range keys {
+			// borrow:books:{kiosk} 对应 borrow:user:{kiosk}
+			if exists, err := r.client.Exists(ctx, userKey+strings.TrimPrefix(key, booksKey)).Result(); err != nil {
+				return deleted, err
+			} else if exists > 0 {
+				continue
+			}
+			n, err := r.client.Del(ctx, key).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += int(n)
+		}
+	}
+
+	keys, err := r.scanKeys(ctx, KioskCurrentKey+":*")
+	if err != nil {
+		return deleted, err
+	}
+	for _, key := range keys {
+		token, err := r.client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return deleted, err
+		}
+		if exists, err := r.KioskSessionExists(ctx, token); err != nil {
+			return deleted, err
+		} else if exists {
+			continue
+		}
+		if err := r.client.Del(ctx, key).Err(); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// SessionCounts 当前的会话数
+type SessionCounts struct {
+	Borrow int // 已设置借阅用户的终端
+	Return int // 已设置归还用户的终端
+	Kiosk  int // 未过期的扫码会话
+}
+
+// CountSessions 统计当前的会话数，用于监控
+func (r *RedisService) CountSessions(ctx context.Context) (SessionCounts, error) {
+	var counts SessionCounts
+	for _, item := range []struct {
+		pattern string
+		n       *int
+	}{
+		{BorrowUserKey + "*", &counts.Borrow},
+		{ReturnUserKey + "*", &counts.Return},
+		{KioskSessionKey + ":*", &counts.Kiosk},
+	} {
+		keys, err := r.scanKeys(ctx, item.pattern)
+		if err != nil {
+			return counts, err
+		}
+		*item.n = len(keys)
+	}
+	return counts, nil
+}
+
+// scanKeys 用 SCAN 列出匹配的键，不阻塞 Redis
+func (r *RedisService) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
 }
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage 附件存储，key 为以 "/" 分隔的相对路径
+type Storage interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open 读取对象，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
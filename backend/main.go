@@ -2,71 +2,182 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/cloudwego/hertz/pkg/common/utils"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
-	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
+	"booksystem/internal/backup"
+	"booksystem/internal/barcode"
+	"booksystem/internal/catalog"
+	"booksystem/internal/config"
 	"booksystem/internal/db"
 	"booksystem/internal/handler"
+	"booksystem/internal/logging"
+	"booksystem/internal/metrics"
 	"booksystem/internal/middleware"
 	"booksystem/internal/service"
+	"booksystem/internal/storage"
 )
 
 func main() {
-	// 初始化数据库
-	// 支持从环境变量读取数据库路径，默认使用当前目录下的 booksystem.db
-	dbPath := getEnv("DB_PATH", "booksystem.db")
+	// 不带参数时启动服务，否则执行子命令，见 booksystem help
+	if len(os.Args) < 2 {
+		serve()
+		return
+	}
+	os.Exit(runCommand(os.Args[1:]))
+}
 
-	database, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
-	if err != nil {
-		log.Fatal("Failed to connect database:", err)
+// serve 启动 HTTP 服务
+func serve() {
+	cfg := loadConfig()
+	database := openMigratedDatabase(cfg)
+	if err := metrics.InstrumentDB(database); err != nil {
+		fatal("Failed to register database metrics", err)
 	}
 
-	// 自动迁移数据库表
-	err = db.AutoMigrate(database)
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	// 后台任务在服务停止时取消，等它们结束后再关闭数据库
+	background, stopBackground := context.WithCancel(context.Background())
+	var backgroundTasks sync.WaitGroup
+
+	// SQLite 在线备份：backup.interval 不为 0 时按间隔定时备份，只保留最新的 backup.keep 个
+	var backups *backup.Manager
+	if cfg.Database.Driver == db.DriverSQLite {
+		var err error
+		backups, err = backup.NewManager(database, cfg.Backup.Dir)
+		if err != nil {
+			fatal("Failed to create backup directory", err)
+		}
+		if cfg.Backup.Interval > 0 {
+			backgroundTasks.Add(1)
+			go func() {
+				defer backgroundTasks.Done()
+				backups.Run(background, cfg.Backup.Interval.D(), cfg.Backup.Keep, cfg.Backup.Compress)
+			}()
+		}
 	}
 
 	// 初始化Redis
-	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
-	redisPassword := getEnv("REDIS_PASSWORD", "")
-	redisDB := 0
-	redisService, err := service.NewRedisService(redisAddr, redisPassword, redisDB)
+	redisService, err := connectRedis(cfg)
 	if err != nil {
-		log.Printf("Warning: Failed to connect to Redis: %v. Redis features will be disabled.", err)
+		slog.Warn("Failed to connect to Redis, Redis features will be disabled", "error", err)
 		redisService = nil
+	} else {
+		redisService.AddHook(metrics.RedisHook{})
+	}
+	if err := metrics.RegisterCirculation(database, redisService, time.Duration(cfg.Loan.PeriodDays)*24*time.Hour); err != nil {
+		fatal("Failed to register circulation metrics", err)
+	}
+
+	barcodeValidator := newBarcodeValidator(cfg)
+
+	// 本地书目目录（离线元数据查询），启动时加载目录文件夹中的 CSV/MARC 文件
+	localCatalog := catalog.NewLocalProvider()
+	if n, err := localCatalog.LoadDir(cfg.Catalog.Dir); err != nil {
+		slog.Warn("Failed to load catalog files", "error", err)
+	} else if n > 0 {
+		slog.Info("Loaded catalog entries", "count", n, "dir", cfg.Catalog.Dir)
+	}
+
+	if cfg.Label.Font == "" {
+		slog.Warn("label.font is not set, non-Latin text on printed labels will be replaced with '?'")
+	}
+
+	// 图书封面和附件保存在本地目录
+	attachmentStore, err := storage.NewLocal(cfg.Storage.UploadDir)
+	if err != nil {
+		fatal("Failed to create upload directory", err)
 	}
+	attachmentMaxSize := int64(cfg.Storage.AttachmentMaxMB) << 20
 
 	// 初始化Hertz服务器
+	// 请求体上限需容纳附件和 multipart 表单的其余部分；请求参数按 binding 标签校验
 	h := server.Default(
-		server.WithHostPorts(":8089"),
+		server.WithHostPorts(cfg.Server.Addr),
+		server.WithMaxRequestBodySize(int(attachmentMaxSize)+1<<20),
+		server.WithCustomValidator(handler.Validator{}),
+		server.WithExitWaitTime(cfg.Server.ShutdownTimeout.D()),
 	)
+	h.SetCustomSignalWaiter(waitForShutdownSignal)
 
 	// 注册中间件
-	h.Use(middleware.CORS())
+	// 请求日志最先执行，为后续中间件和处理函数分配请求 ID；之后确定提示信息使用的语言
+	h.Use(middleware.RequestLog(slog.Default()), metrics.Middleware(), middleware.CORS(cfg.Server.CORSOrigins), middleware.Language())
 
 	// 注册路由
-	registerRoutes(h, database, redisService)
+	registerRoutes(h, cfg, database, redisService, barcodeValidator, localCatalog, attachmentStore, backups)
 
-	// 启动服务器
+	// 启动服务器，收到停止信号后等待处理中的请求完成
 	h.Spin()
+
+	// 请求都已处理完，停止后台任务并关闭连接
+	stopBackground()
+	backgroundTasks.Wait()
+	if redisService != nil {
+		if err := redisService.Close(); err != nil {
+			slog.Warn("Failed to close Redis connection", "error", err)
+		}
+	}
+	if sqlDB, err := database.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Warn("Failed to close database", "error", err)
+		}
+	}
+	slog.Info("Server stopped")
 }
 
-func registerRoutes(h *server.Hertz, db *gorm.DB, redisService *service.RedisService) {
+// waitForShutdownSignal 等待 SIGINT 或 SIGTERM 后返回 nil，由 Hertz 优雅停止：不再接受新连接，
+// 最多等待 server.shutdown_timeout 让处理中的请求完成。Hertz 默认收到 SIGTERM 时立即关闭，容器停止时会中断请求。
+// 服务运行出错时返回该错误，立即退出
+func waitForShutdownSignal(errCh chan error) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case sig := <-signals:
+		slog.Info("Shutting down", "signal", sig.String())
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+func registerRoutes(h *server.Hertz, cfg *config.Config, db *gorm.DB, redisService *service.RedisService, barcodeValidator *barcode.Validator, localCatalog *catalog.LocalProvider, attachmentStore storage.Storage, backups *backup.Manager) {
 	// 创建处理器
-	bookHandler := handler.NewBookHandler(db)
+	bookHandler := handler.NewBookHandler(db, barcodeValidator, attachmentStore)
 	areaHandler := handler.NewAreaHandler(db)
 	bookshelfHandler := handler.NewBookshelfHandler(db)
 	shelfLayerHandler := handler.NewShelfLayerHandler(db)
 	locationHandler := handler.NewLocationHandler(db)
-	borrowHandler := handler.NewBorrowHandler(db, redisService)
+	borrowHandler := handler.NewBorrowHandler(db, redisService, barcodeValidator, cfg.Loan.MaxBooks)
+	exportHandler := handler.NewExportHandler(db)
+	authorHandler := handler.NewAuthorHandler(db)
+	publisherHandler := handler.NewPublisherHandler(db)
+	tagHandler := handler.NewTagHandler(db)
+	categoryHandler := handler.NewCategoryHandler(db)
+	marcHandler := handler.NewMarcHandler(db, barcodeValidator)
+	catalogHandler := handler.NewCatalogHandler(catalog.Chain{localCatalog}, localCatalog, cfg.Catalog.Dir)
+	labelHandler := handler.NewLabelHandler(db, barcodeValidator, cfg.Label.Font)
+	kioskHandler := handler.NewKioskHandler(redisService, cfg.Server.PublicURL)
+	shelvingHandler := handler.NewShelvingHandler(db)
+	attachmentHandler := handler.NewAttachmentHandler(db, attachmentStore, int64(cfg.Storage.AttachmentMaxMB)<<20)
+	backupHandler := handler.NewBackupHandler(backups, cfg.Backup.Compress)
+	healthHandler := handler.NewHealthHandler(db, redisService)
+
+	// 开始停止时就绪检查返回 503，并结束终端的事件流
+	h.OnShutdown = append(h.OnShutdown, healthHandler.Drain, kioskHandler.Drain)
 
 	api := h.Group("/api/v1")
 	{
@@ -74,27 +185,81 @@ func registerRoutes(h *server.Hertz, db *gorm.DB, redisService *service.RedisSer
 		api.POST("/books", bookHandler.Create)
 		api.GET("/books", bookHandler.List)
 		api.GET("/books/barcode/:barcode", bookHandler.GetByBarcode)
+		api.GET("/books/lookup/:barcode", catalogHandler.Lookup)
 		api.PUT("/books/:id", bookHandler.Update)
 		api.DELETE("/books/:id", bookHandler.Delete)
 
+		// 图书封面和附件
+		api.POST("/books/:id/attachments", attachmentHandler.Upload)
+		api.GET("/books/:id/attachments", attachmentHandler.List)
+		api.GET("/attachments/:id", attachmentHandler.Download)
+		api.GET("/attachments/:id/thumbnail", attachmentHandler.Thumbnail)
+		api.DELETE("/attachments/:id", attachmentHandler.Delete)
+
+		// 书目信息：作者、出版社、标签、分类
+		api.POST("/authors", authorHandler.Create)
+		api.GET("/authors", authorHandler.List)
+		api.PUT("/authors/:id", authorHandler.Update)
+		api.DELETE("/authors/:id", authorHandler.Delete)
+
+		api.POST("/publishers", publisherHandler.Create)
+		api.GET("/publishers", publisherHandler.List)
+		api.PUT("/publishers/:id", publisherHandler.Update)
+		api.DELETE("/publishers/:id", publisherHandler.Delete)
+
+		api.POST("/tags", tagHandler.Create)
+		api.GET("/tags", tagHandler.List)
+		api.PUT("/tags/:id", tagHandler.Update)
+		api.DELETE("/tags/:id", tagHandler.Delete)
+
+		api.POST("/categories", categoryHandler.Create)
+		api.GET("/categories", categoryHandler.List)
+		api.PUT("/categories/:id", categoryHandler.Update)
+		api.DELETE("/categories/:id", categoryHandler.Delete)
+
+		// 本地书目目录
+		api.GET("/catalog", catalogHandler.Status)
+		api.POST("/catalog/import", catalogHandler.Import)
+
+		// MARC 导入导出
+		api.POST("/marc/import", marcHandler.Import)
+		api.GET("/marc/export", marcHandler.Export)
+
+		// 标签打印
+		api.GET("/labels/barcode/:code", labelHandler.Barcode)
+		api.GET("/labels/layouts", labelHandler.Layouts)
+		api.POST("/labels/sheet", labelHandler.Sheet)
+		api.POST("/labels/shelf-layers", labelHandler.ShelfLayers)
+
 		// 位置管理
 		api.POST("/areas", areaHandler.Create)
 		api.GET("/areas", areaHandler.List)
+		api.PUT("/areas/order", areaHandler.Reorder)
 		api.PUT("/areas/:id", areaHandler.Update)
 		api.DELETE("/areas/:id", areaHandler.Delete)
 
 		api.POST("/bookshelves", bookshelfHandler.Create)
 		api.GET("/bookshelves", bookshelfHandler.List)
+		api.PUT("/bookshelves/order", bookshelfHandler.Reorder)
 		api.PUT("/bookshelves/:id", bookshelfHandler.Update)
+		api.POST("/bookshelves/:id/move", bookshelfHandler.Move)
 		api.DELETE("/bookshelves/:id", bookshelfHandler.Delete)
 
 		api.POST("/shelf-layers", shelfLayerHandler.Create)
 		api.GET("/shelf-layers", shelfLayerHandler.List)
+		api.PUT("/shelf-layers/order", shelfLayerHandler.Reorder)
 		api.PUT("/shelf-layers/:id", shelfLayerHandler.Update)
+		api.POST("/shelf-layers/:id/move", shelfLayerHandler.Move)
+		api.POST("/shelf-layers/:id/merge", shelfLayerHandler.Merge)
+		api.GET("/shelf-layers/code/:code", shelfLayerHandler.GetByCode)
 		api.DELETE("/shelf-layers/:id", shelfLayerHandler.Delete)
 
 		api.GET("/locations/tree", locationHandler.GetTree)
 
+		// 扫码上架
+		api.POST("/shelving", shelvingHandler.Shelve)
+		api.GET("/shelving/logs", shelvingHandler.Logs)
+
 		// 借阅管理（兼容旧接口）
 		api.POST("/borrow", borrowHandler.Create)
 		api.POST("/borrow/scan", borrowHandler.Scan)
@@ -102,31 +267,114 @@ func registerRoutes(h *server.Hertz, db *gorm.DB, redisService *service.RedisSer
 		api.POST("/borrow/get-borrower", borrowHandler.GetBorrowerByPhone)
 		api.POST("/borrow/return", borrowHandler.Return)
 
-		// 新的借阅API（使用Redis）
-		api.POST("/borrow/user", borrowHandler.SetBorrowUser)
-		api.GET("/borrow/user", borrowHandler.GetBorrowUser)
-		api.POST("/borrow/book", borrowHandler.AddBorrowBook)
-		api.DELETE("/borrow/book", borrowHandler.RemoveBorrowBook)
-		api.POST("/borrow/complete", borrowHandler.CompleteBorrow)
+		// 以下接口依赖 Redis，Redis 不可用时不注册（返回 404），其余接口照常工作
+		if redisService != nil {
+			// 借阅终端扫码会话（二维码）
+			api.POST("/kiosk/sessions", kioskHandler.CreateSession)
+			api.GET("/kiosk/sessions/:token", kioskHandler.GetSession)
+			api.GET("/kiosk/sessions/:token/qr", kioskHandler.QRCode)
+			api.GET("/kiosk/events", kioskHandler.Events)
+
+			// 新的借阅API（使用Redis）
+			api.POST("/borrow/user", borrowHandler.SetBorrowUser)
+			api.GET("/borrow/user", borrowHandler.GetBorrowUser)
+			api.POST("/borrow/book", borrowHandler.AddBorrowBook)
+			api.DELETE("/borrow/book", borrowHandler.RemoveBorrowBook)
+			api.POST("/borrow/complete", borrowHandler.CompleteBorrow)
 
-		// 新的归还API（使用Redis）
-		api.POST("/return/user", borrowHandler.SetReturnUser)
-		api.GET("/return/user", borrowHandler.GetReturnUser)
-		api.POST("/return/book", borrowHandler.AddReturnBook)
-		api.DELETE("/return/book", borrowHandler.RemoveReturnBook)
-		api.POST("/return/complete", borrowHandler.CompleteReturn)
+			// 新的归还API（使用Redis）
+			api.POST("/return/user", borrowHandler.SetReturnUser)
+			api.GET("/return/user", borrowHandler.GetReturnUser)
+			api.POST("/return/book", borrowHandler.AddReturnBook)
+			api.DELETE("/return/book", borrowHandler.RemoveReturnBook)
+			api.POST("/return/complete", borrowHandler.CompleteReturn)
+		}
+
+		// 数据导出（format=csv|xlsx，筛选参数与对应列表接口相同）
+		api.GET("/export/books", exportHandler.Books)
+		api.GET("/export/borrow-records", exportHandler.BorrowRecords)
+		api.GET("/export/borrowers", exportHandler.Borrowers)
+
+		// 数据库备份（仅 SQLite）。接口没有鉴权，只在 backup.api 开启时注册
+		if cfg.Backup.API {
+			api.POST("/backups", backupHandler.Create)
+			api.GET("/backups", backupHandler.List)
+			api.GET("/backups/:name", backupHandler.Download)
+		}
 	}
 
-	// 健康检查
+	// 健康检查：/healthz 存活检查，/readyz 就绪检查（数据库、Redis、迁移状态）
 	h.GET("/ping", func(ctx context.Context, c *app.RequestContext) {
 		c.JSON(consts.StatusOK, utils.H{"message": "pong"})
 	})
+	h.GET("/healthz", healthHandler.Live)
+	h.GET("/readyz", healthHandler.Ready)
+
+	// Prometheus 指标
+	h.GET("/metrics", metrics.Handler())
 }
 
-// getEnv 获取环境变量，如果不存在则返回默认值
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// loadConfig 加载配置：CONFIG_FILE 指定的配置文件（未设置时读取当前目录下的 config.yaml，不存在则跳过）和环境变量。
+// 配置无效时列出全部错误后退出
+func loadConfig() *config.Config {
+	cfg, err := config.Load(configFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
 	}
-	return defaultValue
+	setupLogging(cfg)
+	return cfg
+}
+
+// setupLogging 按 log.level 和 log.format 设置默认日志，Hertz、go-redis 和标准库 log 的输出也转到该日志
+func setupLogging(cfg *config.Config) {
+	logger := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	slog.SetDefault(logger)
+	hlog.SetLogger(logging.NewHertzLogger(logger))
+	redis.SetLogger(logging.NewRedisLogger(logger))
+}
+
+// fatal 记录错误后退出
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// configFile 要读取的配置文件，为空表示没有配置文件
+func configFile() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(config.DefaultFile); err == nil {
+		return config.DefaultFile
+	}
+	return ""
+}
+
+func openDatabase(cfg *config.Config) *gorm.DB {
+	database, err := db.Open(cfg.Database.Driver, cfg.Database.ConnString())
+	if err != nil {
+		fatal("Failed to connect database", err)
+	}
+	// debug 时输出每条 SQL，否则只输出失败的语句和慢查询
+	database.Logger = logging.NewGormLogger(slog.Default(), cfg.Log.Level)
+	return database
+}
+
+// openMigratedDatabase 连接数据库并执行迁移：database.auto_migrate 为 false 时不执行，有未执行的迁移则退出，需先运行 migrate up
+func openMigratedDatabase(cfg *config.Config) *gorm.DB {
+	database := openDatabase(cfg)
+	if err := migrateOnStart(database, cfg.Database.AutoMigrate); err != nil {
+		fatal("Failed to migrate database", err)
+	}
+	return database
+}
+
+func connectRedis(cfg *config.Config) (*service.RedisService, error) {
+	return service.NewRedisService(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB, cfg.Session.TTL.D(), cfg.Session.KioskTTL.D())
+}
+
+// newBarcodeValidator 一维码校验：馆内自编条码前缀
+func newBarcodeValidator(cfg *config.Config) *barcode.Validator {
+	return barcode.NewValidator(cfg.Barcode.InternalPrefixes)
 }
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+	"gorm.io/gorm"
+
+	"booksystem/internal/db"
+	"booksystem/internal/handler"
+	"booksystem/internal/i18n"
+)
+
+// newFlagSet 创建子命令的参数解析器，出错时打印用法
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: booksystem %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runImportBooksCommand 导入 MARC 文件，按 ISBN/馆藏条码匹配已有图书，与导入接口相同
+func runImportBooksCommand(args []string) int {
+	fs := newFlagSet("import-books", "[-format iso2709|marcxml] [-quantity n] <file>")
+	format := fs.String("format", "", "file format, detected from the content when empty")
+	quantity := fs.Int("quantity", 1, "quantity of newly created books")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 || *quantity < 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	cfg := loadConfig()
+	database := openMigratedDatabase(cfg)
+	result, err := handler.NewMarcHandler(database, newBarcodeValidator(cfg)).ImportFile(f, *format, *quantity, i18n.En)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("total %d, created %d, updated %d, skipped %d\n", result.Total, result.Created, result.Updated, result.Skipped)
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "record %d %s: %s\n", e.Index, e.Barcode, e.Message)
+	}
+	return 0
+}
+
+// runExportBooksCommand 导出全部图书，表格格式的列与导出接口相同
+func runExportBooksCommand(args []string) int {
+	fs := newFlagSet("export-books", "[-format csv|xlsx|iso2709|marcxml] [-lang zh-CN|en] [-o file]")
+	format := fs.String("format", "csv", "csv, xlsx, iso2709 or marcxml")
+	langTag := fs.String("lang", string(i18n.Default), "language of the csv/xlsx column headers")
+	output := fs.String("o", "", "output file, standard output when empty")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return 2
+	}
+	lang, ok := i18n.Parse(*langTag)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unsupported language %q\n", *langTag)
+		return 2
+	}
+
+	var export func(*gorm.DB, io.Writer, string) error
+	switch *format {
+	case "csv", "xlsx":
+		export = func(database *gorm.DB, out io.Writer, format string) error {
+			return handler.ExportBooks(database, out, format, lang)
+		}
+	case "iso2709", "marcxml":
+		export = handler.ExportMarc
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported format %q\n", *format)
+		return 2
+	}
+
+	database := openMigratedDatabase(loadConfig())
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	if err := export(database, w, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runCreateAdminCommand 创建管理员账号。密码从终端输入（不回显），或从标准输入的第一行读取，便于脚本调用。
+// 接口目前还不校验管理员登录，账号只是预先创建，管理接口仍需由网络边界（反向代理认证、内网访问）保护。
+func runCreateAdminCommand(args []string) int {
+	fs := newFlagSet("create-admin", "[-reset] <username>")
+	reset := fs.Bool("reset", false, "reset the password of an existing admin")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+	username := strings.TrimSpace(fs.Arg(0))
+	if username == "" {
+		fs.Usage()
+		return 2
+	}
+
+	database := openMigratedDatabase(loadConfig())
+	var admin db.Admin
+	result := database.Where("username = ?", username).Limit(1).Find(&admin)
+	switch {
+	case result.Error != nil:
+		fmt.Fprintln(os.Stderr, result.Error)
+		return 1
+	case result.RowsAffected > 0 && !*reset:
+		fmt.Fprintf(os.Stderr, "admin %q already exists, use -reset to change the password\n", username)
+		return 1
+	case result.RowsAffected == 0 && *reset:
+		fmt.Fprintf(os.Stderr, "admin %q does not exist\n", username)
+		return 1
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	admin.Username = username
+	if err := admin.SetPassword(password); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := database.Save(&admin).Error; err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *reset {
+		fmt.Printf("password of admin %q reset\n", username)
+	} else {
+		fmt.Printf("admin %q created\n", username)
+	}
+	fmt.Fprintln(os.Stderr, "note: the API does not check admin logins yet, protect the management API at the network level")
+	return 0
+}
+
+// readPassword 在终端中提示输入两次密码，否则读取标准输入的第一行
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Confirm password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", errors.New("passwords do not match")
+	}
+	return string(first), nil
+}
+
+// runReconcileStockCommand 按未归还的借阅明细修正图书的在库数量
+func runReconcileStockCommand(args []string) int {
+	fs := newFlagSet("reconcile-stock", "[-dry-run]")
+	dryRun := fs.Bool("dry-run", false, "only list the mismatches, do not fix them")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return 2
+	}
+
+	database := openMigratedDatabase(loadConfig())
+	mismatches, err := db.ReconcileStock(database, !*dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(mismatches) == 0 {
+		fmt.Println("all in-stock counts match")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tBARCODE\tNAME\tQUANTITY\tBORROWED\tIN STOCK\tEXPECTED")
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\t%d\n", m.BookID, m.Barcode, m.Name, m.Quantity, m.Borrowed, m.InStock, m.Expected)
+	}
+	w.Flush()
+	if *dryRun {
+		fmt.Printf("%d book(s) mismatched, run without -dry-run to fix them\n", len(mismatches))
+	} else {
+		fmt.Printf("fixed %d book(s)\n", len(mismatches))
+	}
+	return 0
+}
+
+// runPurgeSessionsCommand 清理 Redis 中过期会话留下的键
+func runPurgeSessionsCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: booksystem purge-sessions")
+		return 2
+	}
+	redisService, err := connectRedis(loadConfig())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	deleted, err := redisService.PurgeExpiredSessions(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("deleted %d key(s)\n", deleted)
+	return 0
+}
+
+// runSeedCommand 向空数据库写入演示数据
+func runSeedCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: booksystem seed")
+		return 2
+	}
+	database := openMigratedDatabase(loadConfig())
+	result, err := db.SeedDemo(database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("created %d areas, %d bookshelves, %d shelf layers, %d books, %d borrowers, %d borrow records\n",
+		result.Areas, result.Bookshelves, result.ShelfLayers, result.Books, result.Borrowers, result.BorrowRecords)
+	return 0
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+
+	"booksystem/internal/db/migrations"
+	"booksystem/internal/migrate"
+)
+
+const migrateUsage = `usage: booksystem migrate <command>
+
+commands:
+  up [version]   apply pending migrations, up to version if given
+  down [steps]   roll back the latest applied migrations (default 1)
+  status         list migrations and whether they are applied`
+
+// migrateOnStart 启动时检查数据库迁移：autoMigrate 为 true 时执行全部未执行的迁移，否则有未执行的迁移时返回错误
+func migrateOnStart(database *gorm.DB, autoMigrate bool) error {
+	migrator, err := migrations.New(database)
+	if err != nil {
+		return err
+	}
+	if !autoMigrate {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d pending migration(s), run `booksystem migrate up` first", pending)
+		}
+		return nil
+	}
+	applied, err := migrator.Up(0)
+	for _, mig := range applied {
+		slog.Info("Applied migration", "version", mig.Version, "name", mig.Name)
+	}
+	return err
+}
+
+// runMigrateCommand 执行 migrate 子命令，返回进程退出码
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	migrator, err := migrations.New(openDatabase(loadConfig()))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var arg int64
+	if len(args) == 2 {
+		arg, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil || arg <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid argument %q, expected a positive number\n", args[1])
+			return 2
+		}
+	}
+
+	var done []migrate.Migration
+	switch args[0] {
+	case "up":
+		done, err = migrator.Up(arg)
+		for _, mig := range done {
+			fmt.Printf("applied  %04d_%s\n", mig.Version, mig.Name)
+		}
+	case "down":
+		steps := 1
+		if arg > 0 {
+			steps = int(arg)
+		}
+		done, err = migrator.Down(steps)
+		for _, mig := range done {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+	case "status":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		err = printMigrationStatus(migrator)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if args[0] != "status" && len(done) == 0 {
+		fmt.Println("nothing to do")
+	}
+	return 0
+}
+
+func printMigrationStatus(migrator *migrate.Migrator) error {
+	list, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range list {
+		status, appliedAt := "pending", ""
+		if s.Applied {
+			status = "applied"
+			appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if s.Unknown {
+			status = "unknown"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+	return w.Flush()
+}
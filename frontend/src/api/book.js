@@ -13,6 +13,10 @@ export const bookApi = {
   getByBarcode(barcode) {
     return api.get(`/books/barcode/${barcode}`)
   },
+  // 从本地书目目录查询元数据（用于录入时预填）
+  lookup(barcode) {
+    return api.get(`/books/lookup/${barcode}`)
+  },
   // 更新图书
   update(id, data) {
     return api.put(`/books/${id}`, data)
@@ -20,6 +24,21 @@ export const bookApi = {
   // 删除图书
   delete(id) {
     return api.delete(`/books/${id}`)
+  },
+  // 上传封面或附件（kind: cover | toc | other）
+  uploadAttachment(id, file, kind) {
+    const formData = new FormData()
+    formData.append('file', file)
+    formData.append('kind', kind)
+    return api.post(`/books/${id}/attachments`, formData, { timeout: 60000 })
+  },
+  // 查询图书附件
+  attachments(id) {
+    return api.get(`/books/${id}/attachments`)
+  },
+  // 删除附件
+  deleteAttachment(attachmentId) {
+    return api.delete(`/attachments/${attachmentId}`)
   }
 }
 
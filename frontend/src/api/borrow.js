@@ -1,4 +1,5 @@
 import api from './index'
+import { kioskHeaders } from './kiosk'
 
 export const borrowApi = {
   // 创建借阅记录（兼容旧接口）
@@ -22,36 +23,38 @@ export const borrowApi = {
     return api.post('/borrow/return', data)
   },
   // 新的借阅API（使用Redis）
-  setBorrowUser(data) {
-    return api.post('/borrow/user', data)
+  // session 为手机端扫码得到的会话令牌，终端调用时不传
+  setBorrowUser(data, session) {
+    return api.post('/borrow/user', data, { headers: kioskHeaders(session) })
   },
-  getBorrowUser() {
-    return api.get('/borrow/user')
+  getBorrowUser(session) {
+    return api.get('/borrow/user', { headers: kioskHeaders(session) })
   },
   addBorrowBook(data) {
-    return api.post('/borrow/book', data)
+    return api.post('/borrow/book', data, { headers: kioskHeaders() })
   },
   removeBorrowBook(data) {
-    return api.delete('/borrow/book', { data: data })
+    return api.delete('/borrow/book', { data: data, headers: kioskHeaders() })
   },
   completeBorrow(data) {
-    return api.post('/borrow/complete', data)
+    return api.post('/borrow/complete', data, { headers: kioskHeaders() })
   },
   // 新的归还API（使用Redis）
-  setReturnUser(data) {
-    return api.post('/return/user', data)
+  // session 为手机端扫码得到的会话令牌，终端调用时不传
+  setReturnUser(data, session) {
+    return api.post('/return/user', data, { headers: kioskHeaders(session) })
   },
-  getReturnUser() {
-    return api.get('/return/user')
+  getReturnUser(session) {
+    return api.get('/return/user', { headers: kioskHeaders(session) })
   },
   addReturnBook(data) {
-    return api.post('/return/book', data)
+    return api.post('/return/book', data, { headers: kioskHeaders() })
   },
   removeReturnBook(data) {
-    return api.delete('/return/book', { data: data })
+    return api.delete('/return/book', { data: data, headers: kioskHeaders() })
   },
   completeReturn(data) {
-    return api.post('/return/complete', data)
+    return api.post('/return/complete', data, { headers: kioskHeaders() })
   }
 }
 
@@ -5,23 +5,60 @@ const api = axios.create({
   timeout: 10000
 })
 
+// apiError 把后端的错误响应转换为 Error：message 为提示，
+// code 为错误码（如 STOCK_EXHAUSTED），details 为字段校验明细，requestId 便于查找服务端日志
+const apiError = (body, status) => {
+  const error = new Error(body?.message || '请求失败')
+  error.status = status
+  error.code = body?.error_code
+  error.details = body?.details || []
+  error.data = body?.data
+  error.requestId = body?.request_id
+  return error
+}
+
+// 文件下载出错时后端返回 JSON，需先从 Blob 中读出
+const readBlobError = (blob, status) => {
+  return blob.text().then(text => {
+    let body
+    try {
+      body = JSON.parse(text)
+    } catch {
+      body = null
+    }
+    return Promise.reject(apiError(body, status))
+  })
+}
+
 // 响应拦截器
 api.interceptors.response.use(
   response => {
     const res = response.data
+    if (response.config.responseType === 'blob') {
+      if (!res.type.startsWith('application/json')) {
+        return res
+      }
+      return readBlobError(res, response.status)
+    }
     if (res.code !== 200) {
-      return Promise.reject(new Error(res.message || '请求失败'))
+      return Promise.reject(apiError(res, response.status))
     }
     return res.data
   },
   error => {
+    // 错误响应的 HTTP 状态码与错误码对应，提示在响应体中
+    const { response } = error
+    if (!response) {
+      return Promise.reject(error)
+    }
+    if (response.data instanceof Blob) {
+      return readBlobError(response.data, response.status)
+    }
+    if (response.data && typeof response.data === 'object') {
+      return Promise.reject(apiError(response.data, response.status))
+    }
     return Promise.reject(error)
   }
 )
 
 export default api
-
-
-
-
-
@@ -0,0 +1,42 @@
+import api from './index'
+
+const KIOSK_ID_KEY = 'kiosk_id'
+
+// 当前浏览器作为借阅终端的标识，首次使用时随机生成并保存
+export const getKioskId = () => {
+  let id = localStorage.getItem(KIOSK_ID_KEY)
+  if (!id) {
+    id = 'kiosk-' + Math.random().toString(36).slice(2, 10)
+    localStorage.setItem(KIOSK_ID_KEY, id)
+  }
+  return id
+}
+
+// 借阅/归还接口的请求头：手机端使用扫码会话令牌，终端使用终端标识
+export const kioskHeaders = (session) => {
+  return session ? { 'X-Kiosk-Session': session } : { 'X-Kiosk-ID': getKioskId() }
+}
+
+export const kioskApi = {
+  // 创建扫码会话（mode: borrow | return），之前的二维码随即失效
+  createSession(mode) {
+    return api.post('/kiosk/sessions', {
+      kiosk: getKioskId(),
+      mode,
+      base_url: window.location.origin
+    })
+  },
+  // 查询扫码会话
+  getSession(token) {
+    return api.get(`/kiosk/sessions/${token}`)
+  },
+  // 二维码图片地址
+  qrUrl(token) {
+    return `/api/v1/kiosk/sessions/${token}/qr`
+  },
+  // 订阅借阅/归还列表的实时变化（SSE），手机端传入会话令牌，终端不传
+  events(mode, session) {
+    const query = session ? `session=${session}` : `kiosk=${encodeURIComponent(getKioskId())}`
+    return new EventSource(`/api/v1/kiosk/events?mode=${mode}&${query}`)
+  }
+}
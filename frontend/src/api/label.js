@@ -0,0 +1,20 @@
+import api from './index'
+
+export const labelApi = {
+  // 查询预置标签纸版式
+  layouts() {
+    return api.get('/labels/layouts')
+  },
+  // 生成 PDF 标签页，返回 Blob
+  sheet(data) {
+    return api.post('/labels/sheet', data, { responseType: 'blob', timeout: 60000 })
+  },
+  // 生成层位置标签 PDF，返回 Blob
+  shelfLayers(data) {
+    return api.post('/labels/shelf-layers', data, { responseType: 'blob', timeout: 60000 })
+  },
+  // 一维码图片地址
+  barcodeUrl(code, format = 'svg') {
+    return `/api/v1/labels/barcode/${encodeURIComponent(code)}?format=${format}`
+  }
+}
@@ -12,8 +12,13 @@ export const locationApi = {
     update(id, data) {
       return api.put(`/areas/${id}`, data)
     },
-    delete(id) {
-      return api.delete(`/areas/${id}`)
+    // 调整顺序，ids 为全部区域的新顺序
+    reorder(ids) {
+      return api.put('/areas/order', { ids })
+    },
+    // params.mode: restrict（默认）| relocate（需 target_layer_id）| cascade
+    delete(id, params) {
+      return api.delete(`/areas/${id}`, { params })
     }
   },
   // 书架管理
@@ -27,8 +32,17 @@ export const locationApi = {
     update(id, data) {
       return api.put(`/bookshelves/${id}`, data)
     },
-    delete(id) {
-      return api.delete(`/bookshelves/${id}`)
+    // 调整顺序，ids 为同一区域下的全部书架的新顺序
+    reorder(ids) {
+      return api.put('/bookshelves/order', { ids })
+    },
+    // 移动到其他区域，data: { area_id, name? }，目标区域有同名书架时返回 409
+    move(id, data) {
+      return api.post(`/bookshelves/${id}/move`, data)
+    },
+    // params.mode: restrict（默认）| relocate（需 target_layer_id）| cascade
+    delete(id, params) {
+      return api.delete(`/bookshelves/${id}`, { params })
     }
   },
   // 层数管理
@@ -42,8 +56,25 @@ export const locationApi = {
     update(id, data) {
       return api.put(`/shelf-layers/${id}`, data)
     },
-    delete(id) {
-      return api.delete(`/shelf-layers/${id}`)
+    // 调整顺序，ids 为同一书架下的全部层的新顺序
+    reorder(ids) {
+      return api.put('/shelf-layers/order', { ids })
+    },
+    // 移动到其他书架，data: { bookshelf_id, name? }，目标书架有同名层时返回 409
+    move(id, data) {
+      return api.post(`/shelf-layers/${id}/move`, data)
+    },
+    // 合并到目标层：图书转移到目标层后删除本层
+    merge(id, targetLayerId) {
+      return api.post(`/shelf-layers/${id}/merge`, { target_layer_id: targetLayerId })
+    },
+    // params.mode: restrict（默认）| relocate（需 target_layer_id）| cascade
+    delete(id, params) {
+      return api.delete(`/shelf-layers/${id}`, { params })
+    },
+    // 根据位置码查询层
+    getByCode(code) {
+      return api.get(`/shelf-layers/code/${encodeURIComponent(code)}`)
     }
   },
   // 获取位置树
@@ -0,0 +1,64 @@
+import api from './index'
+
+export const metadataApi = {
+  // 作者管理
+  author: {
+    create(data) {
+      return api.post('/authors', data)
+    },
+    list(params) {
+      return api.get('/authors', { params })
+    },
+    update(id, data) {
+      return api.put(`/authors/${id}`, data)
+    },
+    delete(id) {
+      return api.delete(`/authors/${id}`)
+    }
+  },
+  // 出版社管理
+  publisher: {
+    create(data) {
+      return api.post('/publishers', data)
+    },
+    list(params) {
+      return api.get('/publishers', { params })
+    },
+    update(id, data) {
+      return api.put(`/publishers/${id}`, data)
+    },
+    delete(id) {
+      return api.delete(`/publishers/${id}`)
+    }
+  },
+  // 标签管理
+  tag: {
+    create(data) {
+      return api.post('/tags', data)
+    },
+    list(params) {
+      return api.get('/tags', { params })
+    },
+    update(id, data) {
+      return api.put(`/tags/${id}`, data)
+    },
+    delete(id) {
+      return api.delete(`/tags/${id}`)
+    }
+  },
+  // 分类管理
+  category: {
+    create(data) {
+      return api.post('/categories', data)
+    },
+    list(params) {
+      return api.get('/categories', { params })
+    },
+    update(id, data) {
+      return api.put(`/categories/${id}`, data)
+    },
+    delete(id) {
+      return api.delete(`/categories/${id}`)
+    }
+  }
+}
@@ -0,0 +1,12 @@
+import api from './index'
+
+export const shelvingApi = {
+  // 扫码上架，codes 为按扫码顺序排列的层位置码和图书一维码
+  shelve(codes) {
+    return api.post('/shelving', { codes })
+  },
+  // 查询上架记录
+  logs(params) {
+    return api.get('/shelving/logs', { params })
+  }
+}
@@ -5,6 +5,8 @@ import LocationManage from '../views/LocationManage.vue'
 import BorrowPage from '../views/BorrowPage.vue'
 import BorrowQuery from '../views/BorrowQuery.vue'
 import ReturnPage from '../views/ReturnPage.vue'
+import KioskJoin from '../views/KioskJoin.vue'
+import ShelvingPage from '../views/ShelvingPage.vue'
 
 const routes = [
   {
@@ -31,6 +33,11 @@ const routes = [
     name: 'LocationManage',
     component: LocationManage
   },
+  {
+    path: '/shelving',
+    name: 'ShelvingPage',
+    component: ShelvingPage
+  },
   {
     path: '/borrow',
     name: 'BorrowPage',
@@ -45,6 +52,12 @@ const routes = [
     path: '/return',
     name: 'ReturnPage',
     component: ReturnPage
+  },
+  {
+    // 读者手机扫描终端二维码后打开的页面
+    path: '/kiosk/:token',
+    name: 'KioskJoin',
+    component: KioskJoin
   }
 ]
 